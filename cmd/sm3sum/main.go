@@ -0,0 +1,248 @@
+// Command sm3sum prints or checks SM3 checksums, in the style of sha256sum.
+//
+// Usage:
+//
+//	sm3sum [-r] [-j workers] [file ...]
+//	sm3sum -c [-quiet] [-j workers] [checksum file ...]
+//
+// With no file, or when file is -, it reads the standard input. The output lines
+// are the hex encoded checksum, two spaces and the file name, which is the input
+// of the check mode.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// job is a file to hash, with the expected checksum in the check mode.
+type job struct {
+	name string
+	want string
+	err  error // the error found before hashing, such as a directory without -r
+}
+
+// result is the checksum of a job.
+type result struct {
+	sum [sm3.Size]byte
+	err error
+}
+
+// run runs sm3sum with the arguments and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sm3sum", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	check := flags.Bool("c", false, "read SM3 checksums from the files and check them")
+	recursive := flags.Bool("r", false, "hash the files in directories recursively")
+	quiet := flags.Bool("quiet", false, "don't print OK for each successfully verified file")
+	workers := flags.Int("j", runtime.NumCPU(), "number of files hashed in parallel")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *workers < 1 {
+		fmt.Fprintln(stderr, "sm3sum: invalid number of workers")
+		return 2
+	}
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	if *check {
+		return checkFiles(names, *quiet, *workers, stdin, stdout, stderr)
+	}
+	exit := 0
+	jobs := expand(names, *recursive)
+	hashFiles(jobs, *workers, stdin, func(j job, r result) {
+		if r.err != nil {
+			fmt.Fprintf(stderr, "sm3sum: %v\n", r.err)
+			exit = 1
+			return
+		}
+		fmt.Fprintf(stdout, "%x  %s\n", r.sum, j.name)
+	})
+	return exit
+}
+
+// expand returns the jobs of the names, directories are walked in lexical order
+// if recursive, otherwise they are reported as errors.
+func expand(names []string, recursive bool) []job {
+	var jobs []job
+	for _, name := range names {
+		if name == "-" {
+			jobs = append(jobs, job{name: name})
+			continue
+		}
+		info, err := os.Stat(name)
+		switch {
+		case err != nil:
+			jobs = append(jobs, job{name: name, err: err})
+		case !info.IsDir():
+			jobs = append(jobs, job{name: name})
+		case !recursive:
+			jobs = append(jobs, job{name: name, err: fmt.Errorf("%s: is a directory", name)})
+		default:
+			err := filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					jobs = append(jobs, job{name: path, err: err})
+					return nil
+				}
+				if d.Type().IsRegular() {
+					jobs = append(jobs, job{name: path})
+				}
+				return nil
+			})
+			if err != nil {
+				jobs = append(jobs, job{name: name, err: err})
+			}
+		}
+	}
+	return jobs
+}
+
+// hashFiles hashes the files of the jobs with the given number of workers, and
+// calls emit with the results in the order of the jobs.
+func hashFiles(jobs []job, workers int, stdin io.Reader, emit func(job, result)) {
+	results := make([]chan result, len(jobs))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	next := make(chan int)
+	go func() {
+		for i := range jobs {
+			next <- i
+		}
+		close(next)
+	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range next {
+				var r result
+				if r.err = jobs[i].err; r.err == nil {
+					r.sum, r.err = sumFile(jobs[i].name, stdin)
+				}
+				results[i] <- r
+			}
+		}()
+	}
+	for i, j := range jobs {
+		emit(j, <-results[i])
+	}
+}
+
+// sumFile returns the SM3 checksum of the named file, or of stdin if name is -.
+func sumFile(name string, stdin io.Reader) (sum [sm3.Size]byte, err error) {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return sum, err
+		}
+		defer f.Close()
+		r = f
+	}
+	h := sm3.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return sum, err
+	}
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// parseCheckLine parses a line of the sm3sum output, the file name may be
+// prefixed with * for the binary mode of sha256sum.
+func parseCheckLine(line string) (job, error) {
+	if len(line) < 2*sm3.Size+2 || line[2*sm3.Size] != ' ' {
+		return job{}, errors.New("improperly formatted SM3 checksum line")
+	}
+	want := strings.ToLower(line[:2*sm3.Size])
+	if _, err := hex.DecodeString(want); err != nil {
+		return job{}, errors.New("improperly formatted SM3 checksum line")
+	}
+	name := line[2*sm3.Size+1:]
+	if name[0] == ' ' || name[0] == '*' {
+		name = name[1:]
+	}
+	if name == "" {
+		return job{}, errors.New("improperly formatted SM3 checksum line")
+	}
+	return job{name: name, want: want}, nil
+}
+
+// checkFiles verifies the checksums listed in the files and returns the exit code.
+func checkFiles(names []string, quiet bool, workers int, stdin io.Reader, stdout, stderr io.Writer) int {
+	var jobs []job
+	exit := 0
+	for _, name := range names {
+		var f *os.File
+		r := stdin
+		if name != "-" {
+			var err error
+			if f, err = os.Open(name); err != nil {
+				fmt.Fprintf(stderr, "sm3sum: %v\n", err)
+				exit = 1
+				continue
+			}
+			r = f
+		}
+		scanner := bufio.NewScanner(r)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSuffix(scanner.Text(), "\r")
+			if line == "" {
+				continue
+			}
+			j, err := parseCheckLine(line)
+			if err != nil {
+				fmt.Fprintf(stderr, "sm3sum: %s: %d: %v\n", name, n, err)
+				exit = 1
+				continue
+			}
+			jobs = append(jobs, j)
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stderr, "sm3sum: %s: %v\n", name, err)
+			exit = 1
+		}
+		if f != nil {
+			f.Close()
+		}
+	}
+
+	var mismatched, unreadable int
+	hashFiles(jobs, workers, stdin, func(j job, r result) {
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(stderr, "sm3sum: %v\n", r.err)
+			fmt.Fprintf(stdout, "%s: FAILED open or read\n", j.name)
+			unreadable++
+		case hex.EncodeToString(r.sum[:]) != j.want:
+			fmt.Fprintf(stdout, "%s: FAILED\n", j.name)
+			mismatched++
+		case !quiet:
+			fmt.Fprintf(stdout, "%s: OK\n", j.name)
+		}
+	})
+	if unreadable > 0 {
+		fmt.Fprintf(stderr, "sm3sum: WARNING: %d listed file(s) could not be read\n", unreadable)
+		exit = 1
+	}
+	if mismatched > 0 {
+		fmt.Fprintf(stderr, "sm3sum: WARNING: %d computed checksum(s) did NOT match\n", mismatched)
+		exit = 1
+	}
+	return exit
+}
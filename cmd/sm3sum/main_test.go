@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sm3("abc") and sm3("") from GB/T 32905-2016 and OpenSSL.
+const (
+	sumABC   = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	sumEmpty = "1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runSM3Sum(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestSum(t *testing.T) {
+	code, stdout, _ := runSM3Sum("abc")
+	if code != 0 || stdout != sumABC+"  -\n" {
+		t.Errorf("stdin: code %d, output %q", code, stdout)
+	}
+
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "sub", "b")
+	writeFile(t, a, "abc")
+	writeFile(t, b, "")
+	code, stdout, _ = runSM3Sum("", "-j", "2", a, b)
+	if want := sumABC + "  " + a + "\n" + sumEmpty + "  " + b + "\n"; code != 0 || stdout != want {
+		t.Errorf("files: code %d, output %q, want %q", code, stdout, want)
+	}
+
+	code, _, stderr := runSM3Sum("", dir)
+	if code != 1 || !strings.Contains(stderr, "is a directory") {
+		t.Errorf("directory without -r: code %d, stderr %q", code, stderr)
+	}
+	code, stdout, _ = runSM3Sum("", "-r", dir)
+	if want := sumABC + "  " + a + "\n" + sumEmpty + "  " + b + "\n"; code != 0 || stdout != want {
+		t.Errorf("recursive: code %d, output %q, want %q", code, stdout, want)
+	}
+
+	code, _, _ = runSM3Sum("", filepath.Join(dir, "missing"))
+	if code != 1 {
+		t.Errorf("missing file: code %d", code)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i, content := range []string{"abc", "", "hello", "world"} {
+		name := filepath.Join(dir, "d", string(rune('a'+i)))
+		writeFile(t, name, content)
+		files = append(files, name)
+	}
+	code, sums, _ := runSM3Sum("", append([]string{"-j", "3"}, files...)...)
+	if code != 0 {
+		t.Fatalf("sum: code %d", code)
+	}
+	list := filepath.Join(dir, "SM3SUMS")
+	writeFile(t, list, sums)
+
+	code, stdout, _ := runSM3Sum("", "-c", list)
+	if code != 0 || strings.Count(stdout, ": OK\n") != len(files) {
+		t.Errorf("check: code %d, output %q", code, stdout)
+	}
+	code, stdout, _ = runSM3Sum(sums, "-c", "-quiet")
+	if code != 0 || stdout != "" {
+		t.Errorf("quiet check from stdin: code %d, output %q", code, stdout)
+	}
+
+	writeFile(t, files[2], "changed")
+	os.Remove(files[3])
+	writeFile(t, list, sums+"not a checksum line\n")
+	code, stdout, stderr := runSM3Sum("", "-c", list)
+	if code != 1 {
+		t.Errorf("failed check: code %d", code)
+	}
+	for _, want := range []string{files[0] + ": OK", files[2] + ": FAILED\n", files[3] + ": FAILED open or read"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("failed check: output %q should contain %q", stdout, want)
+		}
+	}
+	for _, want := range []string{"improperly formatted", "1 computed checksum(s) did NOT match", "1 listed file(s) could not be read"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("failed check: stderr %q should contain %q", stderr, want)
+		}
+	}
+}
+
+func TestParseCheckLine(t *testing.T) {
+	tests := []struct {
+		line, name string
+		ok         bool
+	}{
+		{sumABC + "  file", "file", true},
+		{sumABC + " *file name", "file name", true},
+		{strings.ToUpper(sumABC) + "  file", "file", true},
+		{sumABC + "  ", "", false},
+		{sumABC + "file", "", false},
+		{sumABC[1:] + "   file", "", false},
+		{strings.Replace(sumABC, "6", "x", 1) + "  file", "", false},
+	}
+	for i, tt := range tests {
+		j, err := parseCheckLine(tt.line)
+		if (err == nil) != tt.ok || j.name != tt.name {
+			t.Errorf("case %d: got %q, %v", i, j.name, err)
+		}
+		if tt.ok && j.want != sumABC {
+			t.Errorf("case %d: checksum %q", i, j.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Command sm4crypt encrypts and decrypts files with SM4 in GCM or CBC mode.
+//
+// Usage:
+//
+//	sm4crypt [-d] [-mode gcm|cbc] (-key hex | -keyfile file) [-in file] [-out file]
+//
+// The key is 16 bytes in hex, the key file contains the hex encoded key. The input
+// and output default to the standard input and output.
+//
+// The GCM output is the random 12 bytes nonce, the ciphertext and the 16 bytes tag.
+// The CBC output is the random 16 bytes IV and the PKCS#7 padded ciphertext, it's
+// NOT authenticated, so prefer GCM unless CBC is required for interoperability.
+// The whole input is processed in memory.
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/emmansun/gmsm/padding"
+	"github.com/emmansun/gmsm/sm4"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs sm4crypt with the arguments and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sm4crypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	decrypt := flags.Bool("d", false, "decrypt instead of encrypt")
+	mode := flags.String("mode", "gcm", "cipher mode, gcm or cbc")
+	keyHex := flags.String("key", "", "hex encoded 16 bytes key")
+	keyFile := flags.String("keyfile", "", "file containing the hex encoded key")
+	in := flags.String("in", "", "input file (default standard input)")
+	out := flags.String("out", "", "output file (default standard output)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "sm4crypt: unexpected argument %q\n", flags.Arg(0))
+		return 2
+	}
+	if err := crypt(*decrypt, *mode, *keyHex, *keyFile, *in, *out, stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "sm4crypt: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func crypt(decrypt bool, mode, keyHex, keyFile, in, out string, stdin io.Reader, stdout io.Writer) error {
+	key, err := loadKey(keyHex, keyFile)
+	if err != nil {
+		return err
+	}
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	var input []byte
+	if in == "" {
+		input, err = io.ReadAll(stdin)
+	} else {
+		input, err = os.ReadFile(in)
+	}
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	switch {
+	case mode == "gcm" && decrypt:
+		output, err = decryptGCM(block, input)
+	case mode == "gcm":
+		output, err = encryptGCM(block, input, rand.Reader)
+	case mode == "cbc" && decrypt:
+		output, err = decryptCBC(block, input)
+	case mode == "cbc":
+		output, err = encryptCBC(block, input, rand.Reader)
+	default:
+		return fmt.Errorf("unsupported mode %q", mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = stdout.Write(output)
+		return err
+	}
+	return os.WriteFile(out, output, 0600)
+}
+
+// loadKey returns the key from the hex string or the file, exactly one of them
+// must be given.
+func loadKey(keyHex, keyFile string) ([]byte, error) {
+	if (keyHex == "") == (keyFile == "") {
+		return nil, errors.New("exactly one of -key and -keyfile is required")
+	}
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		keyHex = string(bytes.TrimSpace(b))
+	}
+	key, err := hex.DecodeString(keyHex)
+	if err != nil || len(key) != sm4.BlockSize {
+		return nil, errors.New("invalid key, it must be 16 bytes in hex")
+	}
+	return key, nil
+}
+
+func encryptGCM(block cipher.Block, plaintext []byte, random io.Reader) ([]byte, error) {
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func decryptGCM(block cipher.Block, ciphertext []byte) ([]byte, error) {
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("decryption failed, wrong key or corrupted input")
+	}
+	return plaintext, nil
+}
+
+func encryptCBC(block cipher.Block, plaintext []byte, random io.Reader) ([]byte, error) {
+	plaintext = padding.NewPKCS7Padding(sm4.BlockSize).Pad(plaintext)
+	ciphertext := make([]byte, sm4.BlockSize+len(plaintext))
+	iv := ciphertext[:sm4.BlockSize]
+	if _, err := io.ReadFull(random, iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext[sm4.BlockSize:], plaintext)
+	return ciphertext, nil
+}
+
+func decryptCBC(block cipher.Block, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 2*sm4.BlockSize || len(ciphertext)%sm4.BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext length")
+	}
+	iv, ciphertext := ciphertext[:sm4.BlockSize], ciphertext[sm4.BlockSize:]
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	plaintext, err := padding.NewPKCS7Padding(sm4.BlockSize).Unpad(plaintext)
+	if err != nil {
+		return nil, errors.New("decryption failed, wrong key or corrupted input")
+	}
+	return plaintext, nil
+}
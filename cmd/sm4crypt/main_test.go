@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKey = "0123456789abcdeffedcba9876543210"
+
+func runSM4Crypt(stdin []byte, args ...string) (int, []byte, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.Bytes(), stderr.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, mode := range []string{"gcm", "cbc"} {
+		for _, n := range []int{0, 1, 15, 16, 17, 1000} {
+			plaintext := bytes.Repeat([]byte{'a'}, n)
+			code, ciphertext, stderr := runSM4Crypt(plaintext, "-mode", mode, "-key", testKey)
+			if code != 0 {
+				t.Fatalf("%s encrypt %d bytes: %s", mode, n, stderr)
+			}
+			code, got, stderr := runSM4Crypt(ciphertext, "-d", "-mode", mode, "-key", testKey)
+			if code != 0 {
+				t.Fatalf("%s decrypt %d bytes: %s", mode, n, stderr)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("%s %d bytes: round trip mismatch", mode, n)
+			}
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	keyFile, in := filepath.Join(dir, "key"), filepath.Join(dir, "plain")
+	enc, dec := filepath.Join(dir, "enc"), filepath.Join(dir, "dec")
+	if err := os.WriteFile(keyFile, []byte(testKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(in, []byte("sm4 file encryption"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runSM4Crypt(nil, "-keyfile", keyFile, "-in", in, "-out", enc); code != 0 {
+		t.Fatal(stderr)
+	}
+	if code, _, stderr := runSM4Crypt(nil, "-d", "-keyfile", keyFile, "-in", enc, "-out", dec); code != 0 {
+		t.Fatal(stderr)
+	}
+	got, err := os.ReadFile(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "sm4 file encryption" {
+		t.Errorf("decrypted %q", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, ciphertext, _ := runSM4Crypt([]byte("secret"), "-key", testKey)
+	ciphertext[len(ciphertext)-1] ^= 1
+	wrongKey := strings.Repeat("00", 16)
+	tests := []struct {
+		stdin []byte
+		args  []string
+		want  string
+	}{
+		{nil, []string{}, "exactly one of -key and -keyfile"},
+		{nil, []string{"-key", testKey, "-keyfile", "k"}, "exactly one of -key and -keyfile"},
+		{nil, []string{"-key", "0011"}, "invalid key"},
+		{nil, []string{"-key", testKey, "-mode", "ecb"}, "unsupported mode"},
+		{ciphertext, []string{"-d", "-key", testKey}, "decryption failed"},
+		{ciphertext[:10], []string{"-d", "-key", testKey}, "ciphertext too short"},
+		{make([]byte, 33), []string{"-d", "-mode", "cbc", "-key", testKey}, "invalid ciphertext length"},
+		{make([]byte, 32), []string{"-d", "-mode", "cbc", "-key", wrongKey}, "decryption failed"},
+	}
+	for i, tt := range tests {
+		code, _, stderr := runSM4Crypt(tt.stdin, tt.args...)
+		if code == 0 || !strings.Contains(stderr, tt.want) {
+			t.Errorf("case %d: code %d, stderr %q, want %q", i, code, stderr, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package keyexchange implements the state machine, the message encoding and the
+// transcript hash shared by the SM2 and SM9 key exchange protocols.
+package keyexchange
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/emmansun/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// State is the step of the key exchange flow, the methods of a key exchange
+// must be called in order:
+//
+//	initiator: StateNew -> InitKeyExchange -> StateInitiated -> ConfirmResponder -> StateCompleted
+//	responder: StateNew -> RepondKeyExchange -> StateResponded -> ConfirmInitiator -> StateCompleted
+//
+// A failed verification or Destroy closes the key exchange.
+type State int
+
+const (
+	StateNew State = iota
+	StateInitiated
+	StateResponded
+	StateCompleted
+	StateClosed
+)
+
+// HasTranscript reports whether the transcript of the key exchange is complete, that is
+// after ConfirmResponder (initiator) or RepondKeyExchange (responder).
+func (s State) HasTranscript() bool {
+	return s == StateResponded || s == StateCompleted
+}
+
+var (
+	ErrInvalidMessage = errors.New("invalid key exchange message asn.1 data")
+	ErrUnexpectedType = errors.New("unexpected key exchange message type")
+)
+
+// The messages of the key exchange, each message is tagged by its type, the
+// ephemeral public keys are uncompressed points:
+//
+//	KeyExchangeInit ::= SEQUENCE {
+//	  type INTEGER (1),
+//	  rA   BIT STRING
+//	}
+//
+//	KeyExchangeResponse ::= SEQUENCE {
+//	  type INTEGER (2),
+//	  rB   BIT STRING,
+//	  sB   OCTET STRING OPTIONAL
+//	}
+//
+//	KeyExchangeConfirm ::= SEQUENCE {
+//	  type INTEGER (3),
+//	  sA   OCTET STRING
+//	}
+const (
+	initType     = 1
+	responseType = 2
+	confirmType  = 3
+)
+
+func marshal(msgType int64, f func(b *cryptobyte.Builder)) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(msgType)
+		f(b)
+	})
+	return b.Bytes()
+}
+
+func readMessage(der []byte, msgType int64) (cryptobyte.String, error) {
+	var (
+		inner cryptobyte.String
+		t     int64
+	)
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(&t) {
+		return nil, ErrInvalidMessage
+	}
+	if t != msgType {
+		return nil, ErrUnexpectedType
+	}
+	return inner, nil
+}
+
+func readPoint(s *cryptobyte.String) ([]byte, error) {
+	var point []byte
+	if !s.ReadASN1BitStringAsBytes(&point) || len(point) == 0 {
+		return nil, ErrInvalidMessage
+	}
+	return point, nil
+}
+
+// MarshalInit returns the KeyExchangeInit message of the initiator's ephemeral public key.
+func MarshalInit(rA []byte) ([]byte, error) {
+	return marshal(initType, func(b *cryptobyte.Builder) {
+		b.AddASN1BitString(rA)
+	})
+}
+
+// UnmarshalInit returns the initiator's ephemeral public key of the KeyExchangeInit message.
+func UnmarshalInit(der []byte) ([]byte, error) {
+	inner, err := readMessage(der, initType)
+	if err != nil {
+		return nil, err
+	}
+	rA, err := readPoint(&inner)
+	if err != nil {
+		return nil, err
+	}
+	if !inner.Empty() {
+		return nil, ErrInvalidMessage
+	}
+	return rA, nil
+}
+
+// MarshalResponse returns the KeyExchangeResponse message of the responder's ephemeral
+// public key and optional signature.
+func MarshalResponse(rB, sB []byte) ([]byte, error) {
+	return marshal(responseType, func(b *cryptobyte.Builder) {
+		b.AddASN1BitString(rB)
+		if len(sB) > 0 {
+			b.AddASN1OctetString(sB)
+		}
+	})
+}
+
+// UnmarshalResponse returns the responder's ephemeral public key and optional signature
+// of the KeyExchangeResponse message.
+func UnmarshalResponse(der []byte) (rB, sB []byte, err error) {
+	inner, err := readMessage(der, responseType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rB, err = readPoint(&inner); err != nil {
+		return nil, nil, err
+	}
+	if !inner.Empty() && (!inner.ReadASN1Bytes(&sB, asn1.OCTET_STRING) || !inner.Empty()) {
+		return nil, nil, ErrInvalidMessage
+	}
+	return rB, sB, nil
+}
+
+// MarshalConfirm returns the KeyExchangeConfirm message of the initiator's signature.
+func MarshalConfirm(sA []byte) ([]byte, error) {
+	return marshal(confirmType, func(b *cryptobyte.Builder) {
+		b.AddASN1OctetString(sA)
+	})
+}
+
+// UnmarshalConfirm returns the initiator's signature of the KeyExchangeConfirm message.
+func UnmarshalConfirm(der []byte) ([]byte, error) {
+	inner, err := readMessage(der, confirmType)
+	if err != nil {
+		return nil, err
+	}
+	var sA []byte
+	if !inner.ReadASN1Bytes(&sA, asn1.OCTET_STRING) || !inner.Empty() {
+		return nil, ErrInvalidMessage
+	}
+	return sA, nil
+}
+
+// TranscriptHash returns the SM3 hash of the key exchange transcript:
+//
+//	SM3(len(IDA) || IDA || len(IDB) || IDB || RA || RB || SB)
+//
+// where A is the initiator, B is the responder, the lengths are 4 bytes big endian,
+// RA and RB are the encoded ephemeral public keys and SB is the optional signature of
+// the responder.
+func TranscriptHash(idA, idB, rA, rB, sB []byte) []byte {
+	var l [4]byte
+	h := sm3.New()
+	binary.BigEndian.PutUint32(l[:], uint32(len(idA)))
+	h.Write(l[:])
+	h.Write(idA)
+	binary.BigEndian.PutUint32(l[:], uint32(len(idB)))
+	h.Write(l[:])
+	h.Write(idB)
+	h.Write(rA)
+	h.Write(rB)
+	h.Write(sB)
+	return h.Sum(nil)
+}
@@ -0,0 +1,64 @@
+package keyexchange
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	point := []byte{4, 1, 2, 3}
+	sig := []byte{5, 6, 7}
+
+	der, err := MarshalInit(point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rA, err := UnmarshalInit(der)
+	if err != nil || !bytes.Equal(rA, point) {
+		t.Fatalf("UnmarshalInit: got %x, %v", rA, err)
+	}
+	if _, _, err := UnmarshalResponse(der); err != ErrUnexpectedType {
+		t.Fatalf("expected ErrUnexpectedType, got %v", err)
+	}
+
+	for _, sB := range [][]byte{sig, nil} {
+		if der, err = MarshalResponse(point, sB); err != nil {
+			t.Fatal(err)
+		}
+		rB, s, err := UnmarshalResponse(der)
+		if err != nil || !bytes.Equal(rB, point) || !bytes.Equal(s, sB) {
+			t.Fatalf("UnmarshalResponse: got %x, %x, %v", rB, s, err)
+		}
+	}
+
+	if der, err = MarshalConfirm(sig); err != nil {
+		t.Fatal(err)
+	}
+	sA, err := UnmarshalConfirm(der)
+	if err != nil || !bytes.Equal(sA, sig) {
+		t.Fatalf("UnmarshalConfirm: got %x, %v", sA, err)
+	}
+	if _, err := UnmarshalInit(der); err != ErrUnexpectedType {
+		t.Fatalf("expected ErrUnexpectedType, got %v", err)
+	}
+
+	for _, bad := range [][]byte{nil, {0x30, 0x00}, append(der, 0)} {
+		if _, err := UnmarshalConfirm(bad); err != ErrInvalidMessage {
+			t.Fatalf("%x: expected ErrInvalidMessage, got %v", bad, err)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	for s, want := range map[State]bool{
+		StateNew:       false,
+		StateInitiated: false,
+		StateResponded: true,
+		StateCompleted: true,
+		StateClosed:    false,
+	} {
+		if s.HasTranscript() != want {
+			t.Errorf("state %d: HasTranscript() = %v, want %v", s, !want, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//go:build (!amd64 && !arm64) || purego
+
+package sm2ec
+
+import "github.com/emmansun/gmsm/internal/sm2ec/fiat"
+
+// BatchBytesX returns the encodings of the x-coordinates of points, as
+// specified in SEC 1, Version 2.0, Section 2.3.5. Only one field inversion is
+// performed for the whole batch (Montgomery's trick). The result of the point
+// at infinity is nil.
+//
+// This function is NOT constant-time, it's used for public values only, e.g.
+// signature verification.
+func BatchBytesX(points []*SM2P256Point) [][]byte {
+	n := len(points)
+	if n == 0 {
+		return nil
+	}
+	// acc[i] = Z[0] * Z[1] * ... * Z[i]
+	one := new(fiat.SM2P256Element).One()
+	zs := make([]*fiat.SM2P256Element, n)
+	acc := make([]fiat.SM2P256Element, n)
+	prev := one
+	for i, p := range points {
+		zs[i] = p.z
+		if p.z.IsZero() == 1 {
+			zs[i] = one
+		}
+		acc[i].Mul(prev, zs[i])
+		prev = &acc[i]
+	}
+
+	inv := new(fiat.SM2P256Element).Invert(&acc[n-1])
+
+	out := make([][]byte, n)
+	x := new(fiat.SM2P256Element)
+	for i := n - 1; i >= 0; i-- {
+		// x = Z[i]⁻¹ = inv * acc[i-1]
+		if i > 0 {
+			x.Mul(inv, &acc[i-1])
+			inv.Mul(inv, zs[i])
+		} else {
+			x.Set(inv)
+		}
+		if points[i].z.IsZero() == 1 {
+			continue
+		}
+		out[i] = x.Mul(points[i].x, x).Bytes()
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+//go:build (amd64 && !purego) || (arm64 && !purego)
+
+package sm2ec
+
+// BatchBytesX returns the encodings of the x-coordinates of points, as
+// specified in SEC 1, Version 2.0, Section 2.3.5. Only one field inversion is
+// performed for the whole batch (Montgomery's trick). The result of the point
+// at infinity is nil.
+//
+// This function is NOT constant-time, it's used for public values only, e.g.
+// signature verification.
+func BatchBytesX(points []*SM2P256Point) [][]byte {
+	n := len(points)
+	if n == 0 {
+		return nil
+	}
+	// zz[i] = Z[i]², acc[i] = zz[0] * zz[1] * ... * zz[i]
+	zz := make([]p256Element, n)
+	acc := make([]p256Element, n)
+	prev := p256One
+	for i, p := range points {
+		if p.isInfinity() == 1 {
+			zz[i] = p256One
+		} else {
+			p256Sqr(&zz[i], &p.z, 1)
+		}
+		p256Mul(&acc[i], &prev, &zz[i])
+		prev = acc[i]
+	}
+
+	inv := new(p256Element)
+	p256Inverse(inv, &acc[n-1])
+
+	out := make([][]byte, n)
+	buf := make([]byte, n*p256ElementLength)
+	x := new(p256Element)
+	for i := n - 1; i >= 0; i-- {
+		// x = (zz[i])⁻¹ = inv * acc[i-1]
+		if i > 0 {
+			p256Mul(x, inv, &acc[i-1])
+			p256Mul(inv, inv, &zz[i])
+		} else {
+			*x = *inv
+		}
+		if points[i].isInfinity() == 1 {
+			continue
+		}
+		p256Mul(x, &points[i].x, x)
+		p256FromMont(x, x)
+		out[i] = buf[i*p256ElementLength : (i+1)*p256ElementLength]
+		p256LittleToBig((*[32]byte)(out[i]), x)
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package sm2ec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestBatchBytesX(t *testing.T) {
+	if out := BatchBytesX(nil); out != nil {
+		t.Fatalf("expected nil result for empty batch")
+	}
+	points := make([]*SM2P256Point, 10)
+	for i := range points {
+		if i == 3 || i == 7 {
+			points[i] = NewSM2P256Point()
+			continue
+		}
+		scalar := make([]byte, 32)
+		io.ReadFull(rand.Reader, scalar)
+		p, err := NewSM2P256Point().ScalarBaseMult(scalar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// make Z not equal to one
+		points[i] = p.Add(p, NewSM2P256Point().SetGenerator())
+	}
+	out := BatchBytesX(points)
+	if len(out) != len(points) {
+		t.Fatalf("got %v results, want %v", len(out), len(points))
+	}
+	for i, p := range points {
+		expected, err := p.BytesX()
+		if err != nil {
+			if out[i] != nil {
+				t.Errorf("#%v: expected nil for point at infinity", i)
+			}
+			continue
+		}
+		if !bytes.Equal(out[i], expected) {
+			t.Errorf("#%v: got %x, want %x", i, out[i], expected)
+		}
+	}
+}
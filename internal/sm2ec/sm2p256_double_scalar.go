@@ -0,0 +1,76 @@
+//go:build (!amd64 && !arm64) || purego
+
+package sm2ec
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/emmansun/gmsm/internal/sm2ec/fiat"
+)
+
+var (
+	// sm2p256GeneratorOddTable[i] = (2i+1) × generator
+	sm2p256GeneratorOddTable     *[16]*SM2P256Point
+	sm2p256GeneratorOddTableOnce sync.Once
+)
+
+// oddMultiples returns the points (2i+1)q, i in [0, n).
+func oddMultiples(q *SM2P256Point, n int) []*SM2P256Point {
+	table := make([]*SM2P256Point, n)
+	q2 := NewSM2P256Point().Double(q)
+	table[0] = NewSM2P256Point().Set(q)
+	for i := 1; i < n; i++ {
+		table[i] = NewSM2P256Point().Add(table[i-1], q2)
+	}
+	return table
+}
+
+// VarTimeDoubleScalarBaseMult sets r = s × generator + t × q, where s and t are
+// 32-byte big endian values, and returns r. If s or t is not 32 bytes long, it
+// returns an error and the receiver is unchanged.
+//
+// The two scalar multiplications are interleaved (Straus-Shamir) over the wNAF
+// of the scalars, sharing the doublings.
+//
+// This function is NOT constant-time, it's used for public values only, e.g.
+// signature verification.
+func (r *SM2P256Point) VarTimeDoubleScalarBaseMult(s []byte, q *SM2P256Point, t []byte) (*SM2P256Point, error) {
+	if len(s) != 32 || len(t) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+	sm2p256GeneratorOddTableOnce.Do(func() {
+		sm2p256GeneratorOddTable = (*[16]*SM2P256Point)(oddMultiples(NewSM2P256Point().SetGenerator(), 16))
+	})
+	var sNAF, tNAF [wnafLength]int8
+	wnaf(&sNAF, (*[32]byte)(s), 6)
+	wnaf(&tNAF, (*[32]byte)(t), 5)
+	table := oddMultiples(q, 8)
+
+	acc := NewSM2P256Point()
+	tmp := NewSM2P256Point()
+	zero := new(fiat.SM2P256Element)
+	add := func(p *SM2P256Point, d int8) {
+		if d > 0 {
+			acc.Add(acc, p)
+			return
+		}
+		tmp.Set(p)
+		tmp.y.Sub(zero, tmp.y)
+		acc.Add(acc, tmp)
+	}
+	for i := wnafLength - 1; i >= 0; i-- {
+		acc.Double(acc)
+		if d := tNAF[i]; d > 0 {
+			add(table[d/2], d)
+		} else if d < 0 {
+			add(table[-d/2], d)
+		}
+		if d := sNAF[i]; d > 0 {
+			add(sm2p256GeneratorOddTable[d/2], d)
+		} else if d < 0 {
+			add(sm2p256GeneratorOddTable[-d/2], d)
+		}
+	}
+	return r.Set(acc), nil
+}
@@ -0,0 +1,92 @@
+//go:build (amd64 && !purego) || (arm64 && !purego)
+
+package sm2ec
+
+import "errors"
+
+// VarTimeDoubleScalarBaseMult sets r = s × generator + t × q, where s and t are
+// 32-byte big endian values, and returns r. If s or t is not 32 bytes long, it
+// returns an error and the receiver is unchanged.
+//
+// The two scalar multiplications are interleaved (Straus-Shamir) over the wNAF
+// of the scalars, sharing the doublings. The odd multiples of the generator come
+// from the precomputed table of ScalarBaseMult.
+//
+// This function is NOT constant-time, it's used for public values only, e.g.
+// signature verification.
+func (r *SM2P256Point) VarTimeDoubleScalarBaseMult(s []byte, q *SM2P256Point, t []byte) (*SM2P256Point, error) {
+	if len(s) != 32 || len(t) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+	var sNAF, tNAF [wnafLength]int8
+	wnaf(&sNAF, (*[32]byte)(s), 6)
+	wnaf(&tNAF, (*[32]byte)(t), 5)
+
+	// table[i] = (2i+1)q
+	var table [8]SM2P256Point
+	if q.isInfinity() == 1 {
+		tNAF = [wnafLength]int8{}
+	} else {
+		var q2 SM2P256Point
+		p256PointDoubleAsm(&q2, q)
+		table[0] = *q
+		for i := 1; i < len(table); i++ {
+			table[i] = table[i-1]
+			p256VarTimeAdd(&table[i], &q2)
+		}
+	}
+
+	var acc, tmp SM2P256Point
+	infinity := true
+	for i := wnafLength - 1; i >= 0; i-- {
+		if !infinity {
+			p256PointDoubleAsm(&acc, &acc)
+		}
+		if d := tNAF[i]; d != 0 {
+			if d > 0 {
+				tmp = table[d/2]
+			} else {
+				tmp = table[-d/2]
+				p256NegCond(&tmp.y, 1)
+			}
+			infinity = p256VarTimeAccumulate(&acc, &tmp, infinity)
+		}
+		if d := sNAF[i]; d != 0 {
+			var g *p256AffinePoint
+			if d > 0 {
+				g = &p256Precomputed[0][d-1]
+			} else {
+				g = &p256Precomputed[0][-d-1]
+			}
+			tmp.x, tmp.y, tmp.z = g.x, g.y, p256One
+			if d < 0 {
+				p256NegCond(&tmp.y, 1)
+			}
+			infinity = p256VarTimeAccumulate(&acc, &tmp, infinity)
+		}
+	}
+	if infinity {
+		return r.Set(NewSM2P256Point()), nil
+	}
+	return r.Set(&acc), nil
+}
+
+// p256VarTimeAccumulate sets acc = acc + p, p is not the point at infinity, and
+// reports whether acc is the point at infinity.
+func p256VarTimeAccumulate(acc, p *SM2P256Point, infinity bool) bool {
+	if infinity {
+		*acc = *p
+		return false
+	}
+	p256VarTimeAdd(acc, p)
+	return acc.isInfinity() == 1
+}
+
+// p256VarTimeAdd sets acc = acc + p, neither acc nor p is the point at infinity.
+func p256VarTimeAdd(acc, p *SM2P256Point) {
+	var sum SM2P256Point
+	if p256PointAddAsm(&sum, acc, p) == 1 {
+		p256PointDoubleAsm(&sum, acc)
+	}
+	*acc = sum
+}
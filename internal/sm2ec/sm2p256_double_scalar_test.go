@@ -0,0 +1,97 @@
+package sm2ec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func doubleScalarBaseMultReference(t *testing.T, s []byte, q *SM2P256Point, k []byte) *SM2P256Point {
+	t.Helper()
+	p1, err := NewSM2P256Point().ScalarBaseMult(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := NewSM2P256Point().ScalarMult(q, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p1.Add(p1, p2)
+}
+
+func TestVarTimeDoubleScalarBaseMult(t *testing.T) {
+	orderMinus1, _ := hex.DecodeString("fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54122")
+	zero := make([]byte, 32)
+	one := make([]byte, 32)
+	one[31] = 1
+	g := NewSM2P256Point().SetGenerator()
+	minusG, _ := NewSM2P256Point().ScalarBaseMult(orderMinus1)
+	random := func() []byte {
+		b := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	q, _ := NewSM2P256Point().ScalarBaseMult(random())
+	tests := []struct {
+		s []byte
+		q *SM2P256Point
+		t []byte
+	}{
+		{random(), q, random()},
+		{random(), q, random()},
+		{zero, q, random()},
+		{random(), q, zero},
+		{zero, q, zero},
+		{random(), NewSM2P256Point(), random()},
+		{one, g, one},
+		{orderMinus1, g, orderMinus1},
+		{one, minusG, one},
+		{orderMinus1, minusG, orderMinus1},
+		{bytes.Repeat([]byte{0xff}, 32), q, bytes.Repeat([]byte{0xff}, 32)},
+	}
+	for i, tt := range tests {
+		got, err := NewSM2P256Point().VarTimeDoubleScalarBaseMult(tt.s, tt.q, tt.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := doubleScalarBaseMultReference(t, tt.s, tt.q, tt.t)
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("#%d: got %x, want %x", i, got.Bytes(), want.Bytes())
+		}
+	}
+	if _, err := NewSM2P256Point().VarTimeDoubleScalarBaseMult(one[1:], q, one); err == nil {
+		t.Error("expected error for invalid scalar length")
+	}
+}
+
+func BenchmarkVarTimeDoubleScalarBaseMult(b *testing.B) {
+	s, t := make([]byte, 32), make([]byte, 32)
+	rand.Read(s)
+	rand.Read(t)
+	q, _ := NewSM2P256Point().ScalarBaseMult(t)
+	p := NewSM2P256Point()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.VarTimeDoubleScalarBaseMult(s, q, t)
+	}
+}
+
+func BenchmarkScalarBaseMultAndScalarMult(b *testing.B) {
+	s, t := make([]byte, 32), make([]byte, 32)
+	rand.Read(s)
+	rand.Read(t)
+	q, _ := NewSM2P256Point().ScalarBaseMult(t)
+	p1, p2 := NewSM2P256Point(), NewSM2P256Point()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p1.ScalarBaseMult(s)
+		p2.ScalarMult(q, t)
+		p1.Add(p1, p2)
+	}
+}
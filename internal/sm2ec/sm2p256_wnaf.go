@@ -0,0 +1,45 @@
+package sm2ec
+
+import "encoding/binary"
+
+// wnafLength is the max number of digits of the wNAF of a 256-bit scalar.
+const wnafLength = 257
+
+// wnaf sets naf to the width-w non-adjacent form of the 32-byte big endian scalar,
+// from the least significant digit. Each nonzero digit is odd and in
+// (-2^(w-1), 2^(w-1)), and any w consecutive digits have at most one nonzero digit.
+//
+// This function is NOT constant-time, it's used for public values only.
+func wnaf(naf *[wnafLength]int8, scalar *[32]byte, w uint) {
+	var k [5]uint64
+	for i := 0; i < 4; i++ {
+		k[i] = binary.BigEndian.Uint64(scalar[24-8*i:])
+	}
+	*naf = [wnafLength]int8{}
+	window := uint64(1) << w
+	for i := 0; i < wnafLength && (k[0]|k[1]|k[2]|k[3]|k[4]) != 0; i++ {
+		if k[0]&1 == 1 {
+			d := k[0] & (window - 1)
+			if d >= window/2 {
+				// k -= d - window, that is k += window - d
+				naf[i] = int8(int64(d) - int64(window))
+				carry := window - d
+				for j := 0; j < len(k) && carry != 0; j++ {
+					k[j] += carry
+					if k[j] < carry {
+						carry = 1
+					} else {
+						carry = 0
+					}
+				}
+			} else {
+				naf[i] = int8(d)
+				k[0] -= d
+			}
+		}
+		for j := 0; j < len(k)-1; j++ {
+			k[j] = k[j]>>1 | k[j+1]<<63
+		}
+		k[len(k)-1] >>= 1
+	}
+}
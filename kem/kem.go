@@ -0,0 +1,168 @@
+// Package kem implements a key encapsulation mechanism (KEM) abstraction for ShangMi(SM)
+// algorithms (SM2 via ecdh, SM9), and an HPKE-style public key encryption composed of a KEM and SM4-GCM.
+package kem
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/kdf"
+	"github.com/emmansun/gmsm/sm3"
+	"github.com/emmansun/gmsm/sm4"
+)
+
+// Encapsulator is the public (sender) side of a KEM.
+type Encapsulator interface {
+	// Encapsulate generates a shared key and its encapsulation for the recipient,
+	// the encapsulated key is always EncapsulatedKeySize bytes.
+	Encapsulate(rand io.Reader) (sharedKey, encapsulatedKey []byte, err error)
+	// EncapsulatedKeySize returns the size of the encapsulated key.
+	EncapsulatedKeySize() int
+	// SharedKeySize returns the size of the shared key.
+	SharedKeySize() int
+}
+
+// Decapsulator is the private (recipient) side of a KEM.
+type Decapsulator interface {
+	// Decapsulate recovers the shared key from the encapsulated key.
+	Decapsulate(encapsulatedKey []byte) (sharedKey []byte, err error)
+	// EncapsulatedKeySize returns the size of the encapsulated key.
+	EncapsulatedKeySize() int
+	// SharedKeySize returns the size of the shared key.
+	SharedKeySize() int
+}
+
+// ErrDecapsulation represents a failure to decapsulate a shared key.
+// It is deliberately vague to avoid adaptive attacks.
+var ErrDecapsulation = errors.New("kem: decapsulation error")
+
+// ErrOpen represents a failure to open a sealed message.
+var ErrOpen = errors.New("kem: message authentication failed")
+
+const (
+	sharedKeySize = 32
+	aeadKeySize   = 16
+	aeadNonceSize = 12
+)
+
+// Context is an HPKE-style encryption context established with a KEM. The key and the base
+// nonce of SM4-GCM are derived from the shared key and info:
+//
+//	key || baseNonce = KDF(sharedKey || info, 16 + 12)
+//
+// where KDF is the SM3 based KDF of GB/T 32918.4-2016. The nonce of the i-th message is
+// baseNonce xor i, so the messages must be opened in the order they were sealed.
+// A Context is not safe for concurrent use.
+type Context struct {
+	aead      cipher.AEAD
+	baseNonce []byte
+	seq       uint64
+}
+
+func newContext(sharedKey, info []byte) (*Context, error) {
+	var z []byte
+	z = append(z, sharedKey...)
+	z = append(z, info...)
+	k := kdf.Kdf(sm3.New(), z, aeadKeySize+aeadNonceSize)
+	block, err := sm4.NewCipher(k[:aeadKeySize])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Context{aead: aead, baseNonce: k[aeadKeySize:]}, nil
+}
+
+// NewSender encapsulates a shared key with the encapsulator and returns the encapsulated key,
+// which must be sent to the recipient, and the sender's encryption context.
+func NewSender(rand io.Reader, enc Encapsulator, info []byte) ([]byte, *Context, error) {
+	sharedKey, encapsulatedKey, err := enc.Encapsulate(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := newContext(sharedKey, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encapsulatedKey, ctx, nil
+}
+
+// NewReceiver decapsulates the shared key and returns the recipient's decryption context.
+func NewReceiver(dec Decapsulator, encapsulatedKey, info []byte) (*Context, error) {
+	sharedKey, err := dec.Decapsulate(encapsulatedKey)
+	if err != nil {
+		return nil, err
+	}
+	return newContext(sharedKey, info)
+}
+
+func (c *Context) nextNonce() ([]byte, error) {
+	if c.seq == ^uint64(0) {
+		return nil, errors.New("kem: message limit reached")
+	}
+	nonce := make([]byte, aeadNonceSize)
+	copy(nonce, c.baseNonce)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], c.seq)
+	for i := range seq {
+		nonce[aeadNonceSize-8+i] ^= seq[i]
+	}
+	c.seq++
+	return nonce, nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the additional data aad,
+// and returns the ciphertext.
+func (c *Context) Seal(aad, plaintext []byte) ([]byte, error) {
+	nonce, err := c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// Open decrypts and authenticates the ciphertext and the additional data aad,
+// and returns the plaintext.
+func (c *Context) Open(aad, ciphertext []byte) ([]byte, error) {
+	nonce, err := c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		c.seq--
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+// Seal encrypts a single message to the recipient of the encapsulator, the result is
+// encapsulated key || ciphertext.
+func Seal(rand io.Reader, enc Encapsulator, info, aad, plaintext []byte) ([]byte, error) {
+	encapsulatedKey, ctx, err := NewSender(rand, enc, info)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := ctx.Seal(aad, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(encapsulatedKey, ciphertext...), nil
+}
+
+// Open decrypts a single message sealed by Seal.
+func Open(dec Decapsulator, info, aad, sealed []byte) ([]byte, error) {
+	size := dec.EncapsulatedKeySize()
+	if len(sealed) < size {
+		return nil, ErrOpen
+	}
+	ctx, err := NewReceiver(dec, sealed[:size], info)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.Open(aad, sealed[size:])
+}
@@ -0,0 +1,145 @@
+package kem
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/ecdh"
+	"github.com/emmansun/gmsm/sm9"
+)
+
+func sm2Pair(t *testing.T) (Encapsulator, Decapsulator) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := NewSM2Encapsulator(priv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewSM2Decapsulator(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc, dec
+}
+
+func sm9Pair(t *testing.T) (Encapsulator, Decapsulator) {
+	master, err := sm9.GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("Bob")
+	priv, err := master.GenerateUserKey(uid, 0x03)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSM9Encapsulator(master.Public(), uid, 0x03), NewSM9Decapsulator(priv, uid)
+}
+
+var schemes = []struct {
+	name string
+	pair func(t *testing.T) (Encapsulator, Decapsulator)
+}{
+	{"SM2", sm2Pair},
+	{"SM9", sm9Pair},
+}
+
+func TestEncapsulateDecapsulate(t *testing.T) {
+	for _, s := range schemes {
+		t.Run(s.name, func(t *testing.T) {
+			enc, dec := s.pair(t)
+			key, encapsulatedKey, err := enc.Encapsulate(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(encapsulatedKey) != enc.EncapsulatedKeySize() || len(encapsulatedKey) != dec.EncapsulatedKeySize() {
+				t.Errorf("unexpected encapsulated key size %v", len(encapsulatedKey))
+			}
+			if len(key) != enc.SharedKeySize() || len(key) != dec.SharedKeySize() {
+				t.Errorf("unexpected shared key size %v", len(key))
+			}
+			got, err := dec.Decapsulate(encapsulatedKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key, got) {
+				t.Errorf("shared key mismatch")
+			}
+			if _, err := dec.Decapsulate(encapsulatedKey[1:]); err != ErrDecapsulation {
+				t.Errorf("expected ErrDecapsulation, got %v", err)
+			}
+			bad := append([]byte{}, encapsulatedKey...)
+			bad[len(bad)-1] ^= 1
+			if got, err := dec.Decapsulate(bad); err == nil && bytes.Equal(got, key) {
+				t.Errorf("tampered encapsulated key yields the same shared key")
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	info := []byte("kem test")
+	for _, s := range schemes {
+		t.Run(s.name, func(t *testing.T) {
+			enc, dec := s.pair(t)
+			sealed, err := Seal(rand.Reader, enc, info, []byte("aad"), []byte("hello world"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Open(dec, info, []byte("aad"), sealed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "hello world" {
+				t.Errorf("unexpected plaintext %q", got)
+			}
+			if _, err := Open(dec, []byte("other info"), []byte("aad"), sealed); err != ErrOpen {
+				t.Errorf("expected ErrOpen, got %v", err)
+			}
+			if _, err := Open(dec, info, nil, sealed); err != ErrOpen {
+				t.Errorf("expected ErrOpen, got %v", err)
+			}
+			if _, err := Open(dec, info, nil, sealed[:10]); err == nil {
+				t.Errorf("expected error for short message")
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	enc, dec := sm2Pair(t)
+	encapsulatedKey, sender, err := NewSender(rand.Reader, enc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := NewReceiver(dec, encapsulatedKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sealed [][]byte
+	for i := 0; i < 3; i++ {
+		ct, err := sender.Seal(nil, []byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sealed = append(sealed, ct)
+	}
+	if bytes.Equal(sealed[0], sealed[1]) {
+		t.Errorf("nonce reuse")
+	}
+	// out of order
+	if _, err := receiver.Open(nil, sealed[1]); err != ErrOpen {
+		t.Errorf("expected ErrOpen, got %v", err)
+	}
+	for i, ct := range sealed {
+		pt, err := receiver.Open(nil, ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pt, []byte{byte(i)}) {
+			t.Errorf("message %v mismatch", i)
+		}
+	}
+}
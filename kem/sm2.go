@@ -0,0 +1,86 @@
+package kem
+
+import (
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/ecdh"
+	"github.com/emmansun/gmsm/kdf"
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// sm2PublicKeySize is the size of an uncompressed SM2 public key.
+const sm2PublicKeySize = 65
+
+// SM2 KEM is a Diffie-Hellman based KEM over the SM2 curve:
+//
+//	encapsulated key = ephemeral public key E (uncompressed)
+//	shared key = KDF(x([e]PB) || E || PB, 32)
+
+type sm2Encapsulator struct {
+	pub *ecdh.PublicKey
+}
+
+type sm2Decapsulator struct {
+	priv *ecdh.PrivateKey
+}
+
+// NewSM2Encapsulator returns an Encapsulator for the SM2 ecdh public key of the recipient.
+func NewSM2Encapsulator(pub *ecdh.PublicKey) (Encapsulator, error) {
+	if pub.Curve() != ecdh.P256() {
+		return nil, errors.New("kem: unsupported curve")
+	}
+	return &sm2Encapsulator{pub: pub}, nil
+}
+
+// NewSM2Decapsulator returns a Decapsulator for the SM2 ecdh private key.
+func NewSM2Decapsulator(priv *ecdh.PrivateKey) (Decapsulator, error) {
+	if priv.Curve() != ecdh.P256() {
+		return nil, errors.New("kem: unsupported curve")
+	}
+	return &sm2Decapsulator{priv: priv}, nil
+}
+
+func sm2SharedKey(z, ephemeral, recipient []byte) []byte {
+	var buffer []byte
+	buffer = append(buffer, z...)
+	buffer = append(buffer, ephemeral...)
+	buffer = append(buffer, recipient...)
+	return kdf.Kdf(sm3.New(), buffer, sharedKeySize)
+}
+
+func (e *sm2Encapsulator) Encapsulate(rand io.Reader) ([]byte, []byte, error) {
+	ephemeral, err := ecdh.P256().GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := ephemeral.ECDH(e.pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	encapsulatedKey := ephemeral.PublicKey().Bytes()
+	return sm2SharedKey(z, encapsulatedKey, e.pub.Bytes()), encapsulatedKey, nil
+}
+
+func (e *sm2Encapsulator) EncapsulatedKeySize() int { return sm2PublicKeySize }
+
+func (e *sm2Encapsulator) SharedKeySize() int { return sharedKeySize }
+
+func (d *sm2Decapsulator) Decapsulate(encapsulatedKey []byte) ([]byte, error) {
+	if len(encapsulatedKey) != sm2PublicKeySize {
+		return nil, ErrDecapsulation
+	}
+	ephemeral, err := ecdh.P256().NewPublicKey(encapsulatedKey)
+	if err != nil {
+		return nil, ErrDecapsulation
+	}
+	z, err := d.priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrDecapsulation
+	}
+	return sm2SharedKey(z, encapsulatedKey, d.priv.PublicKey().Bytes()), nil
+}
+
+func (d *sm2Decapsulator) EncapsulatedKeySize() int { return sm2PublicKeySize }
+
+func (d *sm2Decapsulator) SharedKeySize() int { return sharedKeySize }
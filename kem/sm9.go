@@ -0,0 +1,68 @@
+package kem
+
+import (
+	"io"
+
+	"github.com/emmansun/gmsm/sm9"
+	"github.com/emmansun/gmsm/sm9/bn256"
+)
+
+// sm9CipherSize is the size of the uncompressed G1 point C of SM9 key encapsulation.
+const sm9CipherSize = 65
+
+// SM9 KEM is the key encapsulation of SM9 (GB/T 38635.2-2020 part 4), see sm9.WrapKey:
+//
+//	encapsulated key = C = [r]QB (uncompressed)
+//	shared key = KDF(C || e(Ppub-e, P2)^r || IDB, 32)
+
+type sm9Encapsulator struct {
+	pub *sm9.EncryptMasterPublicKey
+	uid []byte
+	hid byte
+}
+
+type sm9Decapsulator struct {
+	priv *sm9.EncryptPrivateKey
+	uid  []byte
+}
+
+// NewSM9Encapsulator returns an Encapsulator for the identity (uid, hid) under the encrypt master public key.
+func NewSM9Encapsulator(pub *sm9.EncryptMasterPublicKey, uid []byte, hid byte) Encapsulator {
+	return &sm9Encapsulator{pub: pub, uid: append([]byte{}, uid...), hid: hid}
+}
+
+// NewSM9Decapsulator returns a Decapsulator for the encrypt private key of uid.
+func NewSM9Decapsulator(priv *sm9.EncryptPrivateKey, uid []byte) Decapsulator {
+	return &sm9Decapsulator{priv: priv, uid: append([]byte{}, uid...)}
+}
+
+func (e *sm9Encapsulator) Encapsulate(rand io.Reader) ([]byte, []byte, error) {
+	key, cipher, err := sm9.WrapKey(rand, e.pub, e.uid, e.hid, sharedKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cipher.MarshalUncompressed(), nil
+}
+
+func (e *sm9Encapsulator) EncapsulatedKeySize() int { return sm9CipherSize }
+
+func (e *sm9Encapsulator) SharedKeySize() int { return sharedKeySize }
+
+func (d *sm9Decapsulator) Decapsulate(encapsulatedKey []byte) ([]byte, error) {
+	if len(encapsulatedKey) != sm9CipherSize || encapsulatedKey[0] != 4 {
+		return nil, ErrDecapsulation
+	}
+	cipher := new(bn256.G1)
+	if _, err := cipher.Unmarshal(encapsulatedKey[1:]); err != nil {
+		return nil, ErrDecapsulation
+	}
+	key, err := sm9.UnwrapKey(d.priv, d.uid, cipher, sharedKeySize)
+	if err != nil {
+		return nil, ErrDecapsulation
+	}
+	return key, nil
+}
+
+func (d *sm9Decapsulator) EncapsulatedKeySize() int { return sm9CipherSize }
+
+func (d *sm9Decapsulator) SharedKeySize() int { return sharedKeySize }
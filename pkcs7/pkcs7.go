@@ -17,6 +17,7 @@ import (
 	_ "crypto/sha1" // for crypto.SHA1
 
 	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm9"
 	"github.com/emmansun/gmsm/smx509"
 )
 
@@ -185,6 +186,8 @@ func getOIDForEncryptionAlgorithm(pkey any, OIDDigestAlg asn1.ObjectIdentifier)
 		}
 	case *sm2.PrivateKey:
 		return OIDDigestEncryptionAlgorithmSM2, nil
+	case *sm9.SignPrivateKey:
+		return OIDDigestEncryptionAlgorithmSM9, nil
 	case *dsa.PrivateKey, *dsa.PublicKey:
 		return OIDDigestAlgorithmDSA, nil
 	case crypto.Signer:
@@ -215,7 +218,7 @@ func Parse(data []byte) (p7 *PKCS7, err error) {
 
 	// fmt.Printf("--> Content Type: %s", info.ContentType)
 	switch {
-	case info.ContentType.Equal(OIDSignedData) || info.ContentType.Equal(SM2OIDSignedData):
+	case info.ContentType.Equal(OIDSignedData) || info.ContentType.Equal(SM2OIDSignedData) || info.ContentType.Equal(SM9OIDSignedData):
 		return parseSignedData(info.Content.Bytes)
 	case info.ContentType.Equal(OIDEnvelopedData) || info.ContentType.Equal(SM2OIDEnvelopedData):
 		return parseEnvelopedData(info.Content.Bytes)
@@ -262,7 +265,7 @@ func (raw rawCertificates) Parse() ([]*smx509.Certificate, error) {
 }
 
 func isCertMatchForIssuerAndSerial(cert *smx509.Certificate, ias issuerAndSerial) bool {
-	return cert.SerialNumber.Cmp(ias.SerialNumber) == 0 && bytes.Equal(cert.RawIssuer, ias.IssuerName.FullBytes)
+	return ias.SerialNumber != nil && cert.SerialNumber.Cmp(ias.SerialNumber) == 0 && bytes.Equal(cert.RawIssuer, ias.IssuerName.FullBytes)
 }
 
 // Attribute represents a key value pair attribute. Value must be marshalable byte
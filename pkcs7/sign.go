@@ -14,6 +14,7 @@ import (
 
 	"github.com/emmansun/gmsm/sm2"
 	"github.com/emmansun/gmsm/sm3"
+	"github.com/emmansun/gmsm/sm9"
 	"github.com/emmansun/gmsm/smx509"
 )
 
@@ -25,6 +26,7 @@ type SignedData struct {
 	digestOid           asn1.ObjectIdentifier
 	encryptionOid       asn1.ObjectIdentifier
 	isSM                bool
+	isSM9               bool
 }
 
 // NewSignedData takes data and initializes a PKCS7 SignedData struct that is
@@ -60,6 +62,19 @@ func NewSMSignedData(data []byte) (*SignedData, error) {
 	return sd, nil
 }
 
+// NewSM9SignedData takes data and initializes a PKCS7 SignedData struct with
+// SM9 content types that is ready to be signed via AddSM9Signer. The digest
+// algorithm is set to SM3.
+func NewSM9SignedData(data []byte) (*SignedData, error) {
+	sd, err := NewSMSignedData(data)
+	if err != nil {
+		return nil, err
+	}
+	sd.sd.ContentInfo.ContentType = SM9OIDData
+	sd.isSM9 = true
+	return sd, nil
+}
+
 // SignerInfoConfig are optional values to include when adding a signer
 type SignerInfoConfig struct {
 	ExtraSignedAttributes   []Attribute
@@ -76,9 +91,13 @@ type signedData struct {
 	SignerInfos                []signerInfo           `asn1:"set"`
 }
 
+// signerInfo identifies the signer by either issuerAndSerialNumber or
+// subjectKeyIdentifier (CMS SignerIdentifier), the latter carries the
+// identity (uid) of an SM9 signer.
 type signerInfo struct {
-	Version                   int `asn1:"default:1"`
-	IssuerAndSerialNumber     issuerAndSerial
+	Version                   int             `asn1:"default:1"`
+	IssuerAndSerialNumber     issuerAndSerial `asn1:"optional"`
+	SubjectKeyIdentifier      []byte          `asn1:"optional,tag:0"`
 	DigestAlgorithm           pkix.AlgorithmIdentifier
 	AuthenticatedAttributes   []attribute `asn1:"optional,omitempty,tag:0"`
 	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
@@ -143,6 +162,9 @@ func (sd *SignedData) AddSigner(ee *smx509.Certificate, pkey crypto.PrivateKey,
 // The signature algorithm used to hash the data is the one of the end-entity
 // certificate.
 func (sd *SignedData) AddSignerChain(ee *smx509.Certificate, pkey crypto.PrivateKey, parents []*smx509.Certificate, config SignerInfoConfig) error {
+	if _, ok := pkey.(*sm9.SignPrivateKey); ok {
+		return errors.New("pkcs7: SM9 signers have no certificate, use AddSM9Signer")
+	}
 	// Following RFC 2315, 9.2 SignerInfo type, the distinguished name of
 	// the issuer of the end-entity signer is stored in the issuerAndSerialNumber
 	// section of the SignedData.SignerInfo, alongside the serial number of
@@ -160,19 +182,63 @@ func (sd *SignedData) AddSignerChain(ee *smx509.Certificate, pkey crypto.Private
 		// the first parent is the issuer
 		ias.IssuerName = asn1.RawValue{FullBytes: parents[0].RawSubject}
 	}
+	signer, err := sd.newSignerInfo(pkey, config)
+	if err != nil {
+		return err
+	}
+	signer.IssuerAndSerialNumber = ias
+
+	if !config.SkipCertificates {
+		sd.certs = append(sd.certs, ee)
+		if len(parents) > 0 {
+			sd.certs = append(sd.certs, parents...)
+		}
+	}
+	sd.sd.SignerInfos = append(sd.sd.SignerInfos, signer)
+	return nil
+}
+
+// AddSM9Signer signs attributes about the content with the SM9 sign private key
+// of the identity uid and adds the signer info to the Signed Data. The uid is
+// stored in the subjectKeyIdentifier of the signer info, there are no certificates,
+// the signature can be verified with the sign master public key via VerifySM9.
+//
+// The sd must be initialized by NewSM9SignedData.
+func (sd *SignedData) AddSM9Signer(uid []byte, pkey *sm9.SignPrivateKey, config SignerInfoConfig) error {
+	if !sd.isSM9 || !sd.digestOid.Equal(OIDDigestAlgorithmSM3) {
+		return errors.New("pkcs7: SM9 signer requires SM9 signed data with SM3 digest, see NewSM9SignedData")
+	}
+	if len(uid) == 0 {
+		return errors.New("pkcs7: empty SM9 signer uid")
+	}
+	signer, err := sd.newSignerInfo(pkey, config)
+	if err != nil {
+		return err
+	}
+	// subjectKeyIdentifier requires version 3, see RFC 5652, 5.3
+	signer.Version = 3
+	signer.SubjectKeyIdentifier = uid
+	sd.sd.Version = 3
+	sd.sd.SignerInfos = append(sd.sd.SignerInfos, signer)
+	return nil
+}
+
+// newSignerInfo signs the content type, message digest, signing time and extra signed
+// attributes with pkey, the signer identifier is left to the caller.
+func (sd *SignedData) newSignerInfo(pkey crypto.PrivateKey, config SignerInfoConfig) (signerInfo, error) {
 	sd.sd.DigestAlgorithmIdentifiers = append(sd.sd.DigestAlgorithmIdentifiers,
 		pkix.AlgorithmIdentifier{Algorithm: sd.digestOid},
 	)
 	hasher, err := getHashForOID(sd.digestOid)
 	if err != nil {
-		return err
+		return signerInfo{}, err
 	}
 	h := newHash(hasher, sd.digestOid)
 	h.Write(sd.data)
 	sd.messageDigest = h.Sum(nil)
 	encryptionOid, err := getOIDForEncryptionAlgorithm(pkey, sd.digestOid)
 	if err != nil {
-		return err
+		return signerInfo{}, err
 	}
 	attrs := &attributes{}
 	attrs.Add(OIDAttributeContentType, sd.sd.ContentInfo.ContentType)
@@ -183,33 +249,24 @@ func (sd *SignedData) AddSignerChain(ee *smx509.Certificate, pkey crypto.Private
 	}
 	finalAttrs, err := attrs.ForMarshalling()
 	if err != nil {
-		return err
+		return signerInfo{}, err
 	}
 	// create signature of signed attributes
 	signature, err := signAttributes(finalAttrs, pkey, hasher)
 	if err != nil {
-		return err
+		return signerInfo{}, err
 	}
 	signer := signerInfo{
 		AuthenticatedAttributes:   finalAttrs,
 		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: sd.digestOid},
 		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: encryptionOid},
-		IssuerAndSerialNumber:     ias,
 		EncryptedDigest:           signature,
 		Version:                   1,
 	}
 	if err = signer.SetUnauthenticatedAttributes(config.ExtraUnsignedAttributes); err != nil {
-		return err
-	}
-
-	if !config.SkipCertificates {
-		sd.certs = append(sd.certs, ee)
-		if len(parents) > 0 {
-			sd.certs = append(sd.certs, parents...)
-		}
+		return signerInfo{}, err
 	}
-	sd.sd.SignerInfos = append(sd.sd.SignerInfos, signer)
-	return nil
+	return signer, nil
 }
 
 func newHash(hasher crypto.Hash, hashOid asn1.ObjectIdentifier) hash.Hash {
@@ -301,7 +358,9 @@ func (sd *SignedData) AddCertificate(cert *smx509.Certificate) {
 // This must be called right before Finish()
 func (sd *SignedData) Detach() {
 	sd.sd.ContentInfo = contentInfo{ContentType: OIDData}
-	if sd.isSM {
+	if sd.isSM9 {
+		sd.sd.ContentInfo.ContentType = SM9OIDData
+	} else if sd.isSM {
 		sd.sd.ContentInfo.ContentType = SM2OIDData
 	}
 }
@@ -324,7 +383,9 @@ func (sd *SignedData) Finish() ([]byte, error) {
 		ContentType: OIDSignedData,
 		Content:     asn1.RawValue{Class: 2, Tag: 0, Bytes: inner, IsCompound: true},
 	}
-	if sd.isSM {
+	if sd.isSM9 {
+		outer.ContentType = SM9OIDSignedData
+	} else if sd.isSM {
 		outer.ContentType = SM2OIDSignedData
 	}
 	return asn1.Marshal(outer)
@@ -385,6 +446,11 @@ func signAttributes(attrs []attribute, pkey crypto.PrivateKey, hasher crypto.Has
 		return key.SignWithSM2(rand.Reader, nil, attrBytes)
 	}
 
+	// SM9 signature hashes the message itself
+	if key, ok := pkey.(*sm9.SignPrivateKey); ok {
+		return key.Sign(rand.Reader, attrBytes, nil)
+	}
+
 	h := hasher.New()
 	h.Write(attrBytes)
 	hash := h.Sum(nil)
@@ -2,6 +2,7 @@ package pkcs7
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/pem"
@@ -12,6 +13,7 @@ import (
 	"os/exec"
 	"testing"
 
+	"github.com/emmansun/gmsm/sm9"
 	"github.com/emmansun/gmsm/smx509"
 )
 
@@ -107,6 +109,103 @@ func TestSignSM(t *testing.T) {
 	testSign(t, true, content, sigalgs)
 }
 
+func TestSignSM9(t *testing.T) {
+	masterKey, err := sm9.GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("emmansun")
+	hid := byte(0x01)
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("Hello World")
+	for _, testDetach := range []bool{false, true} {
+		toBeSigned, err := NewSM9SignedData(content)
+		if err != nil {
+			t.Fatalf("Cannot initialize signed data: %s", err)
+		}
+		if err := toBeSigned.AddSM9Signer(uid, userKey, SignerInfoConfig{}); err != nil {
+			t.Fatalf("Cannot add signer: %s", err)
+		}
+		if testDetach {
+			toBeSigned.Detach()
+		}
+		signed, err := toBeSigned.Finish()
+		if err != nil {
+			t.Fatalf("Cannot finish signing data: %s", err)
+		}
+		p7, err := Parse(signed)
+		if err != nil {
+			t.Fatalf("Cannot parse signed data: %v", err)
+		}
+		if testDetach {
+			p7.Content = content
+		}
+		if !bytes.Equal(content, p7.Content) {
+			t.Errorf("Signed content %q does not match original %q", p7.Content, content)
+		}
+		if len(p7.Certificates) > 0 || p7.GetOnlySigner() != nil {
+			t.Errorf("Unexpected certificates: %v", p7.Certificates)
+		}
+		uids := p7.GetSM9SignerUIDs()
+		if len(uids) != 1 || !bytes.Equal(uids[0], uid) {
+			t.Errorf("Unexpected signer uids: %s", uids)
+		}
+		if err := p7.VerifySM9(masterKey.Public(), hid); err != nil {
+			t.Fatalf("Cannot verify signed data: %v", err)
+		}
+		if err := p7.Verify(); err == nil {
+			t.Error("Expected error for SM9 signer without VerifySM9")
+		}
+		if err := p7.VerifySM9(masterKey.Public(), 0x02); err == nil {
+			t.Error("Expected error for wrong hid")
+		}
+		p7.Signers[0].SubjectKeyIdentifier = []byte("someone")
+		if err := p7.VerifySM9(masterKey.Public(), hid); err == nil {
+			t.Error("Expected error for wrong identity")
+		}
+	}
+}
+
+func TestSignSM9WithoutIdentity(t *testing.T) {
+	masterKey, err := sm9.GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey, err := masterKey.GenerateUserKey([]byte("emmansun"), 0x01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getOIDForEncryptionAlgorithm(userKey.MasterPublic(), OIDDigestAlgorithmSM3); err == nil {
+		t.Error("Expected error for SM9 sign master public key")
+	}
+	toBeSigned, err := NewSM9SignedData([]byte("Hello World"))
+	if err != nil {
+		t.Fatalf("Cannot initialize signed data: %s", err)
+	}
+	if err := toBeSigned.AddSM9Signer(nil, userKey, SignerInfoConfig{}); err == nil {
+		t.Error("Expected error for empty uid")
+	}
+	for _, newSignedData := range []func([]byte) (*SignedData, error){NewSignedData, NewSMSignedData} {
+		sd, err := newSignedData([]byte("Hello World"))
+		if err != nil {
+			t.Fatalf("Cannot initialize signed data: %s", err)
+		}
+		if err := sd.AddSM9Signer([]byte("emmansun"), userKey, SignerInfoConfig{}); err == nil {
+			t.Error("Expected error for SM9 signer of non SM9 signed data")
+		}
+	}
+	toBeSigned.SetDigestAlgorithm(OIDDigestAlgorithmSHA256)
+	if err := toBeSigned.AddSM9Signer([]byte("emmansun"), userKey, SignerInfoConfig{}); err == nil {
+		t.Error("Expected error for SM9 signer with SHA256 digest")
+	}
+	if err := toBeSigned.AddSigner(&smx509.Certificate{}, userKey, SignerInfoConfig{}); err == nil {
+		t.Error("Expected error for SM9 signer with certificate")
+	}
+}
+
 func ExampleSignedData() {
 	// generate a signing cert or load a key pair
 	cert, err := createTestCertificate(x509.SHA256WithRSA)
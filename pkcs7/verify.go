@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/emmansun/gmsm/sm9"
 	"github.com/emmansun/gmsm/smx509"
 )
 
@@ -58,32 +59,16 @@ func (p7 *PKCS7) VerifyWithChainAtTime(truststore *smx509.CertPool, currentTime
 
 func verifySignature(p7 *PKCS7, signer signerInfo, truststore *smx509.CertPool, currentTime *time.Time) (err error) {
 	signedData := p7.Content
+	if signer.DigestEncryptionAlgorithm.Algorithm.Equal(OIDDigestEncryptionAlgorithmSM9) {
+		return errors.New("pkcs7: SM9 signer must be verified with VerifySM9")
+	}
 	ee := getCertFromCertsByIssuerAndSerial(p7.Certificates, signer.IssuerAndSerialNumber)
 	if ee == nil {
 		return errors.New("pkcs7: No certificate for signer")
 	}
 	signingTime := time.Now().UTC()
 	if len(signer.AuthenticatedAttributes) > 0 {
-		// TODO(fullsailor): First check the content type match
-		var digest []byte
-		err := unmarshalAttribute(signer.AuthenticatedAttributes, OIDAttributeMessageDigest, &digest)
-		if err != nil {
-			return err
-		}
-		hasher, err := getHashForOID(signer.DigestAlgorithm.Algorithm)
-		if err != nil {
-			return err
-		}
-		h := newHash(hasher, signer.DigestAlgorithm.Algorithm)
-		h.Write(p7.Content)
-		computed := h.Sum(nil)
-		if subtle.ConstantTimeCompare(digest, computed) != 1 {
-			return &MessageDigestMismatchError{
-				ExpectedDigest: digest,
-				ActualDigest:   computed,
-			}
-		}
-		signedData, err = marshalAttributes(signer.AuthenticatedAttributes)
+		signedData, err = getSignedAttributes(p7, signer)
 		if err != nil {
 			return err
 		}
@@ -114,6 +99,72 @@ func verifySignature(p7 *PKCS7, signer signerInfo, truststore *smx509.CertPool,
 	return ee.CheckSignature(sigalg, signedData, signer.EncryptedDigest)
 }
 
+// getSignedAttributes checks the message digest attribute against the content and
+// returns the DER encoded authenticated attributes, which are signed by the signer.
+func getSignedAttributes(p7 *PKCS7, signer signerInfo) ([]byte, error) {
+	// TODO(fullsailor): First check the content type match
+	var digest []byte
+	err := unmarshalAttribute(signer.AuthenticatedAttributes, OIDAttributeMessageDigest, &digest)
+	if err != nil {
+		return nil, err
+	}
+	hasher, err := getHashForOID(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	h := newHash(hasher, signer.DigestAlgorithm.Algorithm)
+	h.Write(p7.Content)
+	computed := h.Sum(nil)
+	if subtle.ConstantTimeCompare(digest, computed) != 1 {
+		return nil, &MessageDigestMismatchError{
+			ExpectedDigest: digest,
+			ActualDigest:   computed,
+		}
+	}
+	return marshalAttributes(signer.AuthenticatedAttributes)
+}
+
+// VerifySM9 checks the signatures of a PKCS7 object signed by SM9 signers,
+// see SignedData.AddSM9Signer. Each signature is verified against the identity
+// in the signer info, with the sign master public key and the hid of the KGC.
+// Signers without SM9 signature are rejected.
+func (p7 *PKCS7) VerifySM9(pub *sm9.SignMasterPublicKey, hid byte) error {
+	if len(p7.Signers) == 0 {
+		return errors.New("pkcs7: Message has no signers")
+	}
+	for _, signer := range p7.Signers {
+		if !signer.DigestEncryptionAlgorithm.Algorithm.Equal(OIDDigestEncryptionAlgorithmSM9) {
+			return fmt.Errorf("pkcs7: unsupported algorithm %q for SM9 verification",
+				signer.DigestEncryptionAlgorithm.Algorithm.String())
+		}
+		if len(signer.SubjectKeyIdentifier) == 0 {
+			return errors.New("pkcs7: no identity for SM9 signer")
+		}
+		signedData := p7.Content
+		if len(signer.AuthenticatedAttributes) > 0 {
+			var err error
+			if signedData, err = getSignedAttributes(p7, signer); err != nil {
+				return err
+			}
+		}
+		if !sm9.VerifyASN1(pub, signer.SubjectKeyIdentifier, hid, signedData, signer.EncryptedDigest) {
+			return errors.New("pkcs7: SM9 verification failure")
+		}
+	}
+	return nil
+}
+
+// GetSM9SignerUIDs returns the identities of the SM9 signers of the signed data payload.
+func (p7 *PKCS7) GetSM9SignerUIDs() [][]byte {
+	var uids [][]byte
+	for _, signer := range p7.Signers {
+		if signer.DigestEncryptionAlgorithm.Algorithm.Equal(OIDDigestEncryptionAlgorithmSM9) {
+			uids = append(uids, signer.SubjectKeyIdentifier)
+		}
+	}
+	return uids
+}
+
 // GetOnlySigner returns an x509.Certificate for the first signer of the signed
 // data payload. If there are more or less than one signer, nil is returned
 func (p7 *PKCS7) GetOnlySigner() *smx509.Certificate {
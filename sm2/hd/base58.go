@@ -0,0 +1,86 @@
+package hd
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	bigRadix     = big.NewInt(58)
+	base58Lookup = func() [256]int8 {
+		var t [256]int8
+		for i := range t {
+			t[i] = -1
+		}
+		for i := 0; i < len(base58Alphabet); i++ {
+			t[base58Alphabet[i]] = int8(i)
+		}
+		return t
+	}()
+)
+
+// base58CheckEncode encodes b || checksum in Base58, the checksum is
+// the first 4 bytes of SM3(SM3(b)).
+func base58CheckEncode(b []byte) string {
+	sum := checksum(b)
+	input := append(append([]byte{}, b...), sum[:]...)
+
+	x := new(big.Int).SetBytes(input)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, bigRadix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// leading zero bytes are encoded as '1'
+	for _, c := range input {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckDecode decodes the string returned by base58CheckEncode.
+func base58CheckDecode(s string) ([]byte, error) {
+	x := new(big.Int)
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	for i := 0; i < len(s); i++ {
+		v := base58Lookup[s[i]]
+		if v < 0 {
+			return nil, errors.New("hd: invalid base58 character")
+		}
+		x.Mul(x, bigRadix)
+		x.Add(x, big.NewInt(int64(v)))
+	}
+	decoded := append(make([]byte, zeros), x.Bytes()...)
+	if len(decoded) < 4 {
+		return nil, errors.New("hd: invalid base58check string")
+	}
+	payload, sum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	expected := checksum(payload)
+	if !bytes.Equal(sum, expected[:]) {
+		return nil, errors.New("hd: invalid base58check checksum")
+	}
+	return payload, nil
+}
+
+func checksum(b []byte) [4]byte {
+	var sum [4]byte
+	h := sm3.Sum(b)
+	h = sm3.Sum(h[:])
+	copy(sum[:], h[:4])
+	return sum
+}
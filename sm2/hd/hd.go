@@ -0,0 +1,424 @@
+// Package hd implements hierarchical deterministic key derivation on SM2 curve,
+// it follows BIP32 with SM3 in place of SHA-512 and RIPEMD-160:
+//
+//	Master key:   I = HMAC-SM3-512("SM2 seed", seed), k = IL, c = IR.
+//	Hardened:     I = HMAC-SM3-512(cpar, 0x00 || ser256(kpar) || ser32(i)), i >= 2³¹.
+//	Non-hardened: I = HMAC-SM3-512(cpar, serP([kpar]G) || ser32(i)), i < 2³¹.
+//	Child key:    ki = IL + kpar mod n, Ki = [IL]G + Kpar, ci = IR.
+//
+// SM3 only outputs 256 bits, so HMAC-SM3-512(key, data) is defined as
+// T1 || T2, where T1 = HMAC-SM3(key, data || 0x01), T2 = HMAC-SM3(key, T1 || data || 0x02),
+// that is HKDF-Expand with SM3, the key as PRK and the data as info.
+//
+// serP is the compressed point encoding, and the fingerprint of a key is the first
+// 4 bytes of SM3(serP(K)). Public child keys can be derived from the parent public key
+// alone, so a watch-only server can generate receiving keys without any private key.
+package hd
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/emmansun/gmsm/ecdh"
+	"github.com/emmansun/gmsm/internal/bigmod"
+	_sm2ec "github.com/emmansun/gmsm/internal/sm2ec"
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm3"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart = 0x80000000
+
+	// RecommendedSeedLen is the recommended length in bytes of the seed.
+	RecommendedSeedLen = 32
+	// MinSeedLen is the minimum length in bytes of the seed.
+	MinSeedLen = 16
+	// MaxSeedLen is the maximum length in bytes of the seed.
+	MaxSeedLen = 64
+
+	// serializedKeyLen is the length of a serialized extended key:
+	// version(4) || depth(1) || parent fingerprint(4) || child number(4) || chain code(32) || key(33).
+	serializedKeyLen = 4 + 1 + 4 + 4 + 32 + 33
+
+	scalarSize = 32
+)
+
+// The version bytes of the serialized extended keys, "SM2s" and "SM2p" in ASCII.
+var (
+	PrivateVersion = [4]byte{'S', 'M', '2', 's'}
+	PublicVersion  = [4]byte{'S', 'M', '2', 'p'}
+)
+
+var masterKey = []byte("SM2 seed")
+
+var (
+	// ErrInvalidSeedLen is returned when the seed length is out of [MinSeedLen, MaxSeedLen].
+	ErrInvalidSeedLen = fmt.Errorf("hd: seed length must be between %d and %d bytes", MinSeedLen, MaxSeedLen)
+	// ErrUnusableSeed is returned when the seed produces an invalid master key,
+	// the caller should try another seed.
+	ErrUnusableSeed = errors.New("hd: unusable seed")
+	// ErrInvalidChild is returned when the child index produces an invalid key,
+	// the caller should proceed with the next index.
+	ErrInvalidChild = errors.New("hd: the child index produces an invalid key")
+	// ErrDeriveHardenedFromPublic is returned when deriving a hardened child from a public extended key.
+	ErrDeriveHardenedFromPublic = errors.New("hd: cannot derive a hardened key from a public key")
+	// ErrNotPrivate is returned when requesting the private key of a public extended key.
+	ErrNotPrivate = errors.New("hd: not a private extended key")
+	// ErrDepthExceeded is returned when deriving a child of a key at the maximum depth.
+	ErrDepthExceeded = errors.New("hd: max depth exceeded")
+)
+
+var (
+	orderOnce sync.Once
+	n         *bigmod.Modulus
+)
+
+func order() *bigmod.Modulus {
+	orderOnce.Do(func() {
+		n, _ = bigmod.NewModulusFromBig(sm2.P256().Params().N)
+	})
+	return n
+}
+
+// ExtendedKey is a private or public key with the chain code and its position in the key tree.
+type ExtendedKey struct {
+	key         []byte // 32 bytes private scalar or 33 bytes compressed public key
+	pubKey      []byte // compressed public key, computed lazily for private keys
+	pubKeyOnce  sync.Once
+	chainCode   []byte
+	parentFP    [4]byte
+	childNumber uint32
+	depth       uint8
+	isPrivate   bool
+}
+
+// NewMaster creates the master extended private key from the seed.
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinSeedLen || len(seed) > MaxSeedLen {
+		return nil, ErrInvalidSeedLen
+	}
+	il, ir := hmacSM3512(masterKey, seed)
+	k, err := bigmod.NewNat().SetBytes(il, order())
+	if err != nil || k.IsZero() == 1 {
+		return nil, ErrUnusableSeed
+	}
+	return &ExtendedKey{key: il, chainCode: ir, isPrivate: true}, nil
+}
+
+// hmacSM3512 returns the two halves of HMAC-SM3-512(key, data).
+func hmacSM3512(key, data []byte) (il, ir []byte) {
+	mac := hmac.New(sm3.New, key)
+	mac.Write(data)
+	mac.Write([]byte{0x01})
+	il = mac.Sum(nil)
+	mac.Reset()
+	mac.Write(il)
+	mac.Write(data)
+	mac.Write([]byte{0x02})
+	ir = mac.Sum(nil)
+	return
+}
+
+// IsPrivate reports whether the extended key is a private extended key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth returns the depth of the key in the tree, the master key's depth is 0.
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ChildNumber returns the index of the key in its parent, hardened indexes are >= HardenedKeyStart.
+func (k *ExtendedKey) ChildNumber() uint32 {
+	return k.childNumber
+}
+
+// ParentFingerprint returns the fingerprint of the parent key, it's 0 for the master key.
+func (k *ExtendedKey) ParentFingerprint() uint32 {
+	return binary.BigEndian.Uint32(k.parentFP[:])
+}
+
+// Fingerprint returns the fingerprint of the key, the first 4 bytes of SM3(serP(K)).
+func (k *ExtendedKey) Fingerprint() uint32 {
+	fp := fingerprint(k.publicKeyBytes())
+	return binary.BigEndian.Uint32(fp[:])
+}
+
+// ChainCode returns a copy of the chain code.
+func (k *ExtendedKey) ChainCode() []byte {
+	return append([]byte{}, k.chainCode...)
+}
+
+func fingerprint(pub []byte) [4]byte {
+	var fp [4]byte
+	h := sm3.Sum(pub)
+	copy(fp[:], h[:4])
+	return fp
+}
+
+// publicKeyBytes returns the compressed public key. It's safe for concurrent use.
+func (k *ExtendedKey) publicKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	k.pubKeyOnce.Do(func() {
+		p, err := _sm2ec.NewSM2P256Point().ScalarBaseMult(k.key)
+		if err != nil {
+			panic("hd: internal error: invalid private key")
+		}
+		k.pubKey = p.BytesCompressed()
+	})
+	return k.pubKey
+}
+
+// Derive returns the child extended key at index i, the index >= HardenedKeyStart
+// derives a hardened child which requires a private extended key.
+//
+// ErrInvalidChild is returned with negligible probability, the caller should
+// skip the index and derive the next one.
+func (k *ExtendedKey) Derive(i uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, ErrDepthExceeded
+	}
+	hardened := i >= HardenedKeyStart
+	if hardened && !k.isPrivate {
+		return nil, ErrDeriveHardenedFromPublic
+	}
+
+	data := make([]byte, 0, 1+scalarSize+4)
+	if hardened {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, k.publicKeyBytes()...)
+	}
+	data = appendUint32(data, i)
+	il, ir := hmacSM3512(k.chainCode, data)
+
+	m := order()
+	tweak, err := bigmod.NewNat().SetBytes(il, m)
+	if err != nil {
+		return nil, ErrInvalidChild
+	}
+
+	child := &ExtendedKey{
+		chainCode:   ir,
+		parentFP:    fingerprint(k.publicKeyBytes()),
+		childNumber: i,
+		depth:       k.depth + 1,
+		isPrivate:   k.isPrivate,
+	}
+	if k.isPrivate {
+		// ki = IL + kpar mod n
+		kpar, err := bigmod.NewNat().SetBytes(k.key, m)
+		if err != nil {
+			return nil, err
+		}
+		tweak.Add(kpar, m)
+		if tweak.IsZero() == 1 {
+			return nil, ErrInvalidChild
+		}
+		child.key = tweak.Bytes(m)
+		return child, nil
+	}
+	// Ki = [IL]G + Kpar
+	Kpar, err := _sm2ec.NewSM2P256Point().SetBytes(k.key)
+	if err != nil {
+		return nil, err
+	}
+	Ki, err := _sm2ec.NewSM2P256Point().ScalarBaseMult(il)
+	if err != nil {
+		return nil, err
+	}
+	Ki.Add(Ki, Kpar)
+	child.key = Ki.BytesCompressed()
+	if len(child.key) != 1+scalarSize {
+		// the point at infinity
+		return nil, ErrInvalidChild
+	}
+	return child, nil
+}
+
+// DerivePath derives the descendant extended key with the path relative to the key,
+// such as "m/44'/0'/0/1" or "0/1". Hardened indexes are marked by ' or h,
+// the "m" prefix is only allowed for a master key.
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	segments := strings.Split(path, "/")
+	if segments[0] == "m" {
+		if k.depth != 0 {
+			return nil, errors.New("hd: absolute path from a non-master key")
+		}
+		segments = segments[1:]
+	}
+	key := k
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("hd: invalid path %q", path)
+		}
+		var offset uint32
+		if last := segment[len(segment)-1]; last == '\'' || last == 'h' || last == 'H' {
+			offset = HardenedKeyStart
+			segment = segment[:len(segment)-1]
+		}
+		index, err := strconv.ParseUint(segment, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("hd: invalid path %q", path)
+		}
+		if key, err = key.Derive(uint32(index) + offset); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Neuter returns the public extended key of the key. It returns the key itself
+// if it's already a public extended key.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	return &ExtendedKey{
+		key:         k.publicKeyBytes(),
+		chainCode:   k.chainCode,
+		parentFP:    k.parentFP,
+		childNumber: k.childNumber,
+		depth:       k.depth,
+	}
+}
+
+// PrivateKey returns the SM2 private key of a private extended key.
+func (k *ExtendedKey) PrivateKey() (*sm2.PrivateKey, error) {
+	if !k.isPrivate {
+		return nil, ErrNotPrivate
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(k.key)
+	if err != nil {
+		return nil, err
+	}
+	pub := ecdhKey.PublicKey().Bytes()
+	priv := new(sm2.PrivateKey)
+	priv.Curve = sm2.P256()
+	priv.D = new(big.Int).SetBytes(k.key)
+	priv.X = new(big.Int).SetBytes(pub[1 : 1+scalarSize])
+	priv.Y = new(big.Int).SetBytes(pub[1+scalarSize:])
+	return priv, nil
+}
+
+// PublicKey returns the SM2 public key of the extended key.
+func (k *ExtendedKey) PublicKey() (*ecdsa.PublicKey, error) {
+	p, err := _sm2ec.NewSM2P256Point().SetBytes(k.publicKeyBytes())
+	if err != nil {
+		return nil, err
+	}
+	pub := p.Bytes()
+	return &ecdsa.PublicKey{
+		Curve: sm2.P256(),
+		X:     new(big.Int).SetBytes(pub[1 : 1+scalarSize]),
+		Y:     new(big.Int).SetBytes(pub[1+scalarSize:]),
+	}, nil
+}
+
+// ECDHPublicKey returns the public key of the extended key as an [ecdh.PublicKey].
+func (k *ExtendedKey) ECDHPublicKey() (*ecdh.PublicKey, error) {
+	p, err := _sm2ec.NewSM2P256Point().SetBytes(k.publicKeyBytes())
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.P256().NewPublicKey(p.Bytes())
+}
+
+// MarshalBinary returns the 78 bytes serialization of the extended key:
+//
+//	version(4) || depth(1) || parent fingerprint(4) || child number(4) || chain code(32) || key(33)
+//
+// The key is 0x00 || ser256(k) for a private key and serP(K) for a public key.
+func (k *ExtendedKey) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, serializedKeyLen)
+	if k.isPrivate {
+		b = append(b, PrivateVersion[:]...)
+	} else {
+		b = append(b, PublicVersion[:]...)
+	}
+	b = append(b, k.depth)
+	b = append(b, k.parentFP[:]...)
+	b = appendUint32(b, k.childNumber)
+	b = append(b, k.chainCode...)
+	if k.isPrivate {
+		b = append(b, 0x00)
+	}
+	b = append(b, k.key...)
+	return b, nil
+}
+
+// String returns the Base58Check encoding of the serialized extended key,
+// the checksum is the first 4 bytes of SM3(SM3(serialization)).
+func (k *ExtendedKey) String() string {
+	b, _ := k.MarshalBinary()
+	return base58CheckEncode(b)
+}
+
+// ParseExtendedKey parses the 78 bytes serialization returned by MarshalBinary.
+func ParseExtendedKey(b []byte) (*ExtendedKey, error) {
+	if len(b) != serializedKeyLen {
+		return nil, errors.New("hd: invalid extended key length")
+	}
+	k := &ExtendedKey{
+		depth:       b[4],
+		childNumber: binary.BigEndian.Uint32(b[9:13]),
+		chainCode:   append([]byte{}, b[13:45]...),
+	}
+	copy(k.parentFP[:], b[5:9])
+	if k.depth == 0 && (k.ParentFingerprint() != 0 || k.childNumber != 0) {
+		return nil, errors.New("hd: invalid master extended key")
+	}
+	keyData := b[45:]
+	var version [4]byte
+	copy(version[:], b[:4])
+	switch version {
+	case PrivateVersion:
+		if keyData[0] != 0x00 {
+			return nil, errors.New("hd: invalid private key data")
+		}
+		s, err := bigmod.NewNat().SetBytes(keyData[1:], order())
+		if err != nil || s.IsZero() == 1 {
+			return nil, errors.New("hd: invalid private key data")
+		}
+		k.key = append([]byte{}, keyData[1:]...)
+		k.isPrivate = true
+	case PublicVersion:
+		if keyData[0] != 0x02 && keyData[0] != 0x03 {
+			return nil, errors.New("hd: invalid public key data")
+		}
+		if _, err := _sm2ec.NewSM2P256Point().SetBytes(keyData); err != nil {
+			return nil, errors.New("hd: invalid public key data")
+		}
+		k.key = append([]byte{}, keyData...)
+	default:
+		return nil, errors.New("hd: unknown extended key version")
+	}
+	return k, nil
+}
+
+// ParseExtendedKeyString parses the Base58Check encoded extended key returned by String.
+func ParseExtendedKeyString(s string) (*ExtendedKey, error) {
+	b, err := base58CheckDecode(s)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExtendedKey(b)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,235 @@
+package hd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+var testSeed, _ = hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+func TestNewMaster(t *testing.T) {
+	if _, err := NewMaster(make([]byte, MinSeedLen-1)); err != ErrInvalidSeedLen {
+		t.Errorf("expected ErrInvalidSeedLen, got %v", err)
+	}
+	if _, err := NewMaster(make([]byte, MaxSeedLen+1)); err != ErrInvalidSeedLen {
+		t.Errorf("expected ErrInvalidSeedLen, got %v", err)
+	}
+	master, err := NewMaster(testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !master.IsPrivate() || master.Depth() != 0 || master.ChildNumber() != 0 || master.ParentFingerprint() != 0 {
+		t.Errorf("unexpected master key attributes")
+	}
+	if s := master.String(); s != "JggKN11gwrNdsUKNLTXXjmN8UwYEN45Ap9yYzVy9SxqY9apCvpvuh33P4k7FpNYnEQmugzDGmmaCvwQVyUJGJWgFBMvmfnpwgRNU6P4gpDng7Fcc" {
+		t.Errorf("unexpected master key %v", s)
+	}
+	child, err := master.DerivePath("m/0'/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := child.Neuter().String(); s != "JggKMaCDB4oaKYx4Gf9B3qmjyz7Dw4FtzAmBopLpHKg3qt52Ewf9oS7ymkoSACMCup5Y2TUMaaTKgCjtCaEBEZKgEQz5voB64kc3J6nEJDUDnzbe" {
+		t.Errorf("unexpected child public key %v", s)
+	}
+}
+
+func TestPublicDerivation(t *testing.T) {
+	master, err := NewMaster(testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := master.DerivePath("m/44'/0'/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	watchOnly := account.Neuter()
+	for i := uint32(0); i < 5; i++ {
+		change, err := account.Derive(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := change.Derive(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := watchOnly.Derive(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub, err = pub.Derive(i); err != nil {
+			t.Fatal(err)
+		}
+		if priv.Neuter().String() != pub.String() {
+			t.Errorf("child %v: public derivation mismatch", i)
+		}
+		if pub.ParentFingerprint() != priv.ParentFingerprint() || pub.Depth() != 5 {
+			t.Errorf("child %v: unexpected attributes", i)
+		}
+	}
+	if _, err := watchOnly.Derive(HardenedKeyStart); err != ErrDeriveHardenedFromPublic {
+		t.Errorf("expected ErrDeriveHardenedFromPublic, got %v", err)
+	}
+	if _, err := watchOnly.PrivateKey(); err != ErrNotPrivate {
+		t.Errorf("expected ErrNotPrivate, got %v", err)
+	}
+}
+
+func TestConcurrentDerivation(t *testing.T) {
+	master, err := NewMaster(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	children := make([]*ExtendedKey, 8)
+	for i := range children {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			children[i], _ = master.Derive(uint32(i))
+		}(i)
+	}
+	wg.Wait()
+	for i, child := range children {
+		if child == nil || child.ParentFingerprint() != master.Fingerprint() {
+			t.Errorf("child %d: parent fingerprint mismatch", i)
+		}
+	}
+}
+
+func TestHardenedDerivation(t *testing.T) {
+	master, _ := NewMaster(testSeed)
+	normal, _ := master.Derive(0)
+	hardened, _ := master.Derive(HardenedKeyStart)
+	if normal.String() == hardened.String() {
+		t.Errorf("hardened and normal child keys are the same")
+	}
+	byPath, err := master.DerivePath("m/0h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byPath.String() != hardened.String() {
+		t.Errorf("path derivation mismatch")
+	}
+	if byPath.ParentFingerprint() != master.Fingerprint() || byPath.ChildNumber() != HardenedKeyStart {
+		t.Errorf("unexpected child attributes")
+	}
+}
+
+func TestDerivePathInvalid(t *testing.T) {
+	master, _ := NewMaster(testSeed)
+	child, _ := master.Derive(1)
+	for _, path := range []string{"m/", "m//1", "m/a", "m/2147483648", "m/-1", "1/x'"} {
+		if _, err := master.DerivePath(path); err == nil {
+			t.Errorf("%v: expected error", path)
+		}
+	}
+	if _, err := child.DerivePath("m/1"); err == nil {
+		t.Errorf("expected error for absolute path from child")
+	}
+}
+
+func TestSignWithDerivedKey(t *testing.T) {
+	master, _ := NewMaster(testSeed)
+	child, err := master.DerivePath("m/1/2'/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := child.PrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := child.Neuter().PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.PublicKey.Equal(pub) {
+		t.Fatal("public key mismatch")
+	}
+	msg := []byte("hd wallet")
+	sig, err := priv.Sign(rand.Reader, msg, sm2.DefaultSM2SignerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sm2.VerifyASN1WithSM2(pub, nil, msg, sig) {
+		t.Errorf("failed to verify signature with derived public key")
+	}
+	ecdhPub, err := child.ECDHPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhPriv, _ := priv.ECDH()
+	if !ecdhPriv.PublicKey().Equal(ecdhPub) {
+		t.Errorf("ecdh public key mismatch")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	master, _ := NewMaster(testSeed)
+	child, _ := master.DerivePath("m/0'/1")
+	for _, key := range []*ExtendedKey{master, master.Neuter(), child, child.Neuter()} {
+		b, err := key.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != serializedKeyLen {
+			t.Fatalf("unexpected serialized length %v", len(b))
+		}
+		parsed, err := ParseExtendedKey(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b2, _ := parsed.MarshalBinary()
+		if !bytes.Equal(b, b2) || parsed.IsPrivate() != key.IsPrivate() {
+			t.Errorf("binary round trip mismatch")
+		}
+		s := key.String()
+		parsed, err = ParseExtendedKeyString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.String() != s {
+			t.Errorf("string round trip mismatch")
+		}
+		// corrupt the checksum
+		last := s[len(s)-1]
+		corrupted := s[:len(s)-1] + string(base58Alphabet[(bytes.IndexByte([]byte(base58Alphabet), last)+1)%58])
+		if _, err := ParseExtendedKeyString(corrupted); err == nil {
+			t.Errorf("expected checksum error")
+		}
+	}
+
+	b, _ := master.MarshalBinary()
+	bad := append([]byte{}, b...)
+	bad[0] = 'X'
+	if _, err := ParseExtendedKey(bad); err == nil {
+		t.Errorf("expected error for unknown version")
+	}
+	bad = append([]byte{}, b...)
+	bad[45] = 1
+	if _, err := ParseExtendedKey(bad); err == nil {
+		t.Errorf("expected error for invalid private key data")
+	}
+	if _, err := ParseExtendedKey(b[:len(b)-1]); err == nil {
+		t.Errorf("expected error for invalid length")
+	}
+	if _, err := ParseExtendedKeyString("0OIl"); err == nil {
+		t.Errorf("expected error for invalid base58")
+	}
+}
+
+func TestBase58(t *testing.T) {
+	for _, in := range [][]byte{{}, {0}, {0, 0, 1}, []byte("hello world")} {
+		out, err := base58CheckDecode(base58CheckEncode(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(in, out) {
+			t.Errorf("base58check round trip mismatch for %x", in)
+		}
+	}
+}
@@ -119,8 +119,9 @@ type Signer interface {
 // SM2SignerOption implements crypto.SignerOpts interface.
 // It is specific for SM2, used in private key's Sign method.
 type SM2SignerOption struct {
-	uid         []byte
-	forceGMSign bool
+	uid           []byte
+	forceGMSign   bool
+	deterministic bool
 }
 
 // NewSM2SignerOption creates a SM2 specific signer option.
@@ -137,6 +138,16 @@ func NewSM2SignerOption(forceGMSign bool, uid []byte) *SM2SignerOption {
 	return opt
 }
 
+// NewDeterministicSM2SignerOption creates a SM2 specific signer option with deterministic nonce.
+// The nonce k is derived from the private key and the digest with HMAC-SM3 (RFC 6979 style),
+// so the signer doesn't depend on the rand argument and the same input always produces the
+// same signature. The forceGMSign and uid arguments are the same as NewSM2SignerOption.
+func NewDeterministicSM2SignerOption(forceGMSign bool, uid []byte) *SM2SignerOption {
+	opt := NewSM2SignerOption(forceGMSign, uid)
+	opt.deterministic = true
+	return opt
+}
+
 // DefaultSM2SignerOpts uses default UID and forceGMSign is true.
 var DefaultSM2SignerOpts = NewSM2SignerOption(true, nil)
 
@@ -458,6 +469,23 @@ func parseCiphertextASN1(c *sm2Curve, ciphertext []byte) (*_sm2ec.SM2P256Point,
 	return C1, c2, c3, nil
 }
 
+// ParseCiphertext parses the SM2 ciphertext, returns the uncompressed encoding of C1, C2 and C3.
+// The ASN.1 encoding is detected automatically, the splicing order of plain encoding is
+// specified by opts, default is C1C3C2.
+//
+// It's used by protocols which compute [d]C1 elsewhere, e.g. collaborative decryption.
+func ParseCiphertext(ciphertext []byte, opts *DecrypterOpts) (c1, c2, c3 []byte, err error) {
+	c := p256()
+	if len(ciphertext) <= 1+(c.curve.Params().BitSize/8)+sm3.Size {
+		return nil, nil, nil, errCiphertextTooShort
+	}
+	C1, c2, c3, err := parseCiphertext(c, ciphertext, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return C1.Bytes(), c2, c3, nil
+}
+
 var defaultUID = []byte{0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38}
 
 // CalculateZA ZA = H256(ENTLA || IDA || a || b || xG || yG || xA || yA).
@@ -485,7 +513,10 @@ func CalculateZA(pub *ecdsa.PublicKey, uid []byte) ([]byte, error) {
 	return md.Sum(nil), nil
 }
 
-func calculateSM2Hash(pub *ecdsa.PublicKey, data, uid []byte) ([]byte, error) {
+// CalculateSM2Hash returns the digest SM3(ZA || data) of the data, which is the
+// hash argument of SignASN1 and VerifyASN1. If the uid is empty, the default one
+// will be used.
+func CalculateSM2Hash(pub *ecdsa.PublicKey, data, uid []byte) ([]byte, error) {
 	if len(uid) == 0 {
 		uid = defaultUID
 	}
@@ -509,17 +540,28 @@ func calculateSM2Hash(pub *ecdsa.PublicKey, data, uid []byte) ([]byte, error) {
 // the bytes read from rand, and may change between calls and/or between versions.
 //
 // If the opts argument is instance of [*SM2SignerOption], and its ForceGMSign is true,
-// then the hash will be treated as raw message.
+// then the hash will be treated as raw message. If the opts argument is created by
+// [NewDeterministicSM2SignerOption], the rand argument is ignored and the signature is
+// deterministic.
 func SignASN1(rand io.Reader, priv *PrivateKey, hash []byte, opts crypto.SignerOpts) ([]byte, error) {
-	if sm2Opts, ok := opts.(*SM2SignerOption); ok && sm2Opts.forceGMSign {
-		newHash, err := calculateSM2Hash(&priv.PublicKey, hash, sm2Opts.uid)
+	sm2Opts, ok := opts.(*SM2SignerOption)
+	if ok && sm2Opts.forceGMSign {
+		newHash, err := CalculateSM2Hash(&priv.PublicKey, hash, sm2Opts.uid)
 		if err != nil {
 			return nil, err
 		}
 		hash = newHash
 	}
 
-	randutil.MaybeReadByte(rand)
+	if ok && sm2Opts.deterministic {
+		nonceReader, err := newDeterministicNonceReader(priv, hash)
+		if err != nil {
+			return nil, err
+		}
+		rand = nonceReader
+	} else {
+		randutil.MaybeReadByte(rand)
+	}
 
 	switch priv.Curve.Params() {
 	case P256().Params():
@@ -530,44 +572,37 @@ func SignASN1(rand io.Reader, priv *PrivateKey, hash []byte, opts crypto.SignerO
 }
 
 func signSM2EC(c *sm2Curve, priv *PrivateKey, rand io.Reader, hash []byte) (sig []byte, err error) {
-	e := bigmod.NewNat()
-	hashToNat(c, e, hash)
-	var (
-		k, r, s, dp1Inv, oneNat *bigmod.Nat
-		R                       *_sm2ec.SM2P256Point
-	)
-
-	oneNat, err = bigmod.NewNat().SetBytes(one.Bytes(), c.N)
-	if err != nil {
-		return nil, err
-	}
-	dp1Inv, err = bigmod.NewNat().SetBytes(priv.D.Bytes(), c.N)
+	r, s, _, err := signSM2ECRaw(c, priv, rand, hash)
 	if err != nil {
 		return nil, err
 	}
-	dp1Inv.Add(oneNat, c.N)
-	dp1Bytes, err := _sm2ec.P256OrdInverse(dp1Inv.Bytes(c.N))
-	if err != nil {
-		return nil, err
-	}
-	dp1Inv, err = bigmod.NewNat().SetBytes(dp1Bytes, c.N)
+	return EncodeSignature(r.Bytes(c.N), s.Bytes(c.N))
+}
+
+// signSM2ECRaw returns the signature (r, s) and the random point R = [k]G.
+func signSM2ECRaw(c *sm2Curve, priv *PrivateKey, rand io.Reader, hash []byte) (r, s *bigmod.Nat, R *_sm2ec.SM2P256Point, err error) {
+	e := bigmod.NewNat()
+	hashToNat(c, e, hash)
+	var k *bigmod.Nat
+
+	dp1Inv, err := dp1Inverse(c, priv)
 	if err != nil {
-		panic("sm2: internal error: P256OrdInverse produced an invalid value")
+		return nil, nil, nil, err
 	}
 
 	for {
 		for {
 			k, R, err = randomPoint(c, rand)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			Rx, err := R.BytesX()
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			r, err = bigmod.NewNat().SetOverflowingBytes(Rx, c.N)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			r.Add(e, c.N) // r = (Rx + e) mod N
 			if r.IsZero() == 0 {
@@ -580,7 +615,7 @@ func signSM2EC(c *sm2Curve, priv *PrivateKey, rand io.Reader, hash []byte) (sig
 		}
 		s, err = bigmod.NewNat().SetBytes(priv.D.Bytes(), c.N)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 		s.Mul(r, c.N)
 		k.Sub(s, c.N)
@@ -590,10 +625,34 @@ func signSM2EC(c *sm2Curve, priv *PrivateKey, rand io.Reader, hash []byte) (sig
 		}
 	}
 
-	return encodeSignature(r.Bytes(c.N), k.Bytes(c.N))
+	return r, k, R, nil
+}
+
+// dp1Inverse returns (1 + d)⁻¹ mod N.
+func dp1Inverse(c *sm2Curve, priv *PrivateKey) (*bigmod.Nat, error) {
+	oneNat, err := bigmod.NewNat().SetBytes(one.Bytes(), c.N)
+	if err != nil {
+		return nil, err
+	}
+	dp1, err := bigmod.NewNat().SetBytes(priv.D.Bytes(), c.N)
+	if err != nil {
+		return nil, err
+	}
+	dp1.Add(oneNat, c.N)
+	dp1Bytes, err := _sm2ec.P256OrdInverse(dp1.Bytes(c.N))
+	if err != nil {
+		return nil, err
+	}
+	dp1Inv, err := bigmod.NewNat().SetBytes(dp1Bytes, c.N)
+	if err != nil {
+		panic("sm2: internal error: P256OrdInverse produced an invalid value")
+	}
+	return dp1Inv, nil
 }
 
-func encodeSignature(r, s []byte) ([]byte, error) {
+// EncodeSignature returns the ASN.1 encoded signature of the big endian r and s,
+// they may have leading zeroes.
+func EncodeSignature(r, s []byte) ([]byte, error) {
 	var b cryptobyte.Builder
 	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
 		addASN1IntBytes(b, r)
@@ -636,55 +695,21 @@ func VerifyASN1(pub *ecdsa.PublicKey, hash, sig []byte) bool {
 }
 
 func verifySM2EC(c *sm2Curve, pub *ecdsa.PublicKey, hash, sig []byte) bool {
-	rBytes, sBytes, err := parseSignature(sig)
-	if err != nil {
-		return false
-	}
-
 	Q, err := c.pointFromAffine(pub.X, pub.Y)
 	if err != nil {
 		return false
 	}
 
-	r, err := bigmod.NewNat().SetBytes(rBytes, c.N)
-	if err != nil || r.IsZero() == 1 {
-		return false
-	}
-	s, err := bigmod.NewNat().SetBytes(sBytes, c.N)
-	if err != nil || s.IsZero() == 1 {
-		return false
-	}
-
-	e := bigmod.NewNat()
-	hashToNat(c, e, hash)
-
-	t := bigmod.NewNat().Set(r)
-	t.Add(s, c.N)
-	if t.IsZero() == 1 {
-		return false
-	}
-
-	p1, err := c.newPoint().ScalarBaseMult(s.Bytes(c.N))
-	if err != nil {
-		return false
-	}
-	p2, err := Q.ScalarMult(Q, t.Bytes(c.N))
-	if err != nil {
-		return false
-	}
-
-	Rx, err := p1.Add(p1, p2).BytesX()
-	if err != nil {
+	R, r, e, ok := prepareVerifySM2EC(c, Q, hash, sig)
+	if !ok {
 		return false
 	}
 
-	v, err := bigmod.NewNat().SetOverflowingBytes(Rx, c.N)
+	Rx, err := R.BytesX()
 	if err != nil {
 		return false
 	}
-
-	v.Add(e, c.N)
-	return v.Equal(r) == 1
+	return checkVerifySM2EC(c, Rx, r, e)
 }
 
 // VerifyASN1WithSM2 verifies the signature in ASN.1 encoding format sig of raw msg
@@ -692,7 +717,7 @@ func verifySM2EC(c *sm2Curve, pub *ecdsa.PublicKey, hash, sig []byte) bool {
 //
 // It returns value records whether the signature is valid. Compliance with GB/T 32918.2-2016.
 func VerifyASN1WithSM2(pub *ecdsa.PublicKey, uid, msg, sig []byte) bool {
-	digest, err := calculateSM2Hash(pub, msg, uid)
+	digest, err := CalculateSM2Hash(pub, msg, uid)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,319 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	_subtle "crypto/subtle"
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	_sm2ec "github.com/emmansun/gmsm/internal/sm2ec"
+	"github.com/emmansun/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// This file contains SM2 adaptor signature (a.k.a. pre-signature), which is used
+// in atomic swaps and payment channels.
+//
+// The statement is Y = [y]G, and y is the witness. Because [s]G + [s + r]PA = [k]G,
+// we have [s + y]G + [s + y + r]PA = [k]G + Y + [d]Y, so the signer:
+//
+//	computes Z = [d]Y and a DLEQ proof π that log_G(PA) == log_Y(Z),
+//	random k, R = [k]G, r = (x(R + Y + Z) + e) mod n, ŝ = (1 + d)⁻¹(k - rd) mod n.
+//
+// The pre-signature is (r, ŝ, Z, π), and the adapted signature is (r, ŝ + y), which is
+// a standard SM2 signature. Anyone who holds both the pre-signature and the adapted
+// signature can extract the witness y = s - ŝ.
+
+// AdaptorPreSignASN1 creates a pre-signature of hash with the private key priv and the statement
+// (uncompressed or compressed point encoding of Y = [y]G). It returns the ASN.1 encoded pre-signature:
+//
+//	SM2AdaptorPreSignature ::= SEQUENCE {
+//	  r              INTEGER,
+//	  s              INTEGER,
+//	  z              OCTET STRING, -- [d]Y, uncompressed point encoding
+//	  proofChallenge INTEGER,
+//	  proofResponse  INTEGER
+//	}
+//
+// The pre-signature can't be verified by VerifyASN1 until it's adapted by the witness.
+func AdaptorPreSignASN1(rand io.Reader, priv *PrivateKey, hash, statement []byte) ([]byte, error) {
+	if priv.Curve.Params() != P256().Params() {
+		return nil, errors.New("sm2: adaptor signature only supports sm2 curve")
+	}
+	c := p256()
+	Y, err := parseAdaptorStatement(c, statement)
+	if err != nil {
+		return nil, err
+	}
+	Q, err := c.pointFromAffine(priv.X, priv.Y)
+	if err != nil {
+		return nil, err
+	}
+	d, err := bigmod.NewNat().SetBytes(priv.D.Bytes(), c.N)
+	if err != nil {
+		return nil, err
+	}
+	Z, err := c.newPoint().ScalarMult(Y, d.Bytes(c.N))
+	if err != nil {
+		return nil, err
+	}
+	proofC, proofZ, err := proveDLEQ(c, rand, d, Q, Y, Z)
+	if err != nil {
+		return nil, err
+	}
+
+	dp1Inv, err := dp1Inverse(c, priv)
+	if err != nil {
+		return nil, err
+	}
+	e := bigmod.NewNat()
+	hashToNat(c, e, hash)
+	YZ := c.newPoint().Add(Y, Z)
+	for {
+		k, R, err := randomPoint(c, rand)
+		if err != nil {
+			return nil, err
+		}
+		x, err := R.Add(R, YZ).BytesX()
+		if err != nil {
+			continue
+		}
+		r, err := bigmod.NewNat().SetOverflowingBytes(x, c.N)
+		if err != nil {
+			return nil, err
+		}
+		r.Add(e, c.N)
+		if r.IsZero() == 1 {
+			continue
+		}
+		// ŝ = (1 + d)⁻¹(k - rd)
+		s := bigmod.NewNat().Set(d)
+		s.Mul(r, c.N)
+		k.Sub(s, c.N)
+		k.Mul(dp1Inv, c.N)
+		if k.IsZero() == 1 {
+			continue
+		}
+		var b cryptobyte.Builder
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addASN1IntBytes(b, r.Bytes(c.N))
+			addASN1IntBytes(b, k.Bytes(c.N))
+			b.AddASN1OctetString(Z.Bytes())
+			addASN1IntBytes(b, proofC.Bytes(c.N))
+			addASN1IntBytes(b, proofZ.Bytes(c.N))
+		})
+		return b.Bytes()
+	}
+}
+
+// AdaptorPreVerifyASN1 verifies the ASN.1 encoded pre-signature of hash with the public key
+// and the statement. It reports whether the pre-signature is valid, if yes, the signature
+// adapted with the statement's witness will be a valid SM2 signature.
+func AdaptorPreVerifyASN1(pub *ecdsa.PublicKey, hash, statement, preSig []byte) bool {
+	if pub.Curve.Params() != P256().Params() {
+		return false
+	}
+	c := p256()
+	Y, err := parseAdaptorStatement(c, statement)
+	if err != nil {
+		return false
+	}
+	Q, err := c.pointFromAffine(pub.X, pub.Y)
+	if err != nil {
+		return false
+	}
+	r, s, Z, proofC, proofZ, err := parseAdaptorPreSignature(c, preSig)
+	if err != nil {
+		return false
+	}
+	if !verifyDLEQ(c, proofC, proofZ, Q, Y, Z) {
+		return false
+	}
+	t := bigmod.NewNat().Set(r)
+	t.Add(s, c.N)
+	if t.IsZero() == 1 {
+		return false
+	}
+	// R = [ŝ]G + [ŝ + r]PA, check r == x(R + Y + Z) + e
+	R, err := c.newPoint().ScalarBaseMult(s.Bytes(c.N))
+	if err != nil {
+		return false
+	}
+	tQ, err := c.newPoint().ScalarMult(Q, t.Bytes(c.N))
+	if err != nil {
+		return false
+	}
+	x, err := R.Add(R, tQ).Add(R, Y).Add(R, Z).BytesX()
+	if err != nil {
+		return false
+	}
+	e := bigmod.NewNat()
+	hashToNat(c, e, hash)
+	return checkVerifySM2EC(c, x, r, e)
+}
+
+// AdaptorAdapt adapts the ASN.1 encoded pre-signature with the witness y (32 bytes big-endian),
+// returns the ASN.1 encoded SM2 signature.
+//
+// The caller should verify the pre-signature with AdaptorPreVerifyASN1 before adapting.
+func AdaptorAdapt(preSig, witness []byte) ([]byte, error) {
+	c := p256()
+	r, s, _, _, _, err := parseAdaptorPreSignature(c, preSig)
+	if err != nil {
+		return nil, err
+	}
+	y, err := bigmod.NewNat().SetBytes(witness, c.N)
+	if err != nil || y.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid adaptor witness")
+	}
+	s.Add(y, c.N)
+	t := bigmod.NewNat().Set(s)
+	t.Add(r, c.N)
+	if s.IsZero() == 1 || t.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid adaptor witness")
+	}
+	return EncodeSignature(r.Bytes(c.N), s.Bytes(c.N))
+}
+
+// AdaptorExtract extracts the witness y (32 bytes big-endian) of the statement from the ASN.1 encoded
+// SM2 signature and the pre-signature.
+func AdaptorExtract(sig, preSig, statement []byte) ([]byte, error) {
+	c := p256()
+	Y, err := parseAdaptorStatement(c, statement)
+	if err != nil {
+		return nil, err
+	}
+	r, s, _, _, _, err := parseAdaptorPreSignature(c, preSig)
+	if err != nil {
+		return nil, err
+	}
+	rBytes, sBytes, err := parseSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	sigR, err := bigmod.NewNat().SetBytes(rBytes, c.N)
+	if err != nil || sigR.Equal(r) != 1 {
+		return nil, errors.New("sm2: signature does not match pre-signature")
+	}
+	y, err := bigmod.NewNat().SetBytes(sBytes, c.N)
+	if err != nil {
+		return nil, errors.New("sm2: invalid signature")
+	}
+	// y = s - ŝ
+	y.Sub(s, c.N)
+	yG, err := c.newPoint().ScalarBaseMult(y.Bytes(c.N))
+	if err != nil {
+		return nil, err
+	}
+	if _subtle.ConstantTimeCompare(yG.Bytes(), Y.Bytes()) != 1 {
+		return nil, errors.New("sm2: signature does not match pre-signature")
+	}
+	return y.Bytes(c.N), nil
+}
+
+func parseAdaptorStatement(c *sm2Curve, statement []byte) (*_sm2ec.SM2P256Point, error) {
+	if len(statement) == 0 || statement[0] == 0 {
+		return nil, errors.New("sm2: invalid adaptor statement")
+	}
+	Y, err := c.newPoint().SetBytes(statement)
+	if err != nil {
+		return nil, errors.New("sm2: invalid adaptor statement")
+	}
+	return Y, nil
+}
+
+func parseAdaptorPreSignature(c *sm2Curve, preSig []byte) (r, s *bigmod.Nat, Z *_sm2ec.SM2P256Point, proofC, proofZ *bigmod.Nat, err error) {
+	var (
+		rBytes, sBytes, zBytes, cBytes, pBytes []byte
+		inner                                  cryptobyte.String
+	)
+	input := cryptobyte.String(preSig)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(&rBytes) ||
+		!inner.ReadASN1Integer(&sBytes) ||
+		!inner.ReadASN1Bytes(&zBytes, asn1.OCTET_STRING) ||
+		!inner.ReadASN1Integer(&cBytes) ||
+		!inner.ReadASN1Integer(&pBytes) ||
+		!inner.Empty() {
+		err = errors.New("sm2: invalid asn1 format pre-signature")
+		return
+	}
+	errInvalid := errors.New("sm2: invalid pre-signature")
+	if r, err = bigmod.NewNat().SetBytes(rBytes, c.N); err != nil || r.IsZero() == 1 {
+		return nil, nil, nil, nil, nil, errInvalid
+	}
+	if s, err = bigmod.NewNat().SetBytes(sBytes, c.N); err != nil || s.IsZero() == 1 {
+		return nil, nil, nil, nil, nil, errInvalid
+	}
+	if len(zBytes) == 0 || zBytes[0] == 0 {
+		return nil, nil, nil, nil, nil, errInvalid
+	}
+	if Z, err = c.newPoint().SetBytes(zBytes); err != nil {
+		return nil, nil, nil, nil, nil, errInvalid
+	}
+	if proofC, err = bigmod.NewNat().SetBytes(cBytes, c.N); err != nil {
+		return nil, nil, nil, nil, nil, errInvalid
+	}
+	if proofZ, err = bigmod.NewNat().SetBytes(pBytes, c.N); err != nil {
+		return nil, nil, nil, nil, nil, errInvalid
+	}
+	return
+}
+
+// proveDLEQ creates a non-interactive Chaum-Pedersen proof that log_G(Q) == log_Y(Z) == d.
+//
+//	w random, A1 = [w]G, A2 = [w]Y, c = SM3(Q || Y || Z || A1 || A2) mod n, z = w + cd mod n.
+func proveDLEQ(c *sm2Curve, rand io.Reader, d *bigmod.Nat, Q, Y, Z *_sm2ec.SM2P256Point) (*bigmod.Nat, *bigmod.Nat, error) {
+	w, A1, err := randomPoint(c, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	A2, err := c.newPoint().ScalarMult(Y, w.Bytes(c.N))
+	if err != nil {
+		return nil, nil, err
+	}
+	challenge := dleqChallenge(c, Q, Y, Z, A1, A2)
+	z := bigmod.NewNat().Set(challenge)
+	z.Mul(d, c.N)
+	z.Add(w, c.N)
+	return challenge, z, nil
+}
+
+// verifyDLEQ verifies the proof created by proveDLEQ:
+//
+//	A1 = [z]G - [c]Q, A2 = [z]Y - [c]Z, check c == SM3(Q || Y || Z || A1 || A2) mod n.
+func verifyDLEQ(c *sm2Curve, challenge, z *bigmod.Nat, Q, Y, Z *_sm2ec.SM2P256Point) bool {
+	negC := bigmod.NewNat().ExpandFor(c.N).Sub(challenge, c.N)
+	A1, err := c.newPoint().ScalarBaseMult(z.Bytes(c.N))
+	if err != nil {
+		return false
+	}
+	cQ, err := c.newPoint().ScalarMult(Q, negC.Bytes(c.N))
+	if err != nil {
+		return false
+	}
+	A2, err := c.newPoint().ScalarMult(Y, z.Bytes(c.N))
+	if err != nil {
+		return false
+	}
+	cZ, err := c.newPoint().ScalarMult(Z, negC.Bytes(c.N))
+	if err != nil {
+		return false
+	}
+	A1.Add(A1, cQ)
+	A2.Add(A2, cZ)
+	return dleqChallenge(c, Q, Y, Z, A1, A2).Equal(challenge) == 1
+}
+
+func dleqChallenge(c *sm2Curve, points ...*_sm2ec.SM2P256Point) *bigmod.Nat {
+	md := sm3.New()
+	for _, p := range points {
+		md.Write(p.Bytes())
+	}
+	e := bigmod.NewNat()
+	hashToNat(c, e, md.Sum(nil))
+	return e
+}
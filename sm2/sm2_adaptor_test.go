@@ -0,0 +1,92 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+func generateAdaptorStatement(t *testing.T) (witness, statement []byte) {
+	key, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key.D.FillBytes(make([]byte, 32)), elliptic.Marshal(key.Curve, key.X, key.Y)
+}
+
+func TestAdaptorSignature(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	for i := 0; i < 5; i++ {
+		witness, statement := generateAdaptorStatement(t)
+		hashed := sm3.Sum([]byte{byte(i)})
+		preSig, err := AdaptorPreSignASN1(rand.Reader, priv, hashed[:], statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !AdaptorPreVerifyASN1(&priv.PublicKey, hashed[:], statement, preSig) {
+			t.Fatal("pre-signature verification failed")
+		}
+		if VerifyASN1(&priv.PublicKey, hashed[:], preSig) {
+			t.Fatal("pre-signature should not be a valid signature")
+		}
+		sig, err := AdaptorAdapt(preSig, witness)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyASN1(&priv.PublicKey, hashed[:], sig) {
+			t.Fatal("adapted signature verification failed")
+		}
+		extracted, err := AdaptorExtract(sig, preSig, statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(extracted, witness) {
+			t.Fatalf("extracted witness %x, want %x", extracted, witness)
+		}
+	}
+}
+
+func TestAdaptorSignatureInvalid(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	other, _ := GenerateKey(rand.Reader)
+	witness, statement := generateAdaptorStatement(t)
+	otherWitness, otherStatement := generateAdaptorStatement(t)
+	hashed := sm3.Sum([]byte("atomic swap"))
+
+	if _, err := AdaptorPreSignASN1(rand.Reader, priv, hashed[:], []byte{0}); err == nil {
+		t.Error("expected error for infinity statement")
+	}
+	preSig, _ := AdaptorPreSignASN1(rand.Reader, priv, hashed[:], statement)
+	if AdaptorPreVerifyASN1(&other.PublicKey, hashed[:], statement, preSig) {
+		t.Error("pre-signature verified with another public key")
+	}
+	if AdaptorPreVerifyASN1(&priv.PublicKey, hashed[:], otherStatement, preSig) {
+		t.Error("pre-signature verified with another statement")
+	}
+	if AdaptorPreVerifyASN1(&priv.PublicKey, hashed[:], statement, preSig[:len(preSig)-1]) {
+		t.Error("truncated pre-signature verified")
+	}
+	hashed[0] ^= 0xff
+	if AdaptorPreVerifyASN1(&priv.PublicKey, hashed[:], statement, preSig) {
+		t.Error("pre-signature verified with another hash")
+	}
+	hashed[0] ^= 0xff
+
+	sig, _ := AdaptorAdapt(preSig, otherWitness)
+	if VerifyASN1(&priv.PublicKey, hashed[:], sig) {
+		t.Error("signature adapted with wrong witness verified")
+	}
+	if _, err := AdaptorExtract(sig, preSig, statement); err == nil {
+		t.Error("expected error for extracting from mismatched signature")
+	}
+	if _, err := AdaptorAdapt(preSig, make([]byte, 32)); err == nil {
+		t.Error("expected error for zero witness")
+	}
+	sig, _ = AdaptorAdapt(preSig, witness)
+	if _, err := AdaptorExtract(sig, preSig, otherStatement); err == nil {
+		t.Error("expected error for mismatched statement")
+	}
+}
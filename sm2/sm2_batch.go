@@ -0,0 +1,149 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"sort"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	_sm2ec "github.com/emmansun/gmsm/internal/sm2ec"
+)
+
+// This file contains SM2 batch signature verification.
+//
+// SM2 signature (r, s) only carries the x-coordinate of R (through r = e + x1 mod n),
+// so the classic random linear combination check can't be applied to standard signatures.
+// BatchVerifier computes [s]G + [r+s]PA for each entry with a variable-time interleaved
+// double scalar multiplication, which shares the doublings of the two multiplications,
+// and shares the expensive field inversion of the affine conversion among the whole
+// batch, parsed public keys are also reused among the entries signed by the same key.
+
+// BatchVerifier verifies many (public key, digest, signature) triples together.
+// The zero value is ready to use. A BatchVerifier is not safe for concurrent use.
+type BatchVerifier struct {
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	pub       *ecdsa.PublicKey
+	hash, sig []byte
+}
+
+// Add appends a (public key, digest, signature) triple to the batch.
+// The digest should be the same as the hash argument of VerifyASN1,
+// and sig should be ASN.1 encoded.
+func (bv *BatchVerifier) Add(pub *ecdsa.PublicKey, hash, sig []byte) {
+	bv.entries = append(bv.entries, batchEntry{pub, hash, sig})
+}
+
+// Len returns the number of entries in the batch.
+func (bv *BatchVerifier) Len() int {
+	return len(bv.entries)
+}
+
+// Reset removes all entries from the batch.
+func (bv *BatchVerifier) Reset() {
+	bv.entries = bv.entries[:0]
+}
+
+// Verify verifies all entries in the batch. It reports whether all signatures
+// are valid, if not, the indexes (in adding order) of the invalid entries are returned.
+//
+// An empty batch is considered valid.
+func (bv *BatchVerifier) Verify() (bool, []int) {
+	var failed []int
+	c := p256()
+	keys := make(map[*ecdsa.PublicKey]*_sm2ec.SM2P256Point)
+	points := make([]*_sm2ec.SM2P256Point, 0, len(bv.entries))
+	pending := make([]int, 0, len(bv.entries))
+	rs := make([]*bigmod.Nat, 0, len(bv.entries))
+	es := make([]*bigmod.Nat, 0, len(bv.entries))
+
+	for i, entry := range bv.entries {
+		if entry.pub == nil {
+			failed = append(failed, i)
+			continue
+		}
+		if entry.pub.Curve.Params() != P256().Params() {
+			if !verifyLegacy(entry.pub, entry.hash, entry.sig) {
+				failed = append(failed, i)
+			}
+			continue
+		}
+		Q, ok := keys[entry.pub]
+		if !ok {
+			var err error
+			Q, err = c.pointFromAffine(entry.pub.X, entry.pub.Y)
+			if err != nil {
+				Q = nil
+			}
+			keys[entry.pub] = Q
+		}
+		if Q == nil {
+			failed = append(failed, i)
+			continue
+		}
+		R, r, e, ok := prepareVerifySM2EC(c, Q, entry.hash, entry.sig)
+		if !ok {
+			failed = append(failed, i)
+			continue
+		}
+		points = append(points, R)
+		pending = append(pending, i)
+		rs = append(rs, r)
+		es = append(es, e)
+	}
+
+	xs := _sm2ec.BatchBytesX(points)
+	for j, Rx := range xs {
+		if Rx == nil || !checkVerifySM2EC(c, Rx, rs[j], es[j]) {
+			failed = append(failed, pending[j])
+		}
+	}
+	if len(failed) == 0 {
+		return true, nil
+	}
+	sort.Ints(failed)
+	return false, failed
+}
+
+// prepareVerifySM2EC parses the signature and returns point [s]G + [r+s]Q,
+// r and e which will be used to complete the verification.
+func prepareVerifySM2EC(c *sm2Curve, Q *_sm2ec.SM2P256Point, hash, sig []byte) (*_sm2ec.SM2P256Point, *bigmod.Nat, *bigmod.Nat, bool) {
+	rBytes, sBytes, err := parseSignature(sig)
+	if err != nil {
+		return nil, nil, nil, false
+	}
+	r, err := bigmod.NewNat().SetBytes(rBytes, c.N)
+	if err != nil || r.IsZero() == 1 {
+		return nil, nil, nil, false
+	}
+	s, err := bigmod.NewNat().SetBytes(sBytes, c.N)
+	if err != nil || s.IsZero() == 1 {
+		return nil, nil, nil, false
+	}
+
+	e := bigmod.NewNat()
+	hashToNat(c, e, hash)
+
+	t := bigmod.NewNat().Set(r)
+	t.Add(s, c.N)
+	if t.IsZero() == 1 {
+		return nil, nil, nil, false
+	}
+
+	p, err := c.newPoint().VarTimeDoubleScalarBaseMult(s.Bytes(c.N), Q, t.Bytes(c.N))
+	if err != nil {
+		return nil, nil, nil, false
+	}
+	return p, r, e, true
+}
+
+// checkVerifySM2EC reports whether (Rx + e) mod N == r.
+func checkVerifySM2EC(c *sm2Curve, Rx []byte, r, e *bigmod.Nat) bool {
+	v, err := bigmod.NewNat().SetOverflowingBytes(Rx, c.N)
+	if err != nil {
+		return false
+	}
+	v.Add(e, c.N)
+	return v.Equal(r) == 1
+}
@@ -0,0 +1,121 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"reflect"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+func TestBatchVerifier(t *testing.T) {
+	keys := make([]*PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = GenerateKey(rand.Reader)
+	}
+	legacy, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	legacyKey := &PrivateKey{*legacy}
+
+	bv := new(BatchVerifier)
+	if ok, failed := bv.Verify(); !ok || failed != nil {
+		t.Fatalf("empty batch should be valid")
+	}
+
+	var expected []int
+	for i := 0; i < 20; i++ {
+		priv := keys[i%len(keys)]
+		if i == 11 {
+			priv = legacyKey
+		}
+		hashed := sm3.Sum([]byte{byte(i)})
+		sig, err := SignASN1(rand.Reader, priv, hashed[:], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch i {
+		case 3:
+			hashed[0] ^= 0xff
+			expected = append(expected, i)
+		case 7:
+			sig = sig[:len(sig)-1]
+			expected = append(expected, i)
+		case 13:
+			priv = keys[(i+1)%len(keys)]
+			expected = append(expected, i)
+		case 17:
+			sig[len(sig)-1] ^= 0x01
+			expected = append(expected, i)
+		}
+		bv.Add(&priv.PublicKey, hashed[:], sig)
+	}
+	bv.Add(nil, nil, nil)
+	expected = append(expected, 20)
+
+	if bv.Len() != 21 {
+		t.Fatalf("Len() = %v, want 21", bv.Len())
+	}
+	ok, failed := bv.Verify()
+	if ok {
+		t.Fatal("batch with invalid entries should fail")
+	}
+	if !reflect.DeepEqual(failed, expected) {
+		t.Fatalf("failed = %v, want %v", failed, expected)
+	}
+	for i, entry := range bv.entries {
+		isFailed := len(expected) > 0 && expected[0] == i
+		if isFailed {
+			expected = expected[1:]
+		}
+		if entry.pub != nil && VerifyASN1(entry.pub, entry.hash, entry.sig) == isFailed {
+			t.Errorf("#%v: batch result mismatches VerifyASN1", i)
+		}
+	}
+
+	bv.Reset()
+	for i := 0; i < 5; i++ {
+		hashed := sm3.Sum([]byte{byte(i)})
+		sig, _ := SignASN1(rand.Reader, keys[0], hashed[:], nil)
+		bv.Add(&keys[0].PublicKey, hashed[:], sig)
+	}
+	if ok, failed := bv.Verify(); !ok || failed != nil {
+		t.Fatalf("valid batch failed, %v", failed)
+	}
+}
+
+func newBenchmarkBatch(b *testing.B) *BatchVerifier {
+	const batchSize = 64
+	bv := new(BatchVerifier)
+	for i := 0; i < batchSize; i++ {
+		priv, _ := GenerateKey(rand.Reader)
+		hashed := sm3.Sum([]byte{byte(i)})
+		sig, _ := SignASN1(rand.Reader, priv, hashed[:], nil)
+		bv.Add(&priv.PublicKey, hashed[:], sig)
+	}
+	return bv
+}
+
+func BenchmarkBatchVerify_SM2(b *testing.B) {
+	bv := newBenchmarkBatch(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ok, _ := bv.Verify(); !ok {
+			b.Fatal("verify failed")
+		}
+	}
+}
+
+func BenchmarkBatchVerifyEach_SM2(b *testing.B) {
+	bv := newBenchmarkBatch(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, entry := range bv.entries {
+			if !VerifyASN1(entry.pub, entry.hash, entry.sig) {
+				b.Fatal("verify failed")
+			}
+		}
+	}
+}
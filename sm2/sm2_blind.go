@@ -0,0 +1,223 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+)
+
+// This file contains SM2 blind signature.
+//
+//	Signer:    random k, R = [k]G, sends R to requester.
+//	Requester: e' = SM3(ZA || M), random α, β, R' = R + [α]G + [β]PA,
+//	           r' = (x(R') + e') mod n, r = r' - β + α mod n, sends r to signer.
+//	Signer:    s = (1 + d)⁻¹(k + r) - r mod n, sends s to requester.
+//	Requester: s' = s + α mod n, the signature of M is (r', s').
+//
+// Because [s]G + [s + r]PA = R, we have [s']G + [s' + r']PA = R + [α]G + [β]PA = R',
+// so (r', s') is a standard SM2 signature, while the signer can't link (r, s) with (r', s').
+//
+// WARNING: the signing sessions of one key MUST NOT overlap. The scheme is linear, with
+// many concurrent open sessions (about 256 or more), a requester can solve the ROS problem
+// (Benhamouda et al. 2020) and forge one more valid signature than the signer issued. So
+// NewBlindSigner refuses to start a session while another session of the same key is open,
+// a session is closed by Sign or Close.
+
+// ErrBlindSessionOpen is returned by NewBlindSigner if another blind signature session
+// of the key is open.
+var ErrBlindSessionOpen = errors.New("sm2: another blind signature session of the key is open")
+
+// blindSessions holds the open blind signature sessions, keyed by the encoded public key,
+// so that copies of one private key share the session.
+var blindSessions = struct {
+	sync.Mutex
+	open map[string]*BlindSigner
+}{open: make(map[string]*BlindSigner)}
+
+// BlindSigner is the signer side of a blind signature session, it can be used only once.
+type BlindSigner struct {
+	priv *PrivateKey
+	key  string
+	k    *bigmod.Nat
+}
+
+// NewBlindSigner starts a blind signature session, returns the signer and
+// the commitment R = [k]G (uncompressed point encoding) which should be sent to the requester.
+// It returns ErrBlindSessionOpen if another session of priv has not been closed.
+func NewBlindSigner(rand io.Reader, priv *PrivateKey) (*BlindSigner, []byte, error) {
+	if priv.Curve.Params() != P256().Params() {
+		return nil, nil, errors.New("sm2: blind signature only supports sm2 curve")
+	}
+	k, R, err := randomPoint(p256(), rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs := &BlindSigner{priv: priv, key: string(elliptic.Marshal(priv.Curve, priv.X, priv.Y)), k: k}
+	blindSessions.Lock()
+	defer blindSessions.Unlock()
+	if _, ok := blindSessions.open[bs.key]; ok {
+		return nil, nil, ErrBlindSessionOpen
+	}
+	blindSessions.open[bs.key] = bs
+	return bs, R.Bytes(), nil
+}
+
+// Close aborts the session without signing, so that a new session of the key can be started.
+// It's a no-op if the session has been closed.
+func (bs *BlindSigner) Close() {
+	bs.close()
+}
+
+// close closes the session and returns the nonce k, nil if the session has been closed.
+func (bs *BlindSigner) close() *bigmod.Nat {
+	blindSessions.Lock()
+	defer blindSessions.Unlock()
+	k := bs.k
+	bs.k = nil
+	if blindSessions.open[bs.key] == bs {
+		delete(blindSessions.open, bs.key)
+	}
+	return k
+}
+
+// Sign signs the blinded message r sent by the requester, returns the blinded signature s.
+// The session is closed, the signer should be discarded after signing.
+func (bs *BlindSigner) Sign(blinded []byte) ([]byte, error) {
+	k := bs.close()
+	if k == nil {
+		return nil, errors.New("sm2: blind signer has been used")
+	}
+
+	c := p256()
+	r, err := bigmod.NewNat().SetBytes(blinded, c.N)
+	if err != nil || r.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid blinded message")
+	}
+	s, err := dp1Inverse(c, bs.priv)
+	if err != nil {
+		return nil, err
+	}
+	// s = (1 + d)⁻¹(k + r) - r
+	k.Add(r, c.N)
+	s.Mul(k, c.N)
+	s.Sub(r, c.N)
+	if s.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid blinded message")
+	}
+	return s.Bytes(c.N), nil
+}
+
+// Blinder is the requester side of a blind signature session, it can be used only once.
+type Blinder struct {
+	pub    *ecdsa.PublicKey
+	digest []byte
+	alpha  *bigmod.Nat
+	r      *bigmod.Nat // r'
+}
+
+// NewBlinder blinds the message with the signer's public key and commitment R,
+// returns the blinder and the blinded message r which should be sent to the signer.
+// The uid can be empty, meaning to use the default value.
+func NewBlinder(rand io.Reader, pub *ecdsa.PublicKey, uid, msg, commitment []byte) (*Blinder, []byte, error) {
+	if pub.Curve.Params() != P256().Params() {
+		return nil, nil, errors.New("sm2: blind signature only supports sm2 curve")
+	}
+	digest, err := CalculateSM2Hash(pub, msg, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := p256()
+	Q, err := c.pointFromAffine(pub.X, pub.Y)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(commitment) == 0 || commitment[0] == 0 {
+		return nil, nil, errors.New("sm2: invalid commitment")
+	}
+	R, err := c.newPoint().SetBytes(commitment)
+	if err != nil {
+		return nil, nil, errors.New("sm2: invalid commitment")
+	}
+	e := bigmod.NewNat()
+	hashToNat(c, e, digest)
+
+	for {
+		alpha, aG, err := randomPoint(c, rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		beta, err := randomScalar(c, rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		bP, err := c.newPoint().ScalarMult(Q, beta.Bytes(c.N))
+		if err != nil {
+			return nil, nil, err
+		}
+		// R' = R + [α]G + [β]PA
+		x, err := aG.Add(aG, bP).Add(aG, R).BytesX()
+		if err != nil {
+			continue
+		}
+		rPrime, err := bigmod.NewNat().SetOverflowingBytes(x, c.N)
+		if err != nil {
+			return nil, nil, err
+		}
+		rPrime.Add(e, c.N)
+		if rPrime.IsZero() == 1 {
+			continue
+		}
+		// r = r' - β + α
+		r := bigmod.NewNat().Set(rPrime)
+		r.Sub(beta, c.N)
+		r.Add(alpha, c.N)
+		if r.IsZero() == 1 {
+			continue
+		}
+		return &Blinder{pub: pub, digest: digest, alpha: alpha, r: rPrime}, r.Bytes(c.N), nil
+	}
+}
+
+// Unblind unblinds the signer's blinded signature s, returns the ASN.1 encoded signature (r', s'),
+// which can be verified by VerifyASN1WithSM2 with the same uid and message.
+func (b *Blinder) Unblind(blindSig []byte) ([]byte, error) {
+	if b.alpha == nil {
+		return nil, errors.New("sm2: blinder has been used")
+	}
+	alpha := b.alpha
+	b.alpha = nil
+
+	c := p256()
+	s, err := bigmod.NewNat().SetBytes(blindSig, c.N)
+	if err != nil {
+		return nil, errors.New("sm2: invalid blind signature")
+	}
+	// s' = s + α
+	s.Add(alpha, c.N)
+	sig, err := EncodeSignature(b.r.Bytes(c.N), s.Bytes(c.N))
+	if err != nil {
+		return nil, errors.New("sm2: invalid blind signature")
+	}
+	if !VerifyASN1(b.pub, b.digest, sig) {
+		return nil, errors.New("sm2: invalid blind signature")
+	}
+	return sig, nil
+}
+
+// randomScalar returns a random scalar in [1, n-1].
+func randomScalar(c *sm2Curve, rand io.Reader) (*bigmod.Nat, error) {
+	k := bigmod.NewNat()
+	b := make([]byte, c.N.Size())
+	for {
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		if _, err := k.SetBytes(b, c.N); err == nil && k.IsZero() == 0 {
+			return k, nil
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestBlindSignature(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	msg := []byte("blind signature for e-voting")
+	uid := []byte("voter")
+	for i := 0; i < 10; i++ {
+		signer, commitment, err := NewBlindSigner(rand.Reader, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blinder, blinded, err := NewBlinder(rand.Reader, &priv.PublicKey, uid, msg, commitment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blindSig, err := signer.Sign(blinded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := blinder.Unblind(blindSig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyASN1WithSM2(&priv.PublicKey, uid, msg, sig) {
+			t.Fatal("VerifyASN1WithSM2 failed")
+		}
+		r, s, _ := parseSignature(sig)
+		digest, _ := CalculateSM2Hash(&priv.PublicKey, msg, uid)
+		if !Verify(&priv.PublicKey, digest, new(big.Int).SetBytes(r), new(big.Int).SetBytes(s)) {
+			t.Fatal("Verify failed")
+		}
+		if new(big.Int).SetBytes(r).Cmp(new(big.Int).SetBytes(blinded)) == 0 {
+			t.Fatal("r should be blinded")
+		}
+		if _, err = signer.Sign(blinded); err == nil {
+			t.Fatal("blind signer should not be reused")
+		}
+		if _, err = blinder.Unblind(blindSig); err == nil {
+			t.Fatal("blinder should not be reused")
+		}
+	}
+}
+
+func TestBlindSignatureInvalid(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	msg := []byte("blind signature")
+	signer, commitment, _ := NewBlindSigner(rand.Reader, priv)
+	if _, _, err := NewBlinder(rand.Reader, &priv.PublicKey, nil, msg, []byte{0}); err == nil {
+		t.Error("expected error for infinity commitment")
+	}
+	blinder, blinded, _ := NewBlinder(rand.Reader, &priv.PublicKey, nil, msg, commitment)
+	blindSig, _ := signer.Sign(blinded)
+	blindSig[31] ^= 1
+	if _, err := blinder.Unblind(blindSig); err == nil {
+		t.Error("expected error for tampered blind signature")
+	}
+	signer, _, _ = NewBlindSigner(rand.Reader, priv)
+	if _, err := signer.Sign(make([]byte, 32)); err == nil {
+		t.Error("expected error for zero blinded message")
+	}
+
+	legacy, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, _, err := NewBlindSigner(rand.Reader, &PrivateKey{*legacy}); err == nil {
+		t.Error("expected error for non sm2 curve")
+	}
+}
+
+func TestBlindSignatureSessions(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	msg := []byte("blind signature for e-cash")
+	first, commitment, err := NewBlindSigner(rand.Reader, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewBlindSigner(rand.Reader, priv); err != ErrBlindSessionOpen {
+		t.Fatalf("expected ErrBlindSessionOpen, got %v", err)
+	}
+	// a copy of the key shares the session
+	copied := &PrivateKey{priv.PrivateKey}
+	if _, _, err := NewBlindSigner(rand.Reader, copied); err != ErrBlindSessionOpen {
+		t.Fatalf("expected ErrBlindSessionOpen for a copy of the key, got %v", err)
+	}
+	// sessions of other keys are independent
+	other, _ := GenerateKey(rand.Reader)
+	otherSigner, _, err := NewBlindSigner(rand.Reader, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner.Close()
+
+	_, blinded, err := NewBlinder(rand.Reader, &priv.PublicKey, nil, msg, commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Sign(blinded); err != nil {
+		t.Fatal(err)
+	}
+	signer, _, err := NewBlindSigner(rand.Reader, priv)
+	if err != nil {
+		t.Fatalf("expected new session after Sign, got %v", err)
+	}
+	signer.Close()
+	signer.Close()
+	if _, err := signer.Sign(blinded); err == nil {
+		t.Error("expected error for closed session")
+	}
+	signer, _, err = NewBlindSigner(rand.Reader, priv)
+	if err != nil {
+		t.Fatalf("expected new session after Close, got %v", err)
+	}
+	// closing a stale session doesn't close the current one
+	first.Close()
+	if _, _, err := NewBlindSigner(rand.Reader, priv); err != ErrBlindSessionOpen {
+		t.Fatalf("expected ErrBlindSessionOpen, got %v", err)
+	}
+	signer.Close()
+}
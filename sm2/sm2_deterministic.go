@@ -0,0 +1,90 @@
+package sm2
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// This file contains the deterministic nonce generation for SM2 signature,
+// it follows RFC 6979, Section 3.2 with HMAC-SM3 as the HMAC function.
+//
+// The generator is exposed as an io.Reader, each Read call yields the next
+// candidate T of step h, so the rejection sampling in randomPoint and the
+// retry loop in signSM2ECRaw both map to the "otherwise" branch of step h.3.
+
+// deterministicNonceReader is the HMAC_DRBG described in RFC 6979, Section 3.2.
+type deterministicNonceReader struct {
+	k, v    []byte
+	mac     hash.Hash
+	started bool
+}
+
+// newDeterministicNonceReader creates the nonce generator from the private key
+// and the digest to be signed, steps a to g of RFC 6979, Section 3.2.
+func newDeterministicNonceReader(priv *PrivateKey, digest []byte) (*deterministicNonceReader, error) {
+	n := priv.Curve.Params().N
+	if priv.D == nil || priv.D.Sign() <= 0 || priv.D.Cmp(n) >= 0 {
+		return nil, errors.New("sm2: invalid private key")
+	}
+	qlen := (n.BitLen() + 7) / 8
+
+	// int2octets(x)
+	x := make([]byte, qlen)
+	priv.D.FillBytes(x)
+	// bits2octets(h1) = int2octets(bits2int(h1) mod q)
+	h := hashToInt(digest, priv.Curve)
+	if h.Cmp(n) >= 0 {
+		h.Sub(h, n)
+	}
+	h1 := h.FillBytes(make([]byte, qlen))
+
+	size := sm3.Size
+	d := &deterministicNonceReader{
+		k: make([]byte, size),
+		v: make([]byte, size),
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.rekey(0x00, x, h1)
+	d.rekey(0x01, x, h1)
+	return d, nil
+}
+
+// rekey computes K = HMAC_K(V || tag || data...), V = HMAC_K(V).
+func (d *deterministicNonceReader) rekey(tag byte, data ...[]byte) {
+	d.mac = hmac.New(sm3.New, d.k)
+	d.mac.Write(d.v)
+	d.mac.Write([]byte{tag})
+	for _, b := range data {
+		d.mac.Write(b)
+	}
+	d.k = d.mac.Sum(d.k[:0])
+	d.mac = hmac.New(sm3.New, d.k)
+	d.nextV()
+}
+
+// nextV computes V = HMAC_K(V).
+func (d *deterministicNonceReader) nextV() {
+	d.mac.Reset()
+	d.mac.Write(d.v)
+	d.v = d.mac.Sum(d.v[:0])
+}
+
+// Read fills p with the next nonce candidate T. It never returns an error.
+func (d *deterministicNonceReader) Read(p []byte) (int, error) {
+	if d.started {
+		// the previous candidate was rejected: K = HMAC_K(V || 0x00), V = HMAC_K(V)
+		d.rekey(0x00)
+	}
+	d.started = true
+	n := 0
+	for n < len(p) {
+		d.nextV()
+		n += copy(p[n:], d.v)
+	}
+	return n, nil
+}
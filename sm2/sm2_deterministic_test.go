@@ -0,0 +1,142 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// rfc6979Nonce is a straightforward implementation of RFC 6979, Section 3.2
+// with HMAC-SM3, it returns the first candidate k in [1, n-1].
+func rfc6979Nonce(priv *PrivateKey, digest []byte) *big.Int {
+	n := priv.Curve.Params().N
+	qlen := (n.BitLen() + 7) / 8
+	mac := func(key []byte, data ...[]byte) []byte {
+		m := hmac.New(sm3.New, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+	x := priv.D.FillBytes(make([]byte, qlen))
+	h := new(big.Int).Mod(hashToInt(digest, priv.Curve), n)
+	h1 := h.FillBytes(make([]byte, qlen))
+
+	v := bytes.Repeat([]byte{0x01}, sm3.Size)
+	k := make([]byte, sm3.Size)
+	k = mac(k, v, []byte{0x00}, x, h1)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h1)
+	v = mac(k, v)
+	for {
+		v = mac(k, v)
+		candidate := new(big.Int).SetBytes(v)
+		if candidate.Sign() > 0 && candidate.Cmp(n) < 0 {
+			return candidate
+		}
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
+
+func TestSignASN1Deterministic(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := NewDeterministicSM2SignerOption(true, nil)
+	msg := []byte("deterministic sm2 signature")
+	sig1, err := SignASN1(nil, priv, msg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := priv.Sign(rand.Reader, msg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig1, sig2) {
+		t.Errorf("signatures are not deterministic")
+	}
+	if !VerifyASN1WithSM2(&priv.PublicKey, nil, msg, sig1) {
+		t.Errorf("failed to verify deterministic signature")
+	}
+	sig3, err := SignASN1(nil, priv, []byte("another message"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sig1, sig3) {
+		t.Errorf("different messages got the same signature")
+	}
+	sig4, err := SignASN1(nil, priv, msg, NewDeterministicSM2SignerOption(true, []byte("Alice")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sig1, sig4) {
+		t.Errorf("different uids got the same signature")
+	}
+}
+
+func TestSignASN1DeterministicNonce(t *testing.T) {
+	privKey, _ := hex.DecodeString("3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8")
+	priv := new(PrivateKey)
+	priv.Curve = P256()
+	priv.D = new(big.Int).SetBytes(privKey)
+	priv.X, priv.Y = priv.Curve.ScalarBaseMult(privKey)
+	digest := sm3.Sum([]byte("sample"))
+	sig, err := SignASN1(nil, priv, digest[:], NewDeterministicSM2SignerOption(false, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyASN1(&priv.PublicKey, digest[:], sig) {
+		t.Fatal("failed to verify deterministic signature")
+	}
+	rBytes, _, err := parseSignature(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// r = e + x1 mod n, where (x1, y1) = [k]G
+	k := rfc6979Nonce(priv, digest[:])
+	params := priv.Curve.Params()
+	x1, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+	r := new(big.Int).Add(x1, new(big.Int).SetBytes(digest[:]))
+	r.Mod(r, params.N)
+	if r.Cmp(new(big.Int).SetBytes(rBytes)) != 0 {
+		t.Errorf("nonce is not derived as RFC 6979")
+	}
+}
+
+func TestSignASN1DeterministicLegacy(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	priv := &PrivateKey{PrivateKey: *key}
+	digest := sm3.Sum([]byte("sample"))
+	opts := NewDeterministicSM2SignerOption(false, nil)
+	sig1, err := SignASN1(nil, priv, digest[:], opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := SignASN1(nil, priv, digest[:], opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig1, sig2) {
+		t.Errorf("signatures are not deterministic")
+	}
+	if !VerifyASN1(&priv.PublicKey, digest[:], sig1) {
+		t.Errorf("failed to verify deterministic signature")
+	}
+}
+
+func TestSignASN1DeterministicInvalidKey(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	priv.D = new(big.Int).Set(priv.Curve.Params().N)
+	if _, err := SignASN1(nil, priv, []byte("sample"), NewDeterministicSM2SignerOption(true, nil)); err == nil {
+		t.Errorf("expected error for invalid private key")
+	}
+}
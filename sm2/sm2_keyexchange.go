@@ -7,6 +7,7 @@ import (
 	"io"
 	"math/big"
 
+	"github.com/emmansun/gmsm/internal/keyexchange"
 	"github.com/emmansun/gmsm/kdf"
 	"github.com/emmansun/gmsm/sm3"
 )
@@ -17,18 +18,21 @@ import (
 // Initiator's flow will be: NewKeyExchange -> InitKeyExchange -> transmission -> ConfirmResponder
 // Responder's flow will be: NewKeyExchange -> waiting ... -> RepondKeyExchange -> transmission -> ConfirmInitiator
 type KeyExchange struct {
-	genSignature bool             // control the optional sign/verify step triggered by responsder
-	keyLength    int              // key length
-	privateKey   *PrivateKey      // owner's encryption private key
-	z            []byte           // owner identifiable id
-	peerPub      *ecdsa.PublicKey // peer public key
-	peerZ        []byte           // peer identifiable id
-	r            *big.Int         // Ephemeral Private Key, random which will be used to compute secret
-	secret       *ecdsa.PublicKey // Ephemeral Public Key, generated secret which will be passed to peer
-	peerSecret   *ecdsa.PublicKey // received peer's secret, Ephemeral Public Key
-	w2           *big.Int         // internal state which will be used when compute the key and signature, 2^w
-	w2Minus1     *big.Int         // internal state which will be used when compute the key and signature, 2^w – 1
-	v            *ecdsa.PublicKey // internal state which will be used when compute the key and signature, u/v
+	genSignature bool              // control the optional sign/verify step triggered by responsder
+	keyLength    int               // key length
+	privateKey   *PrivateKey       // owner's encryption private key
+	z            []byte            // owner identifiable id
+	peerPub      *ecdsa.PublicKey  // peer public key
+	peerZ        []byte            // peer identifiable id
+	r            *big.Int          // Ephemeral Private Key, random which will be used to compute secret
+	secret       *ecdsa.PublicKey  // Ephemeral Public Key, generated secret which will be passed to peer
+	peerSecret   *ecdsa.PublicKey  // received peer's secret, Ephemeral Public Key
+	w2           *big.Int          // internal state which will be used when compute the key and signature, 2^w
+	w2Minus1     *big.Int          // internal state which will be used when compute the key and signature, 2^w – 1
+	v            *ecdsa.PublicKey  // internal state which will be used when compute the key and signature, u/v
+	state        keyexchange.State // current step of the key exchange flow
+	isResponder  bool              // whether the owner is the responder
+	responderSig []byte            // the optional signature of the responder
 }
 
 func destroyBigInt(n *big.Int) {
@@ -56,6 +60,7 @@ func (ke *KeyExchange) Destroy() {
 	destroyBytes(ke.peerZ)
 	destroyBigInt(ke.r)
 	destroyPublicKey(ke.v)
+	ke.state = keyexchange.StateClosed
 }
 
 // NewKeyExchange create one new KeyExchange object
@@ -135,10 +140,14 @@ func (ke *KeyExchange) SetPeerParameters(peerPub *ecdsa.PublicKey, peerUID []byt
 func initKeyExchange(ke *KeyExchange, r *big.Int) {
 	ke.secret.X, ke.secret.Y = ke.privateKey.ScalarBaseMult(r.Bytes())
 	ke.r = r
+	ke.state = keyexchange.StateInitiated
 }
 
 // InitKeyExchange is for initiator's step A1-A3, returns generated Ephemeral Public Key which will be passed to Reponder.
 func (ke *KeyExchange) InitKeyExchange(rand io.Reader) (*ecdsa.PublicKey, error) {
+	if ke.state != keyexchange.StateNew {
+		return nil, ErrKeyExchangeOutOfOrder
+	}
 	r, err := randFieldElement(ke.privateKey, rand)
 	if err != nil {
 		return nil, err
@@ -220,7 +229,9 @@ func respondKeyExchange(ke *KeyExchange, rA *ecdsa.PublicKey, r *big.Int) (*ecds
 	if ke.peerPub == nil {
 		return nil, nil, errors.New("sm2: no peer public key given")
 	}
+	ke.isResponder = true
 	if !ke.privateKey.IsOnCurve(rA.X, rA.Y) {
+		ke.state = keyexchange.StateClosed
 		return nil, nil, errors.New("sm2: invalid initiator's ephemeral public key")
 	}
 	ke.peerSecret = rA
@@ -230,14 +241,16 @@ func respondKeyExchange(ke *KeyExchange, rA *ecdsa.PublicKey, r *big.Int) (*ecds
 
 	ke.mqv()
 	if ke.v.X.Sign() == 0 && ke.v.Y.Sign() == 0 {
+		ke.state = keyexchange.StateClosed
 		return nil, nil, errors.New("sm2: key exchange failed, V is infinity point")
 	}
 
+	ke.state = keyexchange.StateResponded
 	if !ke.genSignature {
 		return ke.secret, nil, nil
 	}
-
-	return ke.secret, ke.sign(true, 0x02), nil
+	ke.responderSig = ke.sign(true, 0x02)
+	return ke.secret, ke.responderSig, nil
 }
 
 // RepondKeyExchange is for responder's step B1-B8, returns generated Ephemeral Public Key and optional signature
@@ -245,6 +258,9 @@ func respondKeyExchange(ke *KeyExchange, rA *ecdsa.PublicKey, r *big.Int) (*ecds
 //
 // It will check if there are peer's public key and validate the peer's Ephemeral Public Key.
 func (ke *KeyExchange) RepondKeyExchange(rand io.Reader, rA *ecdsa.PublicKey) (*ecdsa.PublicKey, []byte, error) {
+	if ke.state != keyexchange.StateNew {
+		return nil, nil, ErrKeyExchangeOutOfOrder
+	}
 	r, err := randFieldElement(ke.privateKey, rand)
 	if err != nil {
 		return nil, nil, err
@@ -259,29 +275,37 @@ func (ke *KeyExchange) RepondKeyExchange(rand io.Reader, rA *ecdsa.PublicKey) (*
 // If the peer's signature is not empty, then it will also validate the peer's
 // signature and return generated signature depends on KeyExchange.genSignature value.
 func (ke *KeyExchange) ConfirmResponder(rB *ecdsa.PublicKey, sB []byte) ([]byte, []byte, error) {
+	if ke.state != keyexchange.StateInitiated {
+		return nil, nil, ErrKeyExchangeOutOfOrder
+	}
 	if ke.peerPub == nil {
 		return nil, nil, errors.New("sm2: no peer public key given")
 	}
 	if !ke.privateKey.IsOnCurve(rB.X, rB.Y) {
+		ke.state = keyexchange.StateClosed
 		return nil, nil, errors.New("sm2: invalid responder's ephemeral public key")
 	}
 	ke.peerSecret = rB
 
 	ke.mqv()
 	if ke.v.X.Sign() == 0 && ke.v.Y.Sign() == 0 {
+		ke.state = keyexchange.StateClosed
 		return nil, nil, errors.New("sm2: key exchange failed, U is infinity point")
 	}
 
 	if len(sB) > 0 {
 		buffer := ke.sign(false, 0x02)
 		if subtle.ConstantTimeCompare(buffer, sB) != 1 {
+			ke.state = keyexchange.StateClosed
 			return nil, nil, errors.New("sm2: invalid responder's signature")
 		}
+		ke.responderSig = append([]byte{}, sB...)
 	}
 	key, err := ke.generateSharedKey(false)
 	if err != nil {
 		return nil, nil, err
 	}
+	ke.state = keyexchange.StateCompleted
 
 	if !ke.genSignature {
 		return key, nil, nil
@@ -291,11 +315,20 @@ func (ke *KeyExchange) ConfirmResponder(rB *ecdsa.PublicKey, sB []byte) ([]byte,
 
 // ConfirmInitiator for responder's step B10
 func (ke *KeyExchange) ConfirmInitiator(s1 []byte) ([]byte, error) {
+	if ke.state != keyexchange.StateResponded {
+		return nil, ErrKeyExchangeOutOfOrder
+	}
 	if s1 != nil {
 		buffer := ke.sign(true, 0x03)
 		if subtle.ConstantTimeCompare(buffer, s1) != 1 {
+			ke.state = keyexchange.StateClosed
 			return nil, errors.New("sm2: invalid initiator's signature")
 		}
 	}
-	return ke.generateSharedKey(true)
+	key, err := ke.generateSharedKey(true)
+	if err != nil {
+		return nil, err
+	}
+	ke.state = keyexchange.StateCompleted
+	return key, nil
 }
@@ -0,0 +1,136 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+
+	"github.com/emmansun/gmsm/internal/keyexchange"
+)
+
+// ErrKeyExchangeOutOfOrder is returned when a method of KeyExchange is called out of order,
+// or called more than once.
+//
+// The state check only rejects a message repeated within one KeyExchange. A message replayed
+// from another session is rejected by the key confirmation, the signatures SB and SA cover the
+// ephemeral public keys of both parties, so ConfirmResponder or ConfirmInitiator fails. Without
+// genSignature there is no key confirmation, the upper layer protocol must confirm the key.
+var ErrKeyExchangeOutOfOrder = errors.New("sm2: key exchange method called out of order")
+
+// KeyExchangeInit is the message sent by the initiator, the result of InitKeyExchange.
+type KeyExchangeInit struct {
+	RA *ecdsa.PublicKey // initiator's ephemeral public key
+}
+
+// KeyExchangeResponse is the message sent by the responder, the result of RepondKeyExchange.
+type KeyExchangeResponse struct {
+	RB *ecdsa.PublicKey // responder's ephemeral public key
+	SB []byte           // optional signature of the responder
+}
+
+// KeyExchangeConfirm is the message sent by the initiator, the signature returned by ConfirmResponder.
+type KeyExchangeConfirm struct {
+	SA []byte // signature of the initiator
+}
+
+func unmarshalEphemeralKey(bytes []byte) (*ecdsa.PublicKey, error) {
+	x, y := elliptic.Unmarshal(P256(), bytes)
+	if x == nil {
+		return nil, errors.New("sm2: invalid ephemeral public key")
+	}
+	return &ecdsa.PublicKey{Curve: P256(), X: x, Y: y}, nil
+}
+
+// MarshalASN1 marshals the message to asn.1 format data, the points are uncompressed:
+//
+//	SM2KeyExchangeInit ::= SEQUENCE {
+//	  type INTEGER (1),
+//	  rA   BIT STRING
+//	}
+func (m *KeyExchangeInit) MarshalASN1() ([]byte, error) {
+	return keyexchange.MarshalInit(elliptic.Marshal(m.RA.Curve, m.RA.X, m.RA.Y))
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data, SM2KeyExchangeInit definition.
+func (m *KeyExchangeInit) UnmarshalASN1(der []byte) error {
+	point, err := keyexchange.UnmarshalInit(der)
+	if err != nil {
+		return fmt.Errorf("sm2: %w", err)
+	}
+	rA, err := unmarshalEphemeralKey(point)
+	if err != nil {
+		return err
+	}
+	m.RA = rA
+	return nil
+}
+
+// MarshalASN1 marshals the message to asn.1 format data, the points are uncompressed:
+//
+//	SM2KeyExchangeResponse ::= SEQUENCE {
+//	  type INTEGER (2),
+//	  rB   BIT STRING,
+//	  sB   OCTET STRING OPTIONAL
+//	}
+func (m *KeyExchangeResponse) MarshalASN1() ([]byte, error) {
+	return keyexchange.MarshalResponse(elliptic.Marshal(m.RB.Curve, m.RB.X, m.RB.Y), m.SB)
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data, SM2KeyExchangeResponse definition.
+func (m *KeyExchangeResponse) UnmarshalASN1(der []byte) error {
+	point, sB, err := keyexchange.UnmarshalResponse(der)
+	if err != nil {
+		return fmt.Errorf("sm2: %w", err)
+	}
+	rB, err := unmarshalEphemeralKey(point)
+	if err != nil {
+		return err
+	}
+	m.RB = rB
+	m.SB = sB
+	return nil
+}
+
+// MarshalASN1 marshals the message to asn.1 format data:
+//
+//	SM2KeyExchangeConfirm ::= SEQUENCE {
+//	  type INTEGER (3),
+//	  sA   OCTET STRING
+//	}
+func (m *KeyExchangeConfirm) MarshalASN1() ([]byte, error) {
+	return keyexchange.MarshalConfirm(m.SA)
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data, SM2KeyExchangeConfirm definition.
+func (m *KeyExchangeConfirm) UnmarshalASN1(der []byte) error {
+	sA, err := keyexchange.UnmarshalConfirm(der)
+	if err != nil {
+		return fmt.Errorf("sm2: %w", err)
+	}
+	m.SA = sA
+	return nil
+}
+
+// TranscriptHash returns the SM3 hash of the key exchange transcript:
+//
+//	SM3(len(ZA) || ZA || len(ZB) || ZB || RA || RB || SB)
+//
+// where A is the initiator, B is the responder, the lengths are 4 bytes big endian, the points
+// are uncompressed and SB is the optional signature of the responder. Both parties get the same
+// value, which can be used to bind the session to the upper layer protocol.
+//
+// It's available after ConfirmResponder (initiator) or RepondKeyExchange (responder).
+func (ke *KeyExchange) TranscriptHash() ([]byte, error) {
+	if !ke.state.HasTranscript() {
+		return nil, ErrKeyExchangeOutOfOrder
+	}
+	zA, zB := ke.z, ke.peerZ
+	rA, rB := ke.secret, ke.peerSecret
+	if ke.isResponder {
+		zA, zB = zB, zA
+		rA, rB = rB, rA
+	}
+	return keyexchange.TranscriptHash(zA, zB,
+		elliptic.Marshal(rA.Curve, rA.X, rA.Y), elliptic.Marshal(rB.Curve, rB.X, rB.Y), ke.responderSig), nil
+}
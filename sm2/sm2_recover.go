@@ -0,0 +1,140 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	"github.com/emmansun/gmsm/internal/randutil"
+	_sm2ec "github.com/emmansun/gmsm/internal/sm2ec"
+)
+
+// This file contains SM2 public key recovery from signature.
+//
+// From s = (1 + d)⁻¹(k - rd) mod n, we have (s + r)d = k - s mod n, so
+// PA = [(s + r)⁻¹](R - [s]G), where R = [k]G. The x-coordinate of R is
+// x1 = r - e mod n (or x1 = r - e + n with negligible probability), and the
+// recovery id records the parity of R's y-coordinate (bit 0) and whether
+// x1 >= n (bit 1).
+
+// SignASN1WithRecoveryID signs a hash (which should be the result of hashing a larger message
+// with SM3) using the SM2 private key priv, returns the ASN.1 encoded signature and the recovery id,
+// which can be used by RecoverPublicKey to recover the public key from the signature.
+//
+// The hash should NOT contain ZA, because ZA depends on the public key which is unknown when
+// recovering. Only SM2 curve is supported.
+func SignASN1WithRecoveryID(rand io.Reader, priv *PrivateKey, hash []byte) (sig []byte, recoveryID byte, err error) {
+	if priv.Curve.Params() != P256().Params() {
+		return nil, 0, errors.New("sm2: public key recovery only supports sm2 curve")
+	}
+	randutil.MaybeReadByte(rand)
+
+	c := p256()
+	r, s, R, err := signSM2ECRaw(c, priv, rand, hash)
+	if err != nil {
+		return nil, 0, err
+	}
+	point := R.Bytes()
+	byteLen := (c.curve.Params().BitSize + 7) / 8
+	recoveryID = point[len(point)-1] & 1
+	if new(big.Int).SetBytes(point[1:1+byteLen]).Cmp(c.curve.Params().N) >= 0 {
+		recoveryID |= 2
+	}
+	sig, err = EncodeSignature(r.Bytes(c.N), s.Bytes(c.N))
+	if err != nil {
+		return nil, 0, err
+	}
+	return sig, recoveryID, nil
+}
+
+// RecoverPublicKey recovers the signer's public key from the hash, the ASN.1 encoded signature
+// and the recovery id returned by SignASN1WithRecoveryID.
+//
+// The caller should still verify the signature with the recovered public key if the recovery id
+// is not trusted, since any signature can be recovered to "some" public key.
+func RecoverPublicKey(hash, sig []byte, recoveryID byte) (*ecdsa.PublicKey, error) {
+	if recoveryID > 3 {
+		return nil, errors.New("sm2: invalid recovery id")
+	}
+	c := p256()
+	rBytes, sBytes, err := parseSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	r, err := bigmod.NewNat().SetBytes(rBytes, c.N)
+	if err != nil || r.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid signature")
+	}
+	s, err := bigmod.NewNat().SetBytes(sBytes, c.N)
+	if err != nil || s.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid signature")
+	}
+	t := bigmod.NewNat().Set(r)
+	t.Add(s, c.N)
+	if t.IsZero() == 1 {
+		return nil, errors.New("sm2: invalid signature")
+	}
+
+	// x1 = r - e mod n
+	e := bigmod.NewNat()
+	hashToNat(c, e, hash)
+	x1 := bigmod.NewNat().Set(r)
+	x1.Sub(e, c.N)
+
+	params := c.curve.Params()
+	byteLen := (params.BitSize + 7) / 8
+	x := new(big.Int).SetBytes(x1.Bytes(c.N))
+	if recoveryID&2 != 0 {
+		x.Add(x, params.N)
+		if x.Cmp(params.P) >= 0 {
+			return nil, errors.New("sm2: invalid recovery id")
+		}
+	}
+	encoded := make([]byte, 1+byteLen)
+	encoded[0] = compressed02 | (recoveryID & 1)
+	x.FillBytes(encoded[1:])
+	R, err := c.newPoint().SetBytes(encoded)
+	if err != nil {
+		return nil, errors.New("sm2: invalid signature or recovery id")
+	}
+
+	// PA = [(s + r)⁻¹](R - [s]G) = [(s + r)⁻¹](R + [n - s]G)
+	negS := bigmod.NewNat().ExpandFor(c.N).Sub(s, c.N)
+	sG, err := c.newPoint().ScalarBaseMult(negS.Bytes(c.N))
+	if err != nil {
+		return nil, err
+	}
+	tInv, err := _sm2ec.P256OrdInverse(t.Bytes(c.N))
+	if err != nil {
+		return nil, err
+	}
+	Q, err := c.newPoint().ScalarMult(R.Add(R, sG), tInv)
+	if err != nil {
+		return nil, err
+	}
+	pub := &ecdsa.PublicKey{Curve: c.curve}
+	pub.X, pub.Y, err = c.pointToAffine(Q)
+	if err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// RecoverPublicKeys returns all the candidate public keys which can verify the
+// signature of hash, it's used when the recovery id is absent.
+func RecoverPublicKeys(hash, sig []byte) ([]*ecdsa.PublicKey, error) {
+	var keys []*ecdsa.PublicKey
+	for id := byte(0); id < 4; id++ {
+		pub, err := RecoverPublicKey(hash, sig, id)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, pub)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("sm2: failed to recover public key")
+	}
+	return keys, nil
+}
@@ -0,0 +1,74 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+func TestRecoverPublicKey(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		priv, _ := GenerateKey(rand.Reader)
+		hashed := sm3.Sum([]byte{byte(i)})
+		sig, v, err := SignASN1WithRecoveryID(rand.Reader, priv, hashed[:])
+		if err != nil {
+			t.Fatalf("sign failed %v", err)
+		}
+		if !VerifyASN1(&priv.PublicKey, hashed[:], sig) {
+			t.Fatal("verify failed")
+		}
+		pub, err := RecoverPublicKey(hashed[:], sig, v)
+		if err != nil {
+			t.Fatalf("recover failed %v", err)
+		}
+		if !pub.Equal(&priv.PublicKey) {
+			t.Fatal("recovered public key mismatch")
+		}
+		pub, err = RecoverPublicKey(hashed[:], sig, v^1)
+		if err != nil {
+			t.Fatalf("recover failed %v", err)
+		}
+		if pub.Equal(&priv.PublicKey) {
+			t.Fatal("wrong recovery id should not recover the same public key")
+		}
+
+		keys, err := RecoverPublicKeys(hashed[:], sig)
+		if err != nil {
+			t.Fatalf("recover failed %v", err)
+		}
+		found := false
+		for _, key := range keys {
+			found = found || key.Equal(&priv.PublicKey)
+			if !VerifyASN1(key, hashed[:], sig) {
+				t.Fatal("candidate public key can't verify the signature")
+			}
+		}
+		if !found {
+			t.Fatal("public key not found in candidates")
+		}
+	}
+}
+
+func TestRecoverPublicKeyInvalid(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	hashed := sm3.Sum([]byte("test"))
+	sig, v, _ := SignASN1WithRecoveryID(rand.Reader, priv, hashed[:])
+	if _, err := RecoverPublicKey(hashed[:], sig, 4); err == nil {
+		t.Error("expected error for invalid recovery id")
+	}
+	if _, err := RecoverPublicKey(hashed[:], sig[:len(sig)-1], v); err == nil {
+		t.Error("expected error for invalid signature")
+	}
+	zero, _ := EncodeSignature([]byte{0}, []byte{1})
+	if _, err := RecoverPublicKeys(hashed[:], zero); err == nil {
+		t.Error("expected error for zero r")
+	}
+
+	legacy, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, _, err := SignASN1WithRecoveryID(rand.Reader, &PrivateKey{*legacy}, hashed[:]); err == nil {
+		t.Error("expected error for non sm2 curve")
+	}
+}
@@ -0,0 +1,280 @@
+package sm2
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/ecdh"
+	"github.com/emmansun/gmsm/kdf"
+	"github.com/emmansun/gmsm/sm3"
+	"github.com/emmansun/gmsm/sm4"
+)
+
+// This file contains the SM2 hybrid sealed box, which encrypts arbitrary large
+// payloads to a SM2 public key in streaming mode.
+//
+// The sender generates an ephemeral key pair, computes the shared point with the
+// recipient's public key and derives a SM4 key with KDF(x2 || C1 || PB). The payload
+// is split into chunks of SealedBoxChunkSize bytes, each chunk is sealed with SM4-GCM
+// independently, the nonce is a 11 bytes big endian chunk counter followed by a flag
+// byte which is 1 for the last chunk and 0 for others, so truncation, reordering
+// and duplication of chunks are all detected.
+//
+// The format of the sealed box is:
+//
+//	version (1 byte) || C1 (65 bytes, uncompressed ephemeral public key) || chunk_0 || ... || chunk_n
+//
+// Every chunk but the last one is SealedBoxChunkSize + 16 bytes long, the last chunk
+// may be empty (16 bytes tag only) only if the payload is empty.
+
+// SealedBoxChunkSize is the plaintext size of each chunk in the sealed box.
+const SealedBoxChunkSize = 64 * 1024
+
+const (
+	sealedBoxVersion   = 1
+	sealedBoxKeySize   = 16
+	sealedBoxPointSize = 65
+	sealedBoxTagSize   = 16
+	sealedBoxNonceSize = 12
+)
+
+var errSealedBoxOpen = errors.New("sm2: failed to open sealed box")
+
+// sealedBoxStream is the chunked SM4-GCM stream shared by writer and reader.
+type sealedBoxStream struct {
+	aead    cipher.AEAD
+	nonce   [sealedBoxNonceSize]byte
+	counter uint64
+}
+
+func newSealedBoxStream(shared, c1, pub []byte) (*sealedBoxStream, error) {
+	z := make([]byte, 0, len(shared)+len(c1)+len(pub))
+	z = append(z, shared...)
+	z = append(z, c1...)
+	z = append(z, pub...)
+	key := kdf.Kdf(sm3.New(), z, sealedBoxKeySize)
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealedBoxStream{aead: aead}, nil
+}
+
+// nextNonce returns the nonce of the next chunk and advances the counter.
+func (s *sealedBoxStream) nextNonce(last bool) ([]byte, error) {
+	if s.counter == 1<<64-1 {
+		return nil, errors.New("sm2: sealed box is too large")
+	}
+	binary.BigEndian.PutUint64(s.nonce[3:11], s.counter)
+	if last {
+		s.nonce[11] = 1
+	}
+	s.counter++
+	return s.nonce[:], nil
+}
+
+// SealedBoxWriter encrypts the data written to it and writes the sealed box to the underlying writer.
+// Close must be called to write the last chunk.
+type SealedBoxWriter struct {
+	dst    io.Writer
+	stream *sealedBoxStream
+	buf    []byte // pending plaintext, at most SealedBoxChunkSize bytes
+	out    []byte
+	err    error
+}
+
+// NewSealedBoxWriter writes the header of a sealed box to dst and returns a SealedBoxWriter,
+// the data written to the returned writer will be encrypted to the SM2 public key pub.
+func NewSealedBoxWriter(rand io.Reader, pub *ecdsa.PublicKey, dst io.Writer) (*SealedBoxWriter, error) {
+	if pub == nil || pub.Curve.Params() != P256().Params() {
+		return nil, errors.New("sm2: sealed box only supports sm2 curve")
+	}
+	remote, err := PublicKeyToECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.P256().GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(remote)
+	if err != nil {
+		return nil, err
+	}
+	c1 := ephemeral.PublicKey().Bytes()
+	stream, err := newSealedBoxStream(shared, c1, remote.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, 1+len(c1))
+	header = append(header, sealedBoxVersion)
+	header = append(header, c1...)
+	if _, err := dst.Write(header); err != nil {
+		return nil, err
+	}
+	return &SealedBoxWriter{
+		dst:    dst,
+		stream: stream,
+		buf:    make([]byte, 0, SealedBoxChunkSize),
+		out:    make([]byte, 0, SealedBoxChunkSize+sealedBoxTagSize),
+	}, nil
+}
+
+// Write encrypts p and writes the complete chunks to the underlying writer.
+func (w *SealedBoxWriter) Write(p []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	for len(p) > 0 {
+		// Keep a full chunk pending, it will be the last one if Close comes next.
+		if len(w.buf) == SealedBoxChunkSize {
+			if err := w.flushChunk(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):SealedBoxChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close encrypts and writes the last chunk, it doesn't close the underlying writer.
+func (w *SealedBoxWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flushChunk(true); err != nil {
+		return err
+	}
+	w.err = errors.New("sm2: sealed box writer is closed")
+	return nil
+}
+
+func (w *SealedBoxWriter) flushChunk(last bool) error {
+	nonce, err := w.stream.nextNonce(last)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	w.out = w.stream.aead.Seal(w.out[:0], nonce, w.buf, nil)
+	if _, err := w.dst.Write(w.out); err != nil {
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// SealedBoxReader decrypts a sealed box read from the underlying reader.
+//
+// The data returned by Read has been authenticated chunk by chunk, but the whole
+// payload is authenticated only after Read returns io.EOF, callers should not
+// trust the data until then.
+type SealedBoxReader struct {
+	src    *bufio.Reader
+	stream *sealedBoxStream
+	in     []byte
+	plain  []byte // decrypted but not yet returned data
+	last   bool
+	err    error
+}
+
+// NewSealedBoxReader reads the header of a sealed box from src and returns a SealedBoxReader
+// which decrypts the sealed box with the SM2 private key priv.
+func NewSealedBoxReader(priv *PrivateKey, src io.Reader) (*SealedBoxReader, error) {
+	local, err := priv.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 1+sealedBoxPointSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, errSealedBoxOpen
+	}
+	if header[0] != sealedBoxVersion {
+		return nil, errors.New("sm2: unsupported sealed box version")
+	}
+	c1 := header[1:]
+	ephemeral, err := ecdh.P256().NewPublicKey(c1)
+	if err != nil {
+		return nil, errSealedBoxOpen
+	}
+	shared, err := local.ECDH(ephemeral)
+	if err != nil {
+		return nil, errSealedBoxOpen
+	}
+	stream, err := newSealedBoxStream(shared, c1, local.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &SealedBoxReader{
+		src:    bufio.NewReaderSize(src, SealedBoxChunkSize+sealedBoxTagSize+1),
+		stream: stream,
+		in:     make([]byte, SealedBoxChunkSize+sealedBoxTagSize),
+	}, nil
+}
+
+// Read reads and decrypts the sealed box. It returns an error if any chunk
+// fails the authentication or the sealed box is truncated.
+func (r *SealedBoxReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.last {
+			r.err = io.EOF
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			r.err = err
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *SealedBoxReader) readChunk() error {
+	n, err := io.ReadFull(r.src, r.in)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		// a short chunk must be the last one
+		r.last = true
+	case err != nil:
+		return err
+	default:
+		// a full chunk is the last one only if there is nothing behind it
+		if _, err := r.src.Peek(1); err == io.EOF {
+			r.last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if n < sealedBoxTagSize {
+		return errSealedBoxOpen
+	}
+	nonce, err := r.stream.nextNonce(r.last)
+	if err != nil {
+		return err
+	}
+	plain, err := r.stream.aead.Open(r.in[:0], nonce, r.in[:n], nil)
+	if err != nil {
+		return errSealedBoxOpen
+	}
+	// an empty last chunk is only valid for an empty payload
+	if r.last && len(plain) == 0 && r.stream.counter > 1 {
+		return errSealedBoxOpen
+	}
+	r.plain = plain
+	return nil
+}
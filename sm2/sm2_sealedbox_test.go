@@ -0,0 +1,134 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func sealBox(t *testing.T, priv *PrivateKey, plaintext []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewSealedBoxWriter(rand.Reader, &priv.PublicKey, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in odd sized pieces to exercise the chunk boundaries
+	for p := plaintext; len(p) > 0; {
+		n := 1000
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func openBox(priv *PrivateKey, sealed []byte) ([]byte, error) {
+	r, err := NewSealedBoxReader(priv, bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestSealedBox(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	sizes := []int{0, 1, 1000, SealedBoxChunkSize - 1, SealedBoxChunkSize, SealedBoxChunkSize + 1, 3*SealedBoxChunkSize + 17, 4 * SealedBoxChunkSize}
+	for _, size := range sizes {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		sealed := sealBox(t, priv, plaintext)
+		chunks := (size + SealedBoxChunkSize - 1) / SealedBoxChunkSize
+		if chunks == 0 {
+			chunks = 1
+		}
+		if expected := 1 + sealedBoxPointSize + size + chunks*sealedBoxTagSize; len(sealed) != expected {
+			t.Errorf("size %v: sealed box length %v, expected %v", size, len(sealed), expected)
+		}
+		got, err := openBox(priv, sealed)
+		if err != nil {
+			t.Fatalf("size %v: %v", size, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("size %v: decrypted data mismatch", size)
+		}
+	}
+}
+
+func TestSealedBoxTampered(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	plaintext := make([]byte, 2*SealedBoxChunkSize+100)
+	sealed := sealBox(t, priv, plaintext)
+	chunk := SealedBoxChunkSize + sealedBoxTagSize
+	header := 1 + sealedBoxPointSize
+
+	tests := []struct {
+		name   string
+		sealed []byte
+	}{
+		{"truncated header", sealed[:10]},
+		{"truncated at chunk boundary", sealed[:header+chunk]},
+		{"truncated in chunk", sealed[:header+chunk+10]},
+		{"dropped last chunk", sealed[:header+2*chunk]},
+		{"reordered chunks", func() []byte {
+			b := append([]byte{}, sealed[:header]...)
+			b = append(b, sealed[header+chunk:header+2*chunk]...)
+			b = append(b, sealed[header:header+chunk]...)
+			return append(b, sealed[header+2*chunk:]...)
+		}()},
+		{"appended data", append(append([]byte{}, sealed...), 0)},
+		{"bit flipped", func() []byte {
+			b := append([]byte{}, sealed...)
+			b[header+chunk+5] ^= 1
+			return b
+		}()},
+		{"bad version", append([]byte{2}, sealed[1:]...)},
+	}
+	for _, tt := range tests {
+		if _, err := openBox(priv, tt.sealed); err == nil {
+			t.Errorf("%v: expected error", tt.name)
+		}
+	}
+
+	other, _ := GenerateKey(rand.Reader)
+	if _, err := openBox(other, sealed); err == nil {
+		t.Errorf("opened with wrong private key")
+	}
+}
+
+func TestSealedBoxWriterClosed(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	w, err := NewSealedBoxWriter(rand.Reader, &priv.PublicKey, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("more")); err == nil {
+		t.Errorf("expected error when writing to closed writer")
+	}
+}
+
+func BenchmarkSealedBox(b *testing.B) {
+	priv, _ := GenerateKey(rand.Reader)
+	data := make([]byte, 1<<20)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w, err := NewSealedBoxWriter(rand.Reader, &priv.PublicKey, io.Discard)
+		if err != nil {
+			b.Fatal(err)
+		}
+		w.Write(data)
+		w.Close()
+	}
+}
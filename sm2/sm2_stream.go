@@ -0,0 +1,104 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// This file contains streaming SM2 signer/verifier, which computes e = SM3(ZA || M)
+// incrementally so that large messages needn't be loaded into memory.
+
+// StreamSigner signs a message which is written into it piece by piece.
+// It behaves like a hash.Hash: write the message with Write, then call Sign.
+//
+// It is compliance with GB/T 32918.2-2016, the result signature is the same as
+// the one generated by SignWithSM2 or Sign with DefaultSM2SignerOpts.
+type StreamSigner struct {
+	priv *PrivateKey
+	za   []byte
+	md   hash.Hash
+}
+
+// NewStreamSigner creates a StreamSigner with the private key and uid.
+// If the uid is empty, the default one will be used.
+func NewStreamSigner(priv *PrivateKey, uid []byte) (*StreamSigner, error) {
+	if priv == nil {
+		return nil, errors.New("sm2: nil private key")
+	}
+	za, err := calculateStreamZA(&priv.PublicKey, uid)
+	if err != nil {
+		return nil, err
+	}
+	s := &StreamSigner{priv: priv, za: za, md: sm3.New()}
+	s.Reset()
+	return s, nil
+}
+
+// Write adds more data to the running message digest.
+// It never returns an error.
+func (s *StreamSigner) Write(p []byte) (int, error) {
+	return s.md.Write(p)
+}
+
+// Reset discards the written message, the signer can be used to sign a new message.
+func (s *StreamSigner) Reset() {
+	s.md.Reset()
+	s.md.Write(s.za)
+}
+
+// Sign signs the written message and returns the ASN.1 encoded signature.
+// It does not change the underlying state, so more data can still be written.
+func (s *StreamSigner) Sign(rand io.Reader) ([]byte, error) {
+	return SignASN1(rand, s.priv, s.md.Sum(nil), nil)
+}
+
+// StreamVerifier verifies a signature of a message which is written into it piece by piece.
+type StreamVerifier struct {
+	pub *ecdsa.PublicKey
+	za  []byte
+	md  hash.Hash
+}
+
+// NewStreamVerifier creates a StreamVerifier with the public key and uid.
+// If the uid is empty, the default one will be used.
+func NewStreamVerifier(pub *ecdsa.PublicKey, uid []byte) (*StreamVerifier, error) {
+	if pub == nil {
+		return nil, errors.New("sm2: nil public key")
+	}
+	za, err := calculateStreamZA(pub, uid)
+	if err != nil {
+		return nil, err
+	}
+	v := &StreamVerifier{pub: pub, za: za, md: sm3.New()}
+	v.Reset()
+	return v, nil
+}
+
+// Write adds more data to the running message digest.
+// It never returns an error.
+func (v *StreamVerifier) Write(p []byte) (int, error) {
+	return v.md.Write(p)
+}
+
+// Reset discards the written message, the verifier can be used to verify a new message.
+func (v *StreamVerifier) Reset() {
+	v.md.Reset()
+	v.md.Write(v.za)
+}
+
+// Verify reports whether sig is a valid ASN.1 encoded signature of the written message.
+// It does not change the underlying state.
+func (v *StreamVerifier) Verify(sig []byte) bool {
+	return VerifyASN1(v.pub, v.md.Sum(nil), sig)
+}
+
+func calculateStreamZA(pub *ecdsa.PublicKey, uid []byte) ([]byte, error) {
+	if len(uid) == 0 {
+		uid = defaultUID
+	}
+	return CalculateZA(pub, uid)
+}
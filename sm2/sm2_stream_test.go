@@ -0,0 +1,85 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestStreamSignVerify(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	tests := []struct {
+		name string
+		uid  []byte
+		size int
+	}{
+		{"empty message", nil, 0},
+		{"default uid", nil, 1000},
+		{"custom uid", []byte("Alice@YAHOO.COM"), 1000},
+		{"large message", nil, 1 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := make([]byte, tt.size)
+			io.ReadFull(rand.Reader, msg)
+
+			signer, err := NewStreamSigner(priv, tt.uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = io.Copy(signer, bytes.NewReader(msg)); err != nil {
+				t.Fatal(err)
+			}
+			sig, err := signer.Sign(rand.Reader)
+			if err != nil {
+				t.Fatalf("sign failed %v", err)
+			}
+			if !VerifyASN1WithSM2(&priv.PublicKey, tt.uid, msg, sig) {
+				t.Fatal("VerifyASN1WithSM2 failed")
+			}
+
+			sig, err = priv.SignWithSM2(rand.Reader, tt.uid, msg)
+			if err != nil {
+				t.Fatalf("sign failed %v", err)
+			}
+			verifier, err := NewStreamVerifier(&priv.PublicKey, tt.uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// write in small chunks
+			for i := 0; i < len(msg); i += 7 {
+				end := i + 7
+				if end > len(msg) {
+					end = len(msg)
+				}
+				verifier.Write(msg[i:end])
+			}
+			if !verifier.Verify(sig) {
+				t.Fatal("stream verify failed")
+			}
+			verifier.Write([]byte{0})
+			if verifier.Verify(sig) {
+				t.Fatal("stream verify always works")
+			}
+			verifier.Reset()
+			verifier.Write(msg)
+			if !verifier.Verify(sig) {
+				t.Fatal("stream verify failed after reset")
+			}
+		})
+	}
+}
+
+func TestStreamSignerInvalidArguments(t *testing.T) {
+	if _, err := NewStreamSigner(nil, nil); err == nil {
+		t.Error("expected error for nil private key")
+	}
+	if _, err := NewStreamVerifier(nil, nil); err == nil {
+		t.Error("expected error for nil public key")
+	}
+	priv, _ := GenerateKey(rand.Reader)
+	if _, err := NewStreamSigner(priv, make([]byte, 0x2000)); err == nil {
+		t.Error("expected error for too long uid")
+	}
+}
@@ -746,3 +746,28 @@ func BenchmarkMoreThan32_P256(b *testing.B) {
 func BenchmarkMoreThan32_SM2(b *testing.B) {
 	benchmarkEncrypt(b, P256(), "encryption standard encryption standard encryption standard encryption standard encryption standard encryption standard encryption standard")
 }
+
+func TestParseCiphertext(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	plaintext := []byte("parse ciphertext")
+	plain, _ := Encrypt(rand.Reader, &priv.PublicKey, plaintext, NewPlainEncrypterOpts(MarshalUncompressed, C1C2C3))
+	asn1Ciphertext, _ := PlainCiphertext2ASN1(plain, C1C2C3)
+
+	c1, c2, c3, err := ParseCiphertext(plain, NewPlainDecrypterOpts(C1C2C3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c1, plain[:65]) || !bytes.Equal(c2, plain[65:65+len(plaintext)]) || !bytes.Equal(c3, plain[65+len(plaintext):]) {
+		t.Fatal("plain ciphertext parsed incorrectly")
+	}
+	c1a, c2a, c3a, err := ParseCiphertext(asn1Ciphertext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c1, c1a) || !bytes.Equal(c2, c2a) || !bytes.Equal(c3, c3a) {
+		t.Fatal("asn1 ciphertext parsed incorrectly")
+	}
+	if _, _, _, err = ParseCiphertext(plain[:65], nil); err == nil {
+		t.Fatal("expected error for short ciphertext")
+	}
+}
@@ -0,0 +1,106 @@
+package twoparty
+
+import (
+	_subtle "crypto/subtle"
+	"errors"
+
+	_sm2ec "github.com/emmansun/gmsm/internal/sm2ec"
+	"github.com/emmansun/gmsm/internal/subtle"
+	"github.com/emmansun/gmsm/kdf"
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// This file contains SM2 two-party collaborative decryption.
+//
+//	Client: parses ciphertext C1 || C3 || C2, T1 = [d1⁻¹]C1, sends T1 to server.
+//	Server: T2 = [d2⁻¹]T1, sends T2 to client.
+//	Client: (x2, y2) = T2 - C1 = [d]C1, then completes the decryption as GB/T 32918.4-2016.
+//
+// The server never sees C2/C3, so it can't learn the plaintext.
+
+// ErrDecryption represents a failure to decrypt a message.
+// It is deliberately vague to avoid adaptive attacks.
+var ErrDecryption = errors.New("twoparty: decryption error")
+
+// ClientDecryptState holds the client's internal state between the two steps of decryption.
+type ClientDecryptState struct {
+	c1     *_sm2ec.SM2P256Point
+	c2, c3 []byte
+}
+
+// BeginDecrypt starts the decryption of ciphertext, which is the output of sm2.Encrypt or
+// sm2.EncryptASN1. The opts argument specifies the splicing order of plain encoding ciphertext,
+// ASN.1 encoding is detected automatically.
+//
+// It returns the client state and T1 = [d1⁻¹]C1 which should be sent to the server.
+func (ck *ClientKey) BeginDecrypt(ciphertext []byte, opts *sm2.DecrypterOpts) (*ClientDecryptState, []byte, error) {
+	c1, c2, c3, err := sm2.ParseCiphertext(ciphertext, opts)
+	if err != nil {
+		return nil, nil, ErrDecryption
+	}
+	C1, err := parsePoint(c1)
+	if err != nil {
+		return nil, nil, ErrDecryption
+	}
+	d1Inv, err := _sm2ec.P256OrdInverse(ck.d1.Bytes(order()))
+	if err != nil {
+		return nil, nil, err
+	}
+	T1, err := _sm2ec.NewSM2P256Point().ScalarMult(C1, d1Inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ClientDecryptState{c1: C1, c2: c2, c3: c3}, T1.Bytes(), nil
+}
+
+// Decrypt is the server's decryption step, returns T2 = [d2⁻¹]T1.
+func (sk *ServerKey) Decrypt(t1 []byte) ([]byte, error) {
+	T1, err := parsePoint(t1)
+	if err != nil {
+		return nil, err
+	}
+	d2Inv, err := _sm2ec.P256OrdInverse(sk.d2.Bytes(order()))
+	if err != nil {
+		return nil, err
+	}
+	T2, err := _sm2ec.NewSM2P256Point().ScalarMult(T1, d2Inv)
+	if err != nil {
+		return nil, err
+	}
+	return T2.Bytes(), nil
+}
+
+// Finish completes the decryption with the server's T2, returns the plaintext.
+func (st *ClientDecryptState) Finish(t2 []byte) ([]byte, error) {
+	if st.c1 == nil {
+		return nil, errors.New("twoparty: decrypt state has been used")
+	}
+	C1 := st.c1
+	st.c1 = nil
+
+	T2, err := parsePoint(t2)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	// [d]C1 = T2 - C1
+	C2Bytes := T2.Add(T2, negate(C1)).Bytes()
+	if len(C2Bytes) == 1 {
+		return nil, ErrDecryption
+	}
+	C2Bytes = C2Bytes[1:]
+	msg := kdf.Kdf(sm3.New(), C2Bytes, len(st.c2))
+	if subtle.ConstantTimeAllZero(msg) {
+		return nil, ErrDecryption
+	}
+	subtle.XORBytes(msg, st.c2, msg)
+
+	md := sm3.New()
+	md.Write(C2Bytes[:len(C2Bytes)/2])
+	md.Write(msg)
+	md.Write(C2Bytes[len(C2Bytes)/2:])
+	if _subtle.ConstantTimeCompare(md.Sum(nil), st.c3) != 1 {
+		return nil, ErrDecryption
+	}
+	return msg, nil
+}
@@ -0,0 +1,81 @@
+package twoparty
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestDecrypt(t *testing.T) {
+	client, server := generateKeys(t)
+	plaintext := []byte("two party collaborative decryption")
+	tests := []struct {
+		name    string
+		encOpts *sm2.EncrypterOpts
+		decOpts *sm2.DecrypterOpts
+	}{
+		{"default", nil, nil},
+		{"asn1", sm2.ASN1EncrypterOpts, sm2.ASN1DecrypterOpts},
+		{"C1C3C2 compressed", sm2.NewPlainEncrypterOpts(sm2.MarshalCompressed, sm2.C1C3C2), sm2.NewPlainDecrypterOpts(sm2.C1C3C2)},
+		{"C1C2C3", sm2.NewPlainEncrypterOpts(sm2.MarshalUncompressed, sm2.C1C2C3), sm2.NewPlainDecrypterOpts(sm2.C1C2C3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := sm2.Encrypt(rand.Reader, client.PublicKey, plaintext, tt.encOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, t1, err := client.BeginDecrypt(ciphertext, tt.decOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t2, err := server.Decrypt(t1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := state.Finish(t2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("got %x, want %x", got, plaintext)
+			}
+			if _, err = state.Finish(t2); err == nil {
+				t.Fatal("decrypt state should not be reused")
+			}
+		})
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	client, server := generateKeys(t)
+	ciphertext, _ := sm2.Encrypt(rand.Reader, client.PublicKey, []byte("plaintext"), nil)
+	if _, _, err := client.BeginDecrypt(ciphertext[:60], nil); err == nil {
+		t.Error("expected error for short ciphertext")
+	}
+	if _, err := server.Decrypt([]byte{0}); err == nil {
+		t.Error("expected error for infinity T1")
+	}
+
+	// tampered C2
+	ciphertext[len(ciphertext)-1] ^= 1
+	state, t1, err := client.BeginDecrypt(ciphertext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, _ := server.Decrypt(t1)
+	if _, err = state.Finish(t2); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+
+	// mismatched server key share
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, other := generateKeys(t)
+	state, t1, _ = client.BeginDecrypt(ciphertext, nil)
+	t2, _ = other.Decrypt(t1)
+	if _, err = state.Finish(t2); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+}
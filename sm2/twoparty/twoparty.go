@@ -0,0 +1,399 @@
+// Package twoparty implements SM2 two-party collaborative signature and decryption,
+// the private key d is split between a client (e.g. a mobile device) and a server,
+// neither of them holds the full private key.
+//
+// Key generation:
+//
+//	Client: random d1 in [1, n-1], P1 = [d1⁻¹]G, sends P1 to server.
+//	Server: random d2 in [1, n-1], P = [d2⁻¹]P1 - G, P is the SM2 public key.
+//
+// So the full private key d = (d1*d2)⁻¹ - 1, and (1 + d)⁻¹ = d1*d2.
+//
+// Signature:
+//
+//	Client: e = SM3(ZA || M), random k1, Q1 = [k1]G, sends (e, Q1) to server.
+//	Server: random k2, k3, Q2 = [k2]G, (x1, y1) = [k3]Q1 + Q2, r = (e + x1) mod n,
+//	        s2 = d2*k3 mod n, s3 = d2*(r + k2) mod n, sends (r, s2, s3) to client.
+//	Client: s = (d1*k1)*s2 + d1*s3 - r mod n, the signature is (r, s).
+//
+// The output is a standard SM2 signature which can be verified by sm2.VerifyASN1.
+package twoparty
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	_sm2ec "github.com/emmansun/gmsm/internal/sm2ec"
+	"github.com/emmansun/gmsm/sm2"
+)
+
+const (
+	scalarSize = 32
+	pointSize  = 1 + 2*scalarSize
+)
+
+var (
+	paramsOnce sync.Once
+	n          *bigmod.Modulus
+	nMinus1    []byte               // n - 1, used to negate a point
+	negG       *_sm2ec.SM2P256Point // -G
+)
+
+func order() *bigmod.Modulus {
+	paramsOnce.Do(func() {
+		params := sm2.P256().Params()
+		n, _ = bigmod.NewModulusFromBig(params.N)
+		nMinus1 = new(big.Int).Sub(params.N, big.NewInt(1)).FillBytes(make([]byte, scalarSize))
+		negG, _ = _sm2ec.NewSM2P256Point().ScalarBaseMult(nMinus1)
+	})
+	return n
+}
+
+// negate sets p = -p and returns p.
+func negate(p *_sm2ec.SM2P256Point) *_sm2ec.SM2P256Point {
+	order()
+	p.ScalarMult(p, nMinus1)
+	return p
+}
+
+// ClientKey is the client's share of a two-party SM2 private key.
+type ClientKey struct {
+	d1        *bigmod.Nat
+	PublicKey *ecdsa.PublicKey // the SM2 public key, available after key generation completes.
+}
+
+// ServerKey is the server's share of a two-party SM2 private key.
+type ServerKey struct {
+	d2        *bigmod.Nat
+	PublicKey *ecdsa.PublicKey // the SM2 public key
+}
+
+// GenerateClientKey generates the client's key share d1 and returns P1 = [d1⁻¹]G
+// (uncompressed point encoding) which should be sent to the server.
+//
+// The PublicKey of the returned key is nil, please call SetPublicKey after
+// the server returned the SM2 public key.
+func GenerateClientKey(rand io.Reader) (*ClientKey, []byte, error) {
+	d1, err := randomScalar(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	d1Inv, err := _sm2ec.P256OrdInverse(d1.Bytes(order()))
+	if err != nil {
+		return nil, nil, err
+	}
+	P1, err := _sm2ec.NewSM2P256Point().ScalarBaseMult(d1Inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ClientKey{d1: d1}, P1.Bytes(), nil
+}
+
+// NewClientKey creates a ClientKey from the key share d1 and the SM2 public key.
+func NewClientKey(d1 []byte, pub *ecdsa.PublicKey) (*ClientKey, error) {
+	d, err := scalarFromBytes(d1)
+	if err != nil {
+		return nil, err
+	}
+	key := &ClientKey{d1: d}
+	if err = key.SetPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// SetPublicKey sets the SM2 public key which is generated by the server.
+func (ck *ClientKey) SetPublicKey(pub *ecdsa.PublicKey) error {
+	if !sm2.IsSM2PublicKey(pub) || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return errors.New("twoparty: invalid sm2 public key")
+	}
+	ck.PublicKey = pub
+	return nil
+}
+
+// Bytes returns the key share d1 as a 32 bytes big-endian value.
+func (ck *ClientKey) Bytes() []byte {
+	return ck.d1.Bytes(order())
+}
+
+// GenerateServerKey generates the server's key share d2 with the client's P1,
+// and computes the SM2 public key P = [d2⁻¹]P1 - G, which should be returned to the client.
+func GenerateServerKey(rand io.Reader, p1 []byte) (*ServerKey, error) {
+	P1, err := parsePoint(p1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		d2, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		d2Inv, err := _sm2ec.P256OrdInverse(d2.Bytes(order()))
+		if err != nil {
+			return nil, err
+		}
+		P, err := _sm2ec.NewSM2P256Point().ScalarMult(P1, d2Inv)
+		if err != nil {
+			return nil, err
+		}
+		P.Add(P, negG)
+		pub, err := pointToPublicKey(P)
+		if err != nil {
+			// d1*d2 == 1, the private key is zero, retry.
+			continue
+		}
+		return &ServerKey{d2: d2, PublicKey: pub}, nil
+	}
+}
+
+// NewServerKey creates a ServerKey from the key share d2 and the SM2 public key.
+func NewServerKey(d2 []byte, pub *ecdsa.PublicKey) (*ServerKey, error) {
+	d, err := scalarFromBytes(d2)
+	if err != nil {
+		return nil, err
+	}
+	if !sm2.IsSM2PublicKey(pub) || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("twoparty: invalid sm2 public key")
+	}
+	return &ServerKey{d2: d, PublicKey: pub}, nil
+}
+
+// Bytes returns the key share d2 as a 32 bytes big-endian value.
+func (sk *ServerKey) Bytes() []byte {
+	return sk.d2.Bytes(order())
+}
+
+// SignRequest is the first message of the signing protocol, sent by the client.
+type SignRequest struct {
+	digest []byte // e
+	q1     []byte // Q1 = [k1]G
+}
+
+// MarshalBinary encodes the request as e || Q1.
+func (req *SignRequest) MarshalBinary() ([]byte, error) {
+	return append(append(make([]byte, 0, scalarSize+pointSize), req.digest...), req.q1...), nil
+}
+
+// UnmarshalBinary decodes the request which was encoded by MarshalBinary.
+func (req *SignRequest) UnmarshalBinary(data []byte) error {
+	if len(data) != scalarSize+pointSize {
+		return errors.New("twoparty: invalid sign request")
+	}
+	req.digest = append([]byte{}, data[:scalarSize]...)
+	req.q1 = append([]byte{}, data[scalarSize:]...)
+	return nil
+}
+
+// SignResponse is the second message of the signing protocol, sent by the server.
+type SignResponse struct {
+	r, s2, s3 []byte
+}
+
+// MarshalBinary encodes the response as r || s2 || s3.
+func (resp *SignResponse) MarshalBinary() ([]byte, error) {
+	out := make([]byte, 0, 3*scalarSize)
+	out = append(out, resp.r...)
+	out = append(out, resp.s2...)
+	return append(out, resp.s3...), nil
+}
+
+// UnmarshalBinary decodes the response which was encoded by MarshalBinary.
+func (resp *SignResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != 3*scalarSize {
+		return errors.New("twoparty: invalid sign response")
+	}
+	resp.r = append([]byte{}, data[:scalarSize]...)
+	resp.s2 = append([]byte{}, data[scalarSize:2*scalarSize]...)
+	resp.s3 = append([]byte{}, data[2*scalarSize:]...)
+	return nil
+}
+
+// ClientSignState holds the client's internal state between the two rounds of signing.
+type ClientSignState struct {
+	key    *ClientKey
+	digest []byte
+	k1     *bigmod.Nat
+}
+
+// BeginSign starts the signing of digest, which should be the result of SM3(ZA || M),
+// returns the client state and the request which should be sent to the server.
+func (ck *ClientKey) BeginSign(rand io.Reader, digest []byte) (*ClientSignState, *SignRequest, error) {
+	if ck.PublicKey == nil {
+		return nil, nil, errors.New("twoparty: no public key")
+	}
+	if len(digest) != scalarSize {
+		return nil, nil, errors.New("twoparty: invalid digest length")
+	}
+	k1, err := randomScalar(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	Q1, err := _sm2ec.NewSM2P256Point().ScalarBaseMult(k1.Bytes(order()))
+	if err != nil {
+		return nil, nil, err
+	}
+	digest = append([]byte{}, digest...)
+	return &ClientSignState{key: ck, digest: digest, k1: k1}, &SignRequest{digest: digest, q1: Q1.Bytes()}, nil
+}
+
+// BeginSignWithSM2 is similar to BeginSign, but calculates the digest SM3(ZA || M)
+// from the uid and raw message. If the uid is empty, the default one will be used.
+func (ck *ClientKey) BeginSignWithSM2(rand io.Reader, uid, msg []byte) (*ClientSignState, *SignRequest, error) {
+	if ck.PublicKey == nil {
+		return nil, nil, errors.New("twoparty: no public key")
+	}
+	digest, err := sm2.CalculateSM2Hash(ck.PublicKey, msg, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ck.BeginSign(rand, digest)
+}
+
+// Finish completes the signing with the server's response, returns the ASN.1 encoded
+// SM2 signature. The signature is verified with the public key before returning.
+func (st *ClientSignState) Finish(resp *SignResponse) ([]byte, error) {
+	if st.k1 == nil {
+		return nil, errors.New("twoparty: sign state has been used")
+	}
+	k1 := st.k1
+	st.k1 = nil
+
+	N := order()
+	r, err := scalarFromBytes(resp.r)
+	if err != nil {
+		return nil, err
+	}
+	s2, err := scalarFromBytes(resp.s2)
+	if err != nil {
+		return nil, err
+	}
+	s3, err := scalarFromBytes(resp.s3)
+	if err != nil {
+		return nil, err
+	}
+	// s = (d1*k1)*s2 + d1*s3 - r mod n
+	s := bigmod.NewNat().Set(st.key.d1)
+	s.Mul(k1, N)
+	s.Mul(s2, N)
+	s3.Mul(st.key.d1, N)
+	s.Add(s3, N)
+	s.Sub(r, N)
+	t := bigmod.NewNat().Set(s)
+	t.Add(r, N)
+	if s.IsZero() == 1 || t.IsZero() == 1 {
+		return nil, errors.New("twoparty: invalid sign response")
+	}
+
+	sig, err := sm2.EncodeSignature(r.Bytes(N), s.Bytes(N))
+	if err != nil {
+		return nil, err
+	}
+	if !sm2.VerifyASN1(st.key.PublicKey, st.digest, sig) {
+		return nil, errors.New("twoparty: invalid sign response")
+	}
+	return sig, nil
+}
+
+// Sign is the server's signing step, it computes the partial signature with the
+// client's request.
+func (sk *ServerKey) Sign(rand io.Reader, req *SignRequest) (*SignResponse, error) {
+	if len(req.digest) != scalarSize {
+		return nil, errors.New("twoparty: invalid sign request")
+	}
+	Q1, err := parsePoint(req.q1)
+	if err != nil {
+		return nil, err
+	}
+	N := order()
+	e, err := bigmod.NewNat().SetOverflowingBytes(req.digest, N)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		k2, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		k3, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		Q2, err := _sm2ec.NewSM2P256Point().ScalarBaseMult(k2.Bytes(N))
+		if err != nil {
+			return nil, err
+		}
+		R, err := _sm2ec.NewSM2P256Point().ScalarMult(Q1, k3.Bytes(N))
+		if err != nil {
+			return nil, err
+		}
+		x1, err := R.Add(R, Q2).BytesX()
+		if err != nil {
+			continue
+		}
+		r, err := bigmod.NewNat().SetOverflowingBytes(x1, N)
+		if err != nil {
+			return nil, err
+		}
+		r.Add(e, N)
+		if r.IsZero() == 1 {
+			continue
+		}
+		// s2 = d2*k3, s3 = d2*(r + k2)
+		s2 := k3.Mul(sk.d2, N)
+		s3 := k2.Add(r, N)
+		s3.Mul(sk.d2, N)
+		return &SignResponse{r: r.Bytes(N), s2: s2.Bytes(N), s3: s3.Bytes(N)}, nil
+	}
+}
+
+// randomScalar returns a random scalar in [1, n-1].
+func randomScalar(rand io.Reader) (*bigmod.Nat, error) {
+	N := order()
+	k := bigmod.NewNat()
+	b := make([]byte, N.Size())
+	for {
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		if _, err := k.SetBytes(b, N); err == nil && k.IsZero() == 0 {
+			return k, nil
+		}
+	}
+}
+
+// scalarFromBytes returns the scalar in [1, n-1] from its big-endian encoding.
+func scalarFromBytes(b []byte) (*bigmod.Nat, error) {
+	k, err := bigmod.NewNat().SetBytes(b, order())
+	if err != nil || k.IsZero() == 1 {
+		return nil, errors.New("twoparty: invalid scalar")
+	}
+	return k, nil
+}
+
+// parsePoint parses the encoded point, the point at infinity is rejected.
+func parsePoint(b []byte) (*_sm2ec.SM2P256Point, error) {
+	if len(b) == 0 || b[0] == 0 {
+		return nil, errors.New("twoparty: invalid point")
+	}
+	p, err := _sm2ec.NewSM2P256Point().SetBytes(b)
+	if err != nil {
+		return nil, errors.New("twoparty: invalid point")
+	}
+	return p, nil
+}
+
+func pointToPublicKey(p *_sm2ec.SM2P256Point) (*ecdsa.PublicKey, error) {
+	out := p.Bytes()
+	if len(out) == 1 {
+		return nil, errors.New("twoparty: public key point is the infinity")
+	}
+	return &ecdsa.PublicKey{
+		Curve: sm2.P256(),
+		X:     new(big.Int).SetBytes(out[1 : 1+scalarSize]),
+		Y:     new(big.Int).SetBytes(out[1+scalarSize:]),
+	}, nil
+}
@@ -0,0 +1,135 @@
+package twoparty
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm3"
+)
+
+func generateKeys(t *testing.T) (*ClientKey, *ServerKey) {
+	client, p1, err := GenerateClientKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := GenerateServerKey(rand.Reader, p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.SetPublicKey(server.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestSign(t *testing.T) {
+	client, server := generateKeys(t)
+	for i := 0; i < 10; i++ {
+		digest := sm3.Sum([]byte{byte(i)})
+		state, req, err := client.BeginSign(rand.Reader, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		// transmit the request
+		reqBytes, _ := req.MarshalBinary()
+		req = new(SignRequest)
+		if err = req.UnmarshalBinary(reqBytes); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := server.Sign(rand.Reader, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// transmit the response
+		respBytes, _ := resp.MarshalBinary()
+		resp = new(SignResponse)
+		if err = resp.UnmarshalBinary(respBytes); err != nil {
+			t.Fatal(err)
+		}
+		sig, err := state.Finish(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sm2.VerifyASN1(client.PublicKey, digest[:], sig) {
+			t.Fatal("sm2.VerifyASN1 failed")
+		}
+		if _, err = state.Finish(resp); err == nil {
+			t.Fatal("sign state should not be reused")
+		}
+	}
+}
+
+func TestSignWithSM2(t *testing.T) {
+	client, server := generateKeys(t)
+	msg := []byte("two party collaborative signature")
+	uid := []byte("Alice")
+	state, req, err := client.BeginSignWithSM2(rand.Reader, uid, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Sign(rand.Reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := state.Finish(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sm2.VerifyASN1WithSM2(server.PublicKey, uid, msg, sig) {
+		t.Fatal("sm2.VerifyASN1WithSM2 failed")
+	}
+}
+
+func TestRestoreKeys(t *testing.T) {
+	client, server := generateKeys(t)
+	client, err := NewClientKey(client.Bytes(), client.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err = NewServerKey(server.Bytes(), server.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sm3.Sum([]byte("restore"))
+	state, req, _ := client.BeginSign(rand.Reader, digest[:])
+	resp, _ := server.Sign(rand.Reader, req)
+	sig, err := state.Finish(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sm2.VerifyASN1(client.PublicKey, digest[:], sig) {
+		t.Fatal("sm2.VerifyASN1 failed")
+	}
+	if _, err = NewClientKey(make([]byte, 32), client.PublicKey); err == nil {
+		t.Fatal("expected error for zero key share")
+	}
+}
+
+func TestInvalidMessages(t *testing.T) {
+	client, server := generateKeys(t)
+	if _, err := GenerateServerKey(rand.Reader, []byte{0}); err == nil {
+		t.Error("expected error for infinity P1")
+	}
+	if err := new(SignRequest).UnmarshalBinary(make([]byte, 10)); err == nil {
+		t.Error("expected error for invalid request length")
+	}
+	if err := new(SignResponse).UnmarshalBinary(make([]byte, 10)); err == nil {
+		t.Error("expected error for invalid response length")
+	}
+	digest := sm3.Sum([]byte("invalid"))
+	state, req, _ := client.BeginSign(rand.Reader, digest[:])
+	req.q1[len(req.q1)-1] ^= 1
+	if _, err := server.Sign(rand.Reader, req); err == nil {
+		t.Error("expected error for invalid Q1")
+	}
+	req.q1[len(req.q1)-1] ^= 1
+	resp, _ := server.Sign(rand.Reader, req)
+	resp.s3[0] ^= 1
+	if _, err := state.Finish(resp); err == nil {
+		t.Error("expected error for tampered response")
+	}
+	if _, _, err := new(ClientKey).BeginSign(rand.Reader, digest[:]); err == nil {
+		t.Error("expected error for client key without public key")
+	}
+}
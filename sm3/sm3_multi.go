@@ -0,0 +1,131 @@
+package sm3
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// maxLanes is the max number of messages hashed in parallel by blockMulti.
+const maxLanes = 8
+
+var iv = [8]uint32{init0, init1, init2, init3, init4, init5, init6, init7}
+
+// multiState is the state of maxLanes digests, multiState[i][lane] is the
+// i-th word of the digest of the lane, so that each word can be loaded into
+// one vector register.
+type multiState [8][maxLanes]uint32
+
+// SumMulti returns the SM3 checksums of the messages, the i-th checksum is
+// the checksum of msgs[i].
+//
+// Where supported (AVX2 on amd64, NEON on arm64 without the SM3 instructions),
+// the messages are hashed in parallel lanes, which is much faster than calling
+// Sum for each of many small messages, such as the leaves of a Merkle tree.
+// Otherwise, it's equivalent to calling Sum for each message.
+func SumMulti(msgs [][]byte) [][Size]byte {
+	digests := make([][Size]byte, len(msgs))
+	lanes := multiLanes()
+	if lanes == 1 || len(msgs) == 1 {
+		for i, msg := range msgs {
+			digests[i] = Sum(msg)
+		}
+		return digests
+	}
+	// Group the messages with similar lengths, so that most blocks of a batch
+	// are hashed in parallel.
+	order := make([]int, len(msgs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(msgs[order[i]]) < len(msgs[order[j]])
+	})
+	for len(order) > 0 {
+		n := lanes
+		if n > len(order) {
+			n = len(order)
+		}
+		sumBatch(msgs, order[:n], digests)
+		order = order[n:]
+	}
+	return digests
+}
+
+// sumBatch hashes the messages msgs[batch[i]] in parallel lanes.
+func sumBatch(msgs [][]byte, batch []int, digests [][Size]byte) {
+	var (
+		h     multiState
+		p     [maxLanes][]byte
+		tails [maxLanes][2 * chunk]byte
+		tail  [maxLanes][]byte
+	)
+	bodyBlocks, tailBlocks := -1, 2
+	for lane, i := range batch {
+		msg := msgs[i]
+		for j := range h {
+			h[j][lane] = iv[j]
+		}
+		p[lane] = msg
+		tail[lane] = padTail(&tails[lane], msg)
+		if n := len(msg) / chunk; bodyBlocks < 0 || n < bodyBlocks {
+			bodyBlocks = n
+		}
+		if n := len(tail[lane]) / chunk; n < tailBlocks {
+			tailBlocks = n
+		}
+	}
+	// The unused lanes repeat the first lane, their results are ignored.
+	for lane := len(batch); lane < maxLanes; lane++ {
+		p[lane], tail[lane] = p[0], tail[0]
+	}
+
+	if bodyBlocks > 0 {
+		blockMulti(&h, &p, bodyBlocks)
+	}
+	for lane, i := range batch {
+		if rest := msgs[i][bodyBlocks*chunk : len(msgs[i])/chunk*chunk]; len(rest) > 0 {
+			h.blockLane(lane, rest)
+		}
+	}
+	blockMulti(&h, &tail, tailBlocks)
+	for lane, i := range batch {
+		if rest := tail[lane][tailBlocks*chunk:]; len(rest) > 0 {
+			h.blockLane(lane, rest)
+		}
+		for j := range h {
+			binary.BigEndian.PutUint32(digests[i][j*4:], h[j][lane])
+		}
+	}
+}
+
+// padTail copies the last partial block of msg with the padding into buf,
+// it returns the one or two padded blocks.
+func padTail(buf *[2 * chunk]byte, msg []byte) []byte {
+	n := copy(buf[:], msg[len(msg)/chunk*chunk:])
+	buf[n] = 0x80
+	size := chunk
+	if n+1+8 > chunk {
+		size = 2 * chunk
+	}
+	binary.BigEndian.PutUint64(buf[size-8:], uint64(len(msg))<<3)
+	return buf[:size]
+}
+
+// blockLane hashes the blocks p with the digest of the lane.
+func (h *multiState) blockLane(lane int, p []byte) {
+	var d digest
+	for j := range h {
+		d.h[j] = h[j][lane]
+	}
+	block(&d, p)
+	for j := range h {
+		h[j][lane] = d.h[j]
+	}
+}
+
+// blockMultiGeneric hashes blocks blocks of each lane one lane after another.
+func blockMultiGeneric(h *multiState, p *[maxLanes][]byte, blocks int) {
+	for lane := range p {
+		h.blockLane(lane, p[lane][:blocks*chunk])
+	}
+}
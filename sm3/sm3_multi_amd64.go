@@ -0,0 +1,24 @@
+//go:build amd64 && !purego
+
+package sm3
+
+// blockMultiAVX2 hashes blocks blocks of each of the 8 lanes in parallel,
+// the state is updated in place.
+//
+//go:noescape
+func blockMultiAVX2(h *multiState, p *[maxLanes][]byte, blocks int)
+
+func multiLanes() int {
+	if useAVX2 {
+		return maxLanes
+	}
+	return 1
+}
+
+func blockMulti(h *multiState, p *[maxLanes][]byte, blocks int) {
+	if useAVX2 {
+		blockMultiAVX2(h, p, blocks)
+	} else {
+		blockMultiGeneric(h, p, blocks)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build arm64 && !purego
+
+package sm3
+
+// neonLanes is the number of messages hashed in parallel by blockMultiNEON.
+const neonLanes = 4
+
+// blockMultiNEON hashes blocks blocks of each of the first 4 lanes in parallel,
+// the state is updated in place, the other lanes are untouched.
+//
+//go:noescape
+func blockMultiNEON(h *multiState, p *[maxLanes][]byte, blocks int)
+
+// multiLanes returns 1 if the SM3 instructions are available, hashing one message
+// with them is faster than hashing 4 messages in parallel with NEON.
+func multiLanes() int {
+	if useSM3NI {
+		return 1
+	}
+	return neonLanes
+}
+
+func blockMulti(h *multiState, p *[maxLanes][]byte, blocks int) {
+	if useSM3NI {
+		blockMultiGeneric(h, p, blocks)
+	} else {
+		blockMultiNEON(h, p, blocks)
+	}
+}
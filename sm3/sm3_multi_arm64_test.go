@@ -0,0 +1,44 @@
+//go:build arm64 && !purego
+
+package sm3
+
+import (
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+// TestBlockMultiNEON checks the NEON kernel against blockMultiGeneric, even if the
+// SM3 instructions are available and SumMulti doesn't use it.
+func TestBlockMultiNEON(t *testing.T) {
+	for blocks := 0; blocks <= 4; blocks++ {
+		var (
+			p    [maxLanes][]byte
+			h, g multiState
+		)
+		for lane := range p {
+			p[lane] = make([]byte, blocks*chunk)
+			if _, err := io.ReadFull(rand.Reader, p[lane]); err != nil {
+				t.Fatal(err)
+			}
+			for j := range h {
+				h[j][lane] = iv[j] + uint32(lane)
+			}
+		}
+		g = h
+		orig := h
+		blockMultiNEON(&h, &p, blocks)
+		blockMultiGeneric(&g, &p, blocks)
+		for lane := 0; lane < maxLanes; lane++ {
+			want := &g
+			if lane >= neonLanes {
+				want = &orig
+			}
+			for j := range h {
+				if h[j][lane] != want[j][lane] {
+					t.Fatalf("%d blocks: lane %d mismatch", blocks, lane)
+				}
+			}
+		}
+	}
+}
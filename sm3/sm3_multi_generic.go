@@ -0,0 +1,11 @@
+//go:build (!amd64 && !arm64) || purego
+
+package sm3
+
+func multiLanes() int {
+	return 1
+}
+
+func blockMulti(h *multiState, p *[maxLanes][]byte, blocks int) {
+	blockMultiGeneric(h, p, blocks)
+}
@@ -0,0 +1,100 @@
+package sm3
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestSumMulti(t *testing.T) {
+	// lengths around the block and padding boundaries
+	lengths := []int{0, 1, 3, 55, 56, 63, 64, 65, 119, 120, 128, 1000, 4096, 4097}
+	for _, n := range []int{0, 1, 2, 7, 8, 9, 17, len(lengths)} {
+		msgs := make([][]byte, n)
+		for i := range msgs {
+			msgs[i] = make([]byte, lengths[(i*5)%len(lengths)])
+			if _, err := io.ReadFull(rand.Reader, msgs[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		digests := SumMulti(msgs)
+		if len(digests) != n {
+			t.Fatalf("%d messages: got %d checksums", n, len(digests))
+		}
+		for i, msg := range msgs {
+			if digests[i] != Sum(msg) {
+				t.Errorf("%d messages: checksum %d of length %d mismatch", n, i, len(msg))
+			}
+		}
+	}
+}
+
+func TestSumMultiSameLength(t *testing.T) {
+	msgs := make([][]byte, 100)
+	for i := range msgs {
+		msgs[i] = []byte(fmt.Sprintf("leaf %08d %s", i, "abc"))
+	}
+	digests := SumMulti(msgs)
+	for i, msg := range msgs {
+		if digests[i] != Sum(msg) {
+			t.Errorf("checksum %d mismatch", i)
+		}
+	}
+}
+
+func TestBlockMulti(t *testing.T) {
+	const blocks = 3
+	var (
+		p    [maxLanes][]byte
+		h, g multiState
+	)
+	for lane := range p {
+		p[lane] = make([]byte, blocks*chunk)
+		if _, err := io.ReadFull(rand.Reader, p[lane]); err != nil {
+			t.Fatal(err)
+		}
+		for j := range h {
+			h[j][lane] = iv[j] + uint32(lane)
+		}
+	}
+	g = h
+	blockMulti(&h, &p, blocks)
+	blockMultiGeneric(&g, &p, blocks)
+	// only the lanes in use are hashed in parallel
+	for lane := 0; lane < multiLanes(); lane++ {
+		for j := range h {
+			if h[j][lane] != g[j][lane] {
+				t.Fatalf("blockMulti mismatch in lane %d", lane)
+			}
+		}
+	}
+}
+
+func BenchmarkSumMulti(b *testing.B) {
+	msgs := make([][]byte, 1024)
+	for i := range msgs {
+		msgs[i] = make([]byte, 64)
+	}
+	b.SetBytes(int64(len(msgs) * 64))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SumMulti(msgs)
+	}
+}
+
+func BenchmarkSumEach(b *testing.B) {
+	msgs := make([][]byte, 1024)
+	for i := range msgs {
+		msgs[i] = make([]byte, 64)
+	}
+	b.SetBytes(int64(len(msgs) * 64))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, msg := range msgs {
+			Sum(msg)
+		}
+	}
+}
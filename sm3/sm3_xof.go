@@ -0,0 +1,249 @@
+package sm3
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// XOF is an extendable-output function based on SM3. The output is the counter
+// mode expansion SM3(M || ct) for ct = 1, 2, ... over the absorbed message M,
+// so it's the same as the KDF of GB/T 32918.4-2016 5.4.3 with M as the shared
+// secret, kdf.Kdf(sm3.New(), M, n), for any output length n.
+//
+// XOF implements encoding.BinaryMarshaler and encoding.BinaryUnmarshaler to
+// marshal and unmarshal the internal state, both before and while reading output.
+type XOF struct {
+	d       digest
+	reading bool
+	ct      uint32 // the counter of the last output block
+	buf     [Size]byte
+	off     int // the number of bytes of buf already read
+}
+
+// maxXOFBlocks is the max number of output blocks, the same limit as kdf.Kdf.
+const maxXOFBlocks = 1<<32 - 2
+
+const (
+	magicXOF         = "sm3x\x01"
+	marshaledXOFSize = len(magicXOF) + marshaledSize + 1 + 4 + 1
+)
+
+// NewXOF returns a new SM3 extendable-output function.
+func NewXOF() *XOF {
+	x := new(XOF)
+	x.Reset()
+	return x
+}
+
+// Write absorbs more data into the XOF's state. It panics if called after Read.
+func (x *XOF) Write(p []byte) (int, error) {
+	if x.reading {
+		panic("sm3: Write after Read")
+	}
+	return x.d.Write(p)
+}
+
+// Read reads more output from the XOF. It returns io.EOF when the output limit
+// of (2^32-2)*Size bytes is reached.
+func (x *XOF) Read(p []byte) (n int, err error) {
+	if !x.reading {
+		x.reading = true
+		x.off = Size
+	}
+	for len(p) > 0 {
+		if x.off == Size {
+			if x.ct == maxXOFBlocks {
+				return n, io.EOF
+			}
+			x.ct++
+			x.fillBlock()
+			x.off = 0
+		}
+		copied := copy(p, x.buf[x.off:])
+		x.off += copied
+		n += copied
+		p = p[copied:]
+	}
+	return n, nil
+}
+
+// fillBlock computes the output block of the current counter into buf.
+func (x *XOF) fillBlock() {
+	var ct [4]byte
+	binary.BigEndian.PutUint32(ct[:], x.ct)
+	d := x.d
+	d.Write(ct[:])
+	x.buf = d.checkSum()
+}
+
+// Reset resets the XOF to its initial state.
+func (x *XOF) Reset() {
+	x.d.Reset()
+	x.reading = false
+	x.ct = 0
+	x.off = 0
+}
+
+// Clone returns a copy of the XOF in its current state.
+func (x *XOF) Clone() *XOF {
+	x0 := *x
+	return &x0
+}
+
+func (x *XOF) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledXOFSize)
+	b = append(b, magicXOF...)
+	state, err := x.d.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, state...)
+	if x.reading {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = appendUint32(b, x.ct)
+	b = append(b, byte(x.off))
+	return b, nil
+}
+
+func (x *XOF) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magicXOF) || string(b[:len(magicXOF)]) != magicXOF {
+		return errors.New("sm3: invalid XOF state identifier")
+	}
+	if len(b) != marshaledXOFSize {
+		return errors.New("sm3: invalid XOF state size")
+	}
+	b = b[len(magicXOF):]
+	var d digest
+	if err := d.UnmarshalBinary(b[:marshaledSize]); err != nil {
+		return err
+	}
+	b = b[marshaledSize:]
+	flag := b[0]
+	b, ct := consumeUint32(b[1:])
+	off := int(b[0])
+	reading := flag == 1
+	if flag > 1 || off > Size || ct > maxXOFBlocks ||
+		(reading && ct == 0 && off != Size) || (!reading && (ct != 0 || off != 0)) {
+		return errors.New("sm3: invalid XOF state")
+	}
+	x.d, x.reading, x.ct, x.off = d, reading, ct, off
+	if x.ct > 0 && x.off < Size {
+		x.fillBlock()
+	}
+	return nil
+}
+
+// MaxMACKeySize is the max size of the key of NewMAC in bytes.
+const MaxMACKeySize = chunk - 1
+
+// macChunk is the number of message bytes in each block of the MAC encoding,
+// the last byte of the block is the final block flag.
+const macChunk = chunk - 1
+
+const (
+	magicMAC         = "sm3m\x01"
+	marshaledMACSize = len(magicMAC) + marshaledSize
+)
+
+// mac is the keyed SM3 of NewMAC, it hashes a prefix-free encoding of the key and
+// message with SM3:
+//
+//	len(key) || key || 0... (one block)
+//	M1 || 0x00, M2 || 0x00, ..., Mn || 0x80 || 0... || 0x01
+//
+// where M1 ... Mn-1 are the message chunks of 63 bytes and Mn is the rest, which
+// is padded to 63 bytes. As no encoded message is a prefix of another, even after
+// the SM3 padding, the tag of a message can't be extended to the tag of another
+// message, and the tag is the full SM3 digest without a second pass like HMAC.
+type mac struct {
+	d  digest
+	iv [8]uint32 // the SM3 state after the key block
+}
+
+// NewMAC returns a new hash.Hash computing the keyed SM3 MAC with the given key,
+// which is at most MaxMACKeySize bytes. The Hash also implements
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler to marshal and
+// unmarshal the internal state, the state must be restored into a MAC with the
+// same key.
+func NewMAC(key []byte) (hash.Hash, error) {
+	if len(key) > MaxMACKeySize {
+		return nil, errors.New("sm3: invalid MAC key size")
+	}
+	m := new(mac)
+	var block [chunk]byte
+	block[0] = byte(len(key))
+	copy(block[1:], key)
+	m.d.Reset()
+	m.d.Write(block[:])
+	m.iv = m.d.h
+	return m, nil
+}
+
+func (m *mac) Write(p []byte) (nn int, err error) {
+	nn = len(p)
+	for len(p) > 0 {
+		n := macChunk - m.d.nx
+		if n > len(p) {
+			n = len(p)
+		}
+		m.d.Write(p[:n])
+		p = p[n:]
+		if m.d.nx == macChunk {
+			m.d.Write([]byte{0})
+		}
+	}
+	return
+}
+
+// Sum appends the current tag to in and returns the resulting slice.
+// It does not change the underlying MAC state.
+func (m *mac) Sum(in []byte) []byte {
+	var final [chunk]byte
+	final[0] = 0x80
+	final[macChunk-m.d.nx] = 1
+	d := m.d
+	d.Write(final[:chunk-m.d.nx])
+	tag := d.checkSum()
+	return append(in, tag[:]...)
+}
+
+func (m *mac) Reset() {
+	m.d.h = m.iv
+	m.d.nx = 0
+	m.d.len = chunk
+}
+
+func (m *mac) Size() int { return Size }
+
+func (m *mac) BlockSize() int { return macChunk }
+
+func (m *mac) MarshalBinary() ([]byte, error) {
+	state, err := m.d.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(magicMAC), state...), nil
+}
+
+func (m *mac) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magicMAC) || string(b[:len(magicMAC)]) != magicMAC {
+		return errors.New("sm3: invalid MAC state identifier")
+	}
+	if len(b) != marshaledMACSize {
+		return errors.New("sm3: invalid MAC state size")
+	}
+	var d digest
+	if err := d.UnmarshalBinary(b[len(magicMAC):]); err != nil {
+		return err
+	}
+	if d.len < chunk || d.nx == macChunk {
+		return errors.New("sm3: invalid MAC state")
+	}
+	m.d = d
+	return nil
+}
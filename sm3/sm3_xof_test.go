@@ -0,0 +1,192 @@
+package sm3
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"testing"
+
+	"github.com/emmansun/gmsm/kdf"
+)
+
+func TestXOF(t *testing.T) {
+	msg := []byte("abc")
+	for _, n := range []int{0, 1, 31, 32, 33, 100, 1000} {
+		x := NewXOF()
+		x.Write(msg)
+		out := make([]byte, n)
+		if _, err := x.Read(out); err != nil {
+			t.Fatal(err)
+		}
+		if want := kdf.Kdf(New(), msg, n); !bytes.Equal(out, want) {
+			t.Errorf("output of %d bytes mismatch: got %x, want %x", n, out, want)
+		}
+	}
+}
+
+func TestXOFChunkedRead(t *testing.T) {
+	x := NewXOF()
+	x.Write([]byte("message"))
+	y := x.Clone()
+	want := make([]byte, 500)
+	y.Read(want)
+	var got []byte
+	for _, n := range []int{1, 5, 32, 40, 3, 100, 319} {
+		buf := make([]byte, n)
+		x.Read(buf)
+		got = append(got, buf...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("chunked read mismatch")
+	}
+	x.Reset()
+	x.Write([]byte("message"))
+	x.Read(got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("output after Reset mismatch")
+	}
+}
+
+func TestXOFMarshal(t *testing.T) {
+	msg := bytes.Repeat([]byte("0123456789"), 20)
+	want := kdf.Kdf(New(), msg, 200)
+	for _, split := range []int{0, 1, 63, 64, 65, 200} {
+		for _, readSplit := range []int{-1, 0, 1, 32, 50} {
+			x := NewXOF()
+			x.Write(msg[:split])
+			var got []byte
+			if readSplit >= 0 {
+				x.Write(msg[split:])
+				got = make([]byte, readSplit)
+				x.Read(got)
+			}
+			state, err := x.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			y := NewXOF()
+			if err := y.UnmarshalBinary(state); err != nil {
+				t.Fatal(err)
+			}
+			if readSplit < 0 {
+				y.Write(msg[split:])
+				got = nil
+			}
+			rest := make([]byte, len(want)-len(got))
+			y.Read(rest)
+			if got = append(got, rest...); !bytes.Equal(got, want) {
+				t.Errorf("split %d, %d: output mismatch", split, readSplit)
+			}
+		}
+	}
+	if err := NewXOF().UnmarshalBinary([]byte("sm3x\x01")); err == nil {
+		t.Error("expected error for invalid state size")
+	}
+	state, _ := NewXOF().MarshalBinary()
+	state[len(state)-1] = byte(Size + 1)
+	if err := NewXOF().UnmarshalBinary(state); err == nil {
+		t.Error("expected error for invalid state")
+	}
+}
+
+func TestXOFWriteAfterRead(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	x := NewXOF()
+	x.Read(make([]byte, 1))
+	x.Write([]byte("abc"))
+}
+
+func TestXOFLimit(t *testing.T) {
+	x := NewXOF()
+	x.reading, x.ct, x.off = true, maxXOFBlocks-1, Size
+	n, err := x.Read(make([]byte, 2*Size))
+	if n != Size || err != io.EOF {
+		t.Errorf("Read = %d, %v, want %d, EOF", n, err, Size)
+	}
+}
+
+// macReference returns the tag of the MAC encoding computed with Sum.
+func macReference(key, msg []byte) []byte {
+	encoded := make([]byte, chunk)
+	encoded[0] = byte(len(key))
+	copy(encoded[1:], key)
+	for len(msg) >= macChunk {
+		encoded = append(encoded, msg[:macChunk]...)
+		encoded = append(encoded, 0)
+		msg = msg[macChunk:]
+	}
+	final := make([]byte, chunk)
+	copy(final, msg)
+	final[len(msg)] = 0x80
+	final[macChunk] = 1
+	sum := Sum(append(encoded, final...))
+	return sum[:]
+}
+
+func TestMAC(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	msg := make([]byte, 300)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, n := range []int{0, 1, 62, 63, 64, 125, 126, 127, 300} {
+		m, err := NewMAC(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Write(msg[:n])
+		want := macReference(key, msg[:n])
+		if got := m.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("%d bytes: tag = %x, want %x", n, got, want)
+		}
+		m.Reset()
+		for i := 0; i < n; i++ {
+			m.Write(msg[i : i+1])
+		}
+		if got := m.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("%d bytes: tag after Reset mismatch", n)
+		}
+	}
+	m1, _ := NewMAC(key)
+	m2, _ := NewMAC(key[:15])
+	if bytes.Equal(m1.Sum(nil), m2.Sum(nil)) {
+		t.Error("tags with different keys should differ")
+	}
+	if _, err := NewMAC(make([]byte, MaxMACKeySize+1)); err == nil {
+		t.Error("expected error for too long key")
+	}
+}
+
+func TestMACMarshal(t *testing.T) {
+	key := []byte("key")
+	msg := bytes.Repeat([]byte("0123456789"), 20)
+	want := macReference(key, msg)
+	for _, split := range []int{0, 1, 62, 63, 64, 126, 200} {
+		m, _ := NewMAC(key)
+		m.Write(msg[:split])
+		state, err := m.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m2, _ := NewMAC(key)
+		if err := m2.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			t.Fatal(err)
+		}
+		m2.Write(msg[split:])
+		if got := m2.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("split %d: tag mismatch", split)
+		}
+	}
+	m, _ := NewMAC(key)
+	if err := m.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte("sm3m\x01")); err == nil {
+		t.Error("expected error for invalid state size")
+	}
+	state, _ := New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err := m.(encoding.BinaryUnmarshaler).UnmarshalBinary(append([]byte("sm3m\x01"), state...)); err == nil {
+		t.Error("expected error for state without key block")
+	}
+}
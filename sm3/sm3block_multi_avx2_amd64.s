@@ -0,0 +1,397 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// Multi-buffer SM3 with AVX2, each 32-bit element of a vector register is one
+// lane, that is, 8 messages are hashed in parallel.
+
+// Stack layout: the expanded message words W0..W67 of all lanes, 32 bytes each.
+#define W(j) ((j)*32)(SP)
+
+// Lane base pointers and offset of the current block
+#define H_PTR DI
+#define K_PTR AX
+#define NUM_BLOCKS DX
+#define OFFSET CX
+
+// dst = x <<< n, x is kept
+#define ROTL(n, x, dst, tmp) \
+	VPSLLD $(n), x, dst; \
+	VPSRLD $(32-(n)), x, tmp; \
+	VPOR tmp, dst, dst
+
+// x = x <<< n
+#define ROTL_INPLACE(n, x, tmp) \
+	VPSLLD $(n), x, tmp; \
+	VPSRLD $(32-(n)), x, x; \
+	VPOR tmp, x, x
+
+// Load 8 words of each lane at offset off of the block, transpose them so that
+// the j-th register holds the j-th word of all lanes, convert them to big endian
+// and store W(j0)...W(j0+7).
+#define LOAD_TRANSPOSE(off, j0) \
+	VMOVDQU off(R8)(OFFSET*1), Y0; \
+	VMOVDQU off(R9)(OFFSET*1), Y1; \
+	VMOVDQU off(R10)(OFFSET*1), Y2; \
+	VMOVDQU off(R11)(OFFSET*1), Y3; \
+	VMOVDQU off(R12)(OFFSET*1), Y4; \
+	VMOVDQU off(R13)(OFFSET*1), Y5; \
+	VMOVDQU off(R14)(OFFSET*1), Y6; \
+	VMOVDQU off(BX)(OFFSET*1), Y7; \
+	VPUNPCKLDQ Y1, Y0, Y8; \
+	VPUNPCKHDQ Y1, Y0, Y9; \
+	VPUNPCKLDQ Y3, Y2, Y10; \
+	VPUNPCKHDQ Y3, Y2, Y11; \
+	VPUNPCKLDQ Y5, Y4, Y12; \
+	VPUNPCKHDQ Y5, Y4, Y13; \
+	VPUNPCKLDQ Y7, Y6, Y14; \
+	VPUNPCKHDQ Y7, Y6, Y15; \
+	VPUNPCKLQDQ Y10, Y8, Y0; \
+	VPUNPCKHQDQ Y10, Y8, Y1; \
+	VPUNPCKLQDQ Y11, Y9, Y2; \
+	VPUNPCKHQDQ Y11, Y9, Y3; \
+	VPUNPCKLQDQ Y14, Y12, Y4; \
+	VPUNPCKHQDQ Y14, Y12, Y5; \
+	VPUNPCKLQDQ Y15, Y13, Y6; \
+	VPUNPCKHQDQ Y15, Y13, Y7; \
+	VPERM2I128 $0x20, Y4, Y0, Y8; \
+	VPERM2I128 $0x20, Y5, Y1, Y9; \
+	VPERM2I128 $0x20, Y6, Y2, Y10; \
+	VPERM2I128 $0x20, Y7, Y3, Y11; \
+	VPERM2I128 $0x31, Y4, Y0, Y12; \
+	VPERM2I128 $0x31, Y5, Y1, Y13; \
+	VPERM2I128 $0x31, Y6, Y2, Y14; \
+	VPERM2I128 $0x31, Y7, Y3, Y15; \
+	VPSHUFB multi_flip_mask<>(SB), Y8, Y8; \
+	VPSHUFB multi_flip_mask<>(SB), Y9, Y9; \
+	VPSHUFB multi_flip_mask<>(SB), Y10, Y10; \
+	VPSHUFB multi_flip_mask<>(SB), Y11, Y11; \
+	VPSHUFB multi_flip_mask<>(SB), Y12, Y12; \
+	VPSHUFB multi_flip_mask<>(SB), Y13, Y13; \
+	VPSHUFB multi_flip_mask<>(SB), Y14, Y14; \
+	VPSHUFB multi_flip_mask<>(SB), Y15, Y15; \
+	VMOVDQU Y8, W(j0); \
+	VMOVDQU Y9, W(j0+1); \
+	VMOVDQU Y10, W(j0+2); \
+	VMOVDQU Y11, W(j0+3); \
+	VMOVDQU Y12, W(j0+4); \
+	VMOVDQU Y13, W(j0+5); \
+	VMOVDQU Y14, W(j0+6); \
+	VMOVDQU Y15, W(j0+7)
+
+// W(j) = P1(W(j-16) ^ W(j-9) ^ (W(j-3) <<< 15)) ^ (W(j-13) <<< 7) ^ W(j-6)
+// P1(x) = x ^ (x <<< 15) ^ (x <<< 23)
+#define MSG_EXPAND(j) \
+	VMOVDQU W(j-16), Y8; \
+	VPXOR W(j-9), Y8, Y8; \
+	VMOVDQU W(j-3), Y9; \
+	ROTL_INPLACE(15, Y9, Y10); \
+	VPXOR Y9, Y8, Y8; \
+	ROTL(15, Y8, Y9, Y10); \
+	ROTL(23, Y8, Y10, Y11); \
+	VPXOR Y9, Y8, Y8; \
+	VPXOR Y10, Y8, Y8; \
+	VMOVDQU W(j-13), Y9; \
+	ROTL_INPLACE(7, Y9, Y10); \
+	VPXOR Y9, Y8, Y8; \
+	VPXOR W(j-6), Y8, Y8; \
+	VMOVDQU Y8, W(j)
+
+// SS1 = ((a <<< 12) + e + (Tj <<< j)) <<< 7, in Y9
+// SS2 = SS1 ^ (a <<< 12), in Y8
+// d = d + SS2 + (W(j) ^ W(j+4)), h = h + SS1 + W(j)
+#define ROUND_PREPARE(j, a, d, e, h) \
+	ROTL(12, a, Y8, Y9); \
+	VPBROADCASTD ((j)*4)(K_PTR), Y9; \
+	VPADDD Y8, Y9, Y9; \
+	VPADDD e, Y9, Y9; \
+	ROTL_INPLACE(7, Y9, Y10); \
+	VPXOR Y9, Y8, Y8; \
+	VMOVDQU W(j), Y10; \
+	VPADDD Y10, h, h; \
+	VPADDD Y9, h, h; \
+	VPXOR W(j+4), Y10, Y10; \
+	VPADDD Y10, d, d; \
+	VPADDD Y8, d, d
+
+// b = b <<< 9, f = f <<< 19, h = P0(h) = h ^ (h <<< 9) ^ (h <<< 17)
+#define ROUND_FINISH(b, f, h) \
+	ROTL_INPLACE(9, b, Y8); \
+	ROTL_INPLACE(19, f, Y8); \
+	ROTL(9, h, Y8, Y9); \
+	ROTL(17, h, Y9, Y10); \
+	VPXOR Y8, h, h; \
+	VPXOR Y9, h, h
+
+// Rounds 0-15, FF = GG = x ^ y ^ z
+#define ROUND_00_15(j, a, b, c, d, e, f, g, h) \
+	ROUND_PREPARE(j, a, d, e, h); \
+	VPXOR a, b, Y8; \
+	VPXOR c, Y8, Y8; \
+	VPADDD Y8, d, d; \
+	VPXOR e, f, Y9; \
+	VPXOR g, Y9, Y9; \
+	VPADDD Y9, h, h; \
+	ROUND_FINISH(b, f, h)
+
+// Rounds 16-63, FF = (x & y) | ((x | y) & z), GG = ((y ^ z) & x) ^ z
+#define ROUND_16_63(j, a, b, c, d, e, f, g, h) \
+	ROUND_PREPARE(j, a, d, e, h); \
+	VPAND a, b, Y8; \
+	VPOR a, b, Y9; \
+	VPAND c, Y9, Y9; \
+	VPOR Y9, Y8, Y8; \
+	VPADDD Y8, d, d; \
+	VPXOR f, g, Y9; \
+	VPAND e, Y9, Y9; \
+	VPXOR g, Y9, Y9; \
+	VPADDD Y9, h, h; \
+	ROUND_FINISH(b, f, h)
+
+// func blockMultiAVX2(h *multiState, p *[maxLanes][]byte, blocks int)
+TEXT ·blockMultiAVX2(SB), 0, $2176-24
+	MOVQ h+0(FP), H_PTR
+	MOVQ p+8(FP), SI
+	MOVQ blocks+16(FP), NUM_BLOCKS
+	MOVQ 0(SI), R8
+	MOVQ 24(SI), R9
+	MOVQ 48(SI), R10
+	MOVQ 72(SI), R11
+	MOVQ 96(SI), R12
+	MOVQ 120(SI), R13
+	MOVQ 144(SI), R14
+	MOVQ 168(SI), BX
+	LEAQ multi_k<>(SB), K_PTR
+	XORQ OFFSET, OFFSET
+
+	CMPQ NUM_BLOCKS, $0
+	JEQ done
+
+loop:
+	LOAD_TRANSPOSE(0, 0)
+	LOAD_TRANSPOSE(32, 8)
+	MSG_EXPAND(16)
+	MSG_EXPAND(17)
+	MSG_EXPAND(18)
+	MSG_EXPAND(19)
+	MSG_EXPAND(20)
+	MSG_EXPAND(21)
+	MSG_EXPAND(22)
+	MSG_EXPAND(23)
+	MSG_EXPAND(24)
+	MSG_EXPAND(25)
+	MSG_EXPAND(26)
+	MSG_EXPAND(27)
+	MSG_EXPAND(28)
+	MSG_EXPAND(29)
+	MSG_EXPAND(30)
+	MSG_EXPAND(31)
+	MSG_EXPAND(32)
+	MSG_EXPAND(33)
+	MSG_EXPAND(34)
+	MSG_EXPAND(35)
+	MSG_EXPAND(36)
+	MSG_EXPAND(37)
+	MSG_EXPAND(38)
+	MSG_EXPAND(39)
+	MSG_EXPAND(40)
+	MSG_EXPAND(41)
+	MSG_EXPAND(42)
+	MSG_EXPAND(43)
+	MSG_EXPAND(44)
+	MSG_EXPAND(45)
+	MSG_EXPAND(46)
+	MSG_EXPAND(47)
+	MSG_EXPAND(48)
+	MSG_EXPAND(49)
+	MSG_EXPAND(50)
+	MSG_EXPAND(51)
+	MSG_EXPAND(52)
+	MSG_EXPAND(53)
+	MSG_EXPAND(54)
+	MSG_EXPAND(55)
+	MSG_EXPAND(56)
+	MSG_EXPAND(57)
+	MSG_EXPAND(58)
+	MSG_EXPAND(59)
+	MSG_EXPAND(60)
+	MSG_EXPAND(61)
+	MSG_EXPAND(62)
+	MSG_EXPAND(63)
+	MSG_EXPAND(64)
+	MSG_EXPAND(65)
+	MSG_EXPAND(66)
+	MSG_EXPAND(67)
+
+	VMOVDQU 0(H_PTR), Y0
+	VMOVDQU 32(H_PTR), Y1
+	VMOVDQU 64(H_PTR), Y2
+	VMOVDQU 96(H_PTR), Y3
+	VMOVDQU 128(H_PTR), Y4
+	VMOVDQU 160(H_PTR), Y5
+	VMOVDQU 192(H_PTR), Y6
+	VMOVDQU 224(H_PTR), Y7
+
+	ROUND_00_15(0, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_00_15(1, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_00_15(2, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_00_15(3, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_00_15(4, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_00_15(5, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_00_15(6, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_00_15(7, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_00_15(8, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_00_15(9, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_00_15(10, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_00_15(11, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_00_15(12, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_00_15(13, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_00_15(14, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_00_15(15, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(16, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(17, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(18, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(19, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(20, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(21, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(22, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(23, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(24, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(25, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(26, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(27, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(28, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(29, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(30, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(31, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(32, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(33, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(34, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(35, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(36, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(37, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(38, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(39, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(40, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(41, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(42, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(43, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(44, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(45, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(46, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(47, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(48, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(49, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(50, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(51, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(52, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(53, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(54, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(55, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(56, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(57, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(58, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(59, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_16_63(60, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_16_63(61, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_16_63(62, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_16_63(63, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+
+	VPXOR 0(H_PTR), Y0, Y0
+	VPXOR 32(H_PTR), Y1, Y1
+	VPXOR 64(H_PTR), Y2, Y2
+	VPXOR 96(H_PTR), Y3, Y3
+	VPXOR 128(H_PTR), Y4, Y4
+	VPXOR 160(H_PTR), Y5, Y5
+	VPXOR 192(H_PTR), Y6, Y6
+	VPXOR 224(H_PTR), Y7, Y7
+	VMOVDQU Y0, 0(H_PTR)
+	VMOVDQU Y1, 32(H_PTR)
+	VMOVDQU Y2, 64(H_PTR)
+	VMOVDQU Y3, 96(H_PTR)
+	VMOVDQU Y4, 128(H_PTR)
+	VMOVDQU Y5, 160(H_PTR)
+	VMOVDQU Y6, 192(H_PTR)
+	VMOVDQU Y7, 224(H_PTR)
+
+	ADDQ $64, OFFSET
+	DECQ NUM_BLOCKS
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
+
+// shuffle byte order from LE to BE
+DATA multi_flip_mask<>+0x00(SB)/8, $0x0405060700010203
+DATA multi_flip_mask<>+0x08(SB)/8, $0x0c0d0e0f08090a0b
+DATA multi_flip_mask<>+0x10(SB)/8, $0x0405060700010203
+DATA multi_flip_mask<>+0x18(SB)/8, $0x0c0d0e0f08090a0b
+GLOBL multi_flip_mask<>(SB), RODATA, $32
+
+// Tj <<< j
+DATA multi_k<>+0x00(SB)/4, $0x79cc4519
+DATA multi_k<>+0x04(SB)/4, $0xf3988a32
+DATA multi_k<>+0x08(SB)/4, $0xe7311465
+DATA multi_k<>+0x0c(SB)/4, $0xce6228cb
+DATA multi_k<>+0x10(SB)/4, $0x9cc45197
+DATA multi_k<>+0x14(SB)/4, $0x3988a32f
+DATA multi_k<>+0x18(SB)/4, $0x7311465e
+DATA multi_k<>+0x1c(SB)/4, $0xe6228cbc
+DATA multi_k<>+0x20(SB)/4, $0xcc451979
+DATA multi_k<>+0x24(SB)/4, $0x988a32f3
+DATA multi_k<>+0x28(SB)/4, $0x311465e7
+DATA multi_k<>+0x2c(SB)/4, $0x6228cbce
+DATA multi_k<>+0x30(SB)/4, $0xc451979c
+DATA multi_k<>+0x34(SB)/4, $0x88a32f39
+DATA multi_k<>+0x38(SB)/4, $0x11465e73
+DATA multi_k<>+0x3c(SB)/4, $0x228cbce6
+DATA multi_k<>+0x40(SB)/4, $0x9d8a7a87
+DATA multi_k<>+0x44(SB)/4, $0x3b14f50f
+DATA multi_k<>+0x48(SB)/4, $0x7629ea1e
+DATA multi_k<>+0x4c(SB)/4, $0xec53d43c
+DATA multi_k<>+0x50(SB)/4, $0xd8a7a879
+DATA multi_k<>+0x54(SB)/4, $0xb14f50f3
+DATA multi_k<>+0x58(SB)/4, $0x629ea1e7
+DATA multi_k<>+0x5c(SB)/4, $0xc53d43ce
+DATA multi_k<>+0x60(SB)/4, $0x8a7a879d
+DATA multi_k<>+0x64(SB)/4, $0x14f50f3b
+DATA multi_k<>+0x68(SB)/4, $0x29ea1e76
+DATA multi_k<>+0x6c(SB)/4, $0x53d43cec
+DATA multi_k<>+0x70(SB)/4, $0xa7a879d8
+DATA multi_k<>+0x74(SB)/4, $0x4f50f3b1
+DATA multi_k<>+0x78(SB)/4, $0x9ea1e762
+DATA multi_k<>+0x7c(SB)/4, $0x3d43cec5
+DATA multi_k<>+0x80(SB)/4, $0x7a879d8a
+DATA multi_k<>+0x84(SB)/4, $0xf50f3b14
+DATA multi_k<>+0x88(SB)/4, $0xea1e7629
+DATA multi_k<>+0x8c(SB)/4, $0xd43cec53
+DATA multi_k<>+0x90(SB)/4, $0xa879d8a7
+DATA multi_k<>+0x94(SB)/4, $0x50f3b14f
+DATA multi_k<>+0x98(SB)/4, $0xa1e7629e
+DATA multi_k<>+0x9c(SB)/4, $0x43cec53d
+DATA multi_k<>+0xa0(SB)/4, $0x879d8a7a
+DATA multi_k<>+0xa4(SB)/4, $0x0f3b14f5
+DATA multi_k<>+0xa8(SB)/4, $0x1e7629ea
+DATA multi_k<>+0xac(SB)/4, $0x3cec53d4
+DATA multi_k<>+0xb0(SB)/4, $0x79d8a7a8
+DATA multi_k<>+0xb4(SB)/4, $0xf3b14f50
+DATA multi_k<>+0xb8(SB)/4, $0xe7629ea1
+DATA multi_k<>+0xbc(SB)/4, $0xcec53d43
+DATA multi_k<>+0xc0(SB)/4, $0x9d8a7a87
+DATA multi_k<>+0xc4(SB)/4, $0x3b14f50f
+DATA multi_k<>+0xc8(SB)/4, $0x7629ea1e
+DATA multi_k<>+0xcc(SB)/4, $0xec53d43c
+DATA multi_k<>+0xd0(SB)/4, $0xd8a7a879
+DATA multi_k<>+0xd4(SB)/4, $0xb14f50f3
+DATA multi_k<>+0xd8(SB)/4, $0x629ea1e7
+DATA multi_k<>+0xdc(SB)/4, $0xc53d43ce
+DATA multi_k<>+0xe0(SB)/4, $0x8a7a879d
+DATA multi_k<>+0xe4(SB)/4, $0x14f50f3b
+DATA multi_k<>+0xe8(SB)/4, $0x29ea1e76
+DATA multi_k<>+0xec(SB)/4, $0x53d43cec
+DATA multi_k<>+0xf0(SB)/4, $0xa7a879d8
+DATA multi_k<>+0xf4(SB)/4, $0x4f50f3b1
+DATA multi_k<>+0xf8(SB)/4, $0x9ea1e762
+DATA multi_k<>+0xfc(SB)/4, $0x3d43cec5
+GLOBL multi_k<>(SB), RODATA, $256
@@ -0,0 +1,317 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+#include "sm3_const_asm.s"
+
+// Multi-buffer SM3 with NEON, each 32-bit element of a vector register is one
+// lane, that is, 4 messages are hashed in parallel.
+
+// Stack layout: the expanded message words W0..W67 of all lanes, 16 bytes each.
+#define W(j) (16+(j)*16)(RSP)
+
+#define H_PTR R0
+#define NUM_BLOCKS R2
+#define CONST R9
+
+// dst = x <<< n
+#define ROTL(n, x, dst) \
+	VSHL $(n), x.S4, dst.S4; \
+	VSRI $(32-(n)), x.S4, dst.S4
+
+// Transpose the j-th 4 words of the lanes, t0 of lane 0 ... t3 of lane 3, so
+// that the i-th register holds the (4j+i)-th word of all lanes, and store them
+// to W(4j)...W(4j+3), R8 points to W(4j).
+//
+// input: from high to low
+// t0 = t0.S3, t0.S2, t0.S1, t0.S0
+// t1 = t1.S3, t1.S2, t1.S1, t1.S0
+// t2 = t2.S3, t2.S2, t2.S1, t2.S0
+// t3 = t3.S3, t3.S2, t3.S1, t3.S0
+// output: from high to low
+// V8 = t3.S0, t2.S0, t1.S0, t0.S0
+// V9 = t3.S1, t2.S1, t1.S1, t0.S1
+// V10 = t3.S2, t2.S2, t1.S2, t0.S2
+// V11 = t3.S3, t2.S3, t1.S3, t0.S3
+#define TRANSPOSE_STORE(t0, t1, t2, t3) \
+	VZIP1 t1.S4, t0.S4, V12.S4; \
+	VZIP1 t3.S4, t2.S4, V13.S4; \
+	VZIP2 t1.S4, t0.S4, V14.S4; \
+	VZIP2 t3.S4, t2.S4, V15.S4; \
+	VZIP1 V13.D2, V12.D2, V8.D2; \
+	VZIP2 V13.D2, V12.D2, V9.D2; \
+	VZIP1 V15.D2, V14.D2, V10.D2; \
+	VZIP2 V15.D2, V14.D2, V11.D2; \
+	VST1.P [V8.S4, V9.S4, V10.S4, V11.S4], 64(R8)
+
+// W(j) = P1(W(j-16) ^ W(j-9) ^ (W(j-3) <<< 15)) ^ (W(j-13) <<< 7) ^ W(j-6)
+// P1(x) = x ^ (x <<< 15) ^ (x <<< 23)
+#define MSG_EXPAND(j) \
+	FMOVQ W(j-16), F8; \
+	FMOVQ W(j-9), F9; \
+	VEOR V9.B16, V8.B16, V8.B16; \
+	FMOVQ W(j-3), F9; \
+	ROTL(15, V9, V10); \
+	VEOR V10.B16, V8.B16, V8.B16; \
+	ROTL(15, V8, V9); \
+	ROTL(23, V8, V10); \
+	VEOR V9.B16, V8.B16, V8.B16; \
+	VEOR V10.B16, V8.B16, V8.B16; \
+	FMOVQ W(j-13), F9; \
+	ROTL(7, V9, V10); \
+	VEOR V10.B16, V8.B16, V8.B16; \
+	FMOVQ W(j-6), F9; \
+	VEOR V9.B16, V8.B16, V8.B16; \
+	FMOVQ F8, W(j)
+
+// SS1 = ((a <<< 12) + e + (Tj <<< j)) <<< 7, in V10
+// SS2 = SS1 ^ (a <<< 12), in V8
+// d = d + SS2 + (W(j) ^ W(j+4)), h = h + SS1 + W(j)
+#define ROUND_PREPARE(j, const, a, d, e, h) \
+	ROTL(12, a, V8); \
+	MOVW $const, CONST; \
+	VDUP CONST, V9.S4; \
+	VADD V8.S4, V9.S4, V9.S4; \
+	VADD e.S4, V9.S4, V9.S4; \
+	ROTL(7, V9, V10); \
+	VEOR V10.B16, V8.B16, V8.B16; \
+	FMOVQ W(j), F11; \
+	VADD V11.S4, h.S4, h.S4; \
+	VADD V10.S4, h.S4, h.S4; \
+	FMOVQ W(j+4), F12; \
+	VEOR V12.B16, V11.B16, V11.B16; \
+	VADD V11.S4, d.S4, d.S4; \
+	VADD V8.S4, d.S4, d.S4
+
+// b = b <<< 9, f = f <<< 19, h = P0(h) = h ^ (h <<< 9) ^ (h <<< 17)
+#define ROUND_FINISH(b, f, h) \
+	ROTL(9, b, V8); \
+	VMOV V8.B16, b.B16; \
+	ROTL(19, f, V8); \
+	VMOV V8.B16, f.B16; \
+	ROTL(9, h, V8); \
+	ROTL(17, h, V9); \
+	VEOR V8.B16, h.B16, h.B16; \
+	VEOR V9.B16, h.B16, h.B16
+
+// Rounds 0-15, FF = GG = x ^ y ^ z
+#define ROUND_00_15(j, const, a, b, c, d, e, f, g, h) \
+	ROUND_PREPARE(j, const, a, d, e, h); \
+	VEOR a.B16, b.B16, V8.B16; \
+	VEOR c.B16, V8.B16, V8.B16; \
+	VADD V8.S4, d.S4, d.S4; \
+	VEOR e.B16, f.B16, V9.B16; \
+	VEOR g.B16, V9.B16, V9.B16; \
+	VADD V9.S4, h.S4, h.S4; \
+	ROUND_FINISH(b, f, h)
+
+// Rounds 16-63, FF = (x & y) | ((x | y) & z), GG = ((y ^ z) & x) ^ z
+#define ROUND_16_63(j, const, a, b, c, d, e, f, g, h) \
+	ROUND_PREPARE(j, const, a, d, e, h); \
+	VAND a.B16, b.B16, V8.B16; \
+	VORR a.B16, b.B16, V9.B16; \
+	VAND c.B16, V9.B16, V9.B16; \
+	VORR V9.B16, V8.B16, V8.B16; \
+	VADD V8.S4, d.S4, d.S4; \
+	VEOR f.B16, g.B16, V9.B16; \
+	VAND e.B16, V9.B16, V9.B16; \
+	VEOR g.B16, V9.B16, V9.B16; \
+	VADD V9.S4, h.S4, h.S4; \
+	ROUND_FINISH(b, f, h)
+
+// func blockMultiNEON(h *multiState, p *[maxLanes][]byte, blocks int)
+TEXT ·blockMultiNEON(SB), 0, $1096-24
+	MOVD h+0(FP), H_PTR
+	MOVD p+8(FP), R1
+	MOVD blocks+16(FP), NUM_BLOCKS
+	MOVD 0(R1), R3
+	MOVD 24(R1), R4
+	MOVD 48(R1), R5
+	MOVD 72(R1), R6
+
+	CBZ NUM_BLOCKS, done
+
+loop:
+	VLD1.P 64(R3), [V16.S4, V17.S4, V18.S4, V19.S4]
+	VLD1.P 64(R4), [V20.S4, V21.S4, V22.S4, V23.S4]
+	VLD1.P 64(R5), [V24.S4, V25.S4, V26.S4, V27.S4]
+	VLD1.P 64(R6), [V28.S4, V29.S4, V30.S4, V31.S4]
+	MOVD RSP, R8
+	ADD $16, R8
+	VREV32 V16.B16, V16.B16
+	VREV32 V17.B16, V17.B16
+	VREV32 V18.B16, V18.B16
+	VREV32 V19.B16, V19.B16
+	VREV32 V20.B16, V20.B16
+	VREV32 V21.B16, V21.B16
+	VREV32 V22.B16, V22.B16
+	VREV32 V23.B16, V23.B16
+	VREV32 V24.B16, V24.B16
+	VREV32 V25.B16, V25.B16
+	VREV32 V26.B16, V26.B16
+	VREV32 V27.B16, V27.B16
+	VREV32 V28.B16, V28.B16
+	VREV32 V29.B16, V29.B16
+	VREV32 V30.B16, V30.B16
+	VREV32 V31.B16, V31.B16
+	TRANSPOSE_STORE(V16, V20, V24, V28)
+	TRANSPOSE_STORE(V17, V21, V25, V29)
+	TRANSPOSE_STORE(V18, V22, V26, V30)
+	TRANSPOSE_STORE(V19, V23, V27, V31)
+	MSG_EXPAND(16)
+	MSG_EXPAND(17)
+	MSG_EXPAND(18)
+	MSG_EXPAND(19)
+	MSG_EXPAND(20)
+	MSG_EXPAND(21)
+	MSG_EXPAND(22)
+	MSG_EXPAND(23)
+	MSG_EXPAND(24)
+	MSG_EXPAND(25)
+	MSG_EXPAND(26)
+	MSG_EXPAND(27)
+	MSG_EXPAND(28)
+	MSG_EXPAND(29)
+	MSG_EXPAND(30)
+	MSG_EXPAND(31)
+	MSG_EXPAND(32)
+	MSG_EXPAND(33)
+	MSG_EXPAND(34)
+	MSG_EXPAND(35)
+	MSG_EXPAND(36)
+	MSG_EXPAND(37)
+	MSG_EXPAND(38)
+	MSG_EXPAND(39)
+	MSG_EXPAND(40)
+	MSG_EXPAND(41)
+	MSG_EXPAND(42)
+	MSG_EXPAND(43)
+	MSG_EXPAND(44)
+	MSG_EXPAND(45)
+	MSG_EXPAND(46)
+	MSG_EXPAND(47)
+	MSG_EXPAND(48)
+	MSG_EXPAND(49)
+	MSG_EXPAND(50)
+	MSG_EXPAND(51)
+	MSG_EXPAND(52)
+	MSG_EXPAND(53)
+	MSG_EXPAND(54)
+	MSG_EXPAND(55)
+	MSG_EXPAND(56)
+	MSG_EXPAND(57)
+	MSG_EXPAND(58)
+	MSG_EXPAND(59)
+	MSG_EXPAND(60)
+	MSG_EXPAND(61)
+	MSG_EXPAND(62)
+	MSG_EXPAND(63)
+	MSG_EXPAND(64)
+	MSG_EXPAND(65)
+	MSG_EXPAND(66)
+	MSG_EXPAND(67)
+
+	FMOVQ 0(H_PTR), F0
+	FMOVQ 32(H_PTR), F1
+	FMOVQ 64(H_PTR), F2
+	FMOVQ 96(H_PTR), F3
+	FMOVQ 128(H_PTR), F4
+	FMOVQ 160(H_PTR), F5
+	FMOVQ 192(H_PTR), F6
+	FMOVQ 224(H_PTR), F7
+
+	ROUND_00_15(0, T0, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_00_15(1, T1, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_00_15(2, T2, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_00_15(3, T3, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_00_15(4, T4, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_00_15(5, T5, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_00_15(6, T6, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_00_15(7, T7, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_00_15(8, T8, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_00_15(9, T9, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_00_15(10, T10, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_00_15(11, T11, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_00_15(12, T12, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_00_15(13, T13, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_00_15(14, T14, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_00_15(15, T15, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(16, T16, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(17, T17, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(18, T18, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(19, T19, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(20, T20, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(21, T21, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(22, T22, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(23, T23, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(24, T24, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(25, T25, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(26, T26, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(27, T27, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(28, T28, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(29, T29, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(30, T30, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(31, T31, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(32, T32, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(33, T33, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(34, T34, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(35, T35, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(36, T36, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(37, T37, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(38, T38, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(39, T39, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(40, T40, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(41, T41, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(42, T42, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(43, T43, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(44, T44, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(45, T45, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(46, T46, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(47, T47, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(48, T48, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(49, T49, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(50, T50, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(51, T51, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(52, T52, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(53, T53, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(54, T54, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(55, T55, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(56, T56, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(57, T57, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(58, T58, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(59, T59, V1, V2, V3, V0, V5, V6, V7, V4)
+	ROUND_16_63(60, T60, V0, V1, V2, V3, V4, V5, V6, V7)
+	ROUND_16_63(61, T61, V3, V0, V1, V2, V7, V4, V5, V6)
+	ROUND_16_63(62, T62, V2, V3, V0, V1, V6, V7, V4, V5)
+	ROUND_16_63(63, T63, V1, V2, V3, V0, V5, V6, V7, V4)
+
+	FMOVQ 0(H_PTR), F8
+	FMOVQ 32(H_PTR), F9
+	FMOVQ 64(H_PTR), F10
+	FMOVQ 96(H_PTR), F11
+	FMOVQ 128(H_PTR), F12
+	FMOVQ 160(H_PTR), F13
+	FMOVQ 192(H_PTR), F14
+	FMOVQ 224(H_PTR), F15
+	VEOR V8.B16, V0.B16, V0.B16
+	VEOR V9.B16, V1.B16, V1.B16
+	VEOR V10.B16, V2.B16, V2.B16
+	VEOR V11.B16, V3.B16, V3.B16
+	VEOR V12.B16, V4.B16, V4.B16
+	VEOR V13.B16, V5.B16, V5.B16
+	VEOR V14.B16, V6.B16, V6.B16
+	VEOR V15.B16, V7.B16, V7.B16
+	FMOVQ F0, 0(H_PTR)
+	FMOVQ F1, 32(H_PTR)
+	FMOVQ F2, 64(H_PTR)
+	FMOVQ F3, 96(H_PTR)
+	FMOVQ F4, 128(H_PTR)
+	FMOVQ F5, 160(H_PTR)
+	FMOVQ F6, 192(H_PTR)
+	FMOVQ F7, 224(H_PTR)
+
+	SUB $1, NUM_BLOCKS
+	CBNZ NUM_BLOCKS, loop
+
+done:
+	RET
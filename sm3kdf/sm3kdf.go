@@ -0,0 +1,96 @@
+// Package sm3kdf implements HMAC-SM3, HKDF-SM3 (RFC 5869) and PBKDF2-SM3 (RFC 8018),
+// and a KDF interface which makes them and the GB/T 32918.4 KDF interchangeable.
+package sm3kdf
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/emmansun/gmsm/kdf"
+	"github.com/emmansun/gmsm/sm3"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KDF is a key derivation function bound to SM3 with its parameters.
+type KDF interface {
+	// DeriveKey derives a key of size bytes from the secret.
+	DeriveKey(secret []byte, size int) ([]byte, error)
+}
+
+// NewHMAC returns a new HMAC-SM3 hash using the given key.
+func NewHMAC(key []byte) hash.Hash {
+	return hmac.New(sm3.New, key)
+}
+
+// HMAC returns the HMAC-SM3 of data using the given key.
+func HMAC(key, data []byte) []byte {
+	mac := NewHMAC(key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Extract generates a pseudorandom key from secret and salt with HKDF-SM3,
+// the salt is optional.
+func Extract(secret, salt []byte) []byte {
+	return hkdf.Extract(sm3.New, secret, salt)
+}
+
+// Expand derives a key of size bytes from the pseudorandom key prk and the optional
+// info with HKDF-SM3. The size must not exceed 255 * sm3.Size.
+func Expand(prk, info []byte, size int) ([]byte, error) {
+	if size < 0 || size > 255*sm3.Size {
+		return nil, errors.New("sm3kdf: invalid HKDF key size")
+	}
+	key := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.Expand(sm3.New, prk, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// PBKDF2Key derives a key of size bytes from the password and salt with PBKDF2-SM3.
+func PBKDF2Key(password, salt []byte, iterations, size int) []byte {
+	return pbkdf2.Key(password, salt, iterations, size, sm3.New)
+}
+
+// X963 is the KDF of GB/T 32918.4-2016 5.4.3 (ANSI-X9.63-KDF) with SM3, see kdf.Kdf.
+type X963 struct{}
+
+// DeriveKey derives a key of size bytes from the secret with kdf.Kdf.
+func (X963) DeriveKey(secret []byte, size int) ([]byte, error) {
+	if size < 0 || uint64(size) > (1<<32-2)*uint64(sm3.Size) {
+		return nil, errors.New("sm3kdf: invalid X9.63 key size")
+	}
+	return kdf.Kdf(sm3.New(), secret, size), nil
+}
+
+// HKDF is HKDF-SM3 with the optional salt and info.
+type HKDF struct {
+	Salt []byte
+	Info []byte
+}
+
+// DeriveKey derives a key of size bytes from the secret with Extract and Expand.
+func (h HKDF) DeriveKey(secret []byte, size int) ([]byte, error) {
+	return Expand(Extract(secret, h.Salt), h.Info, size)
+}
+
+// PBKDF2 is PBKDF2 with HMAC-SM3 as the pseudorandom function.
+type PBKDF2 struct {
+	Salt       []byte
+	Iterations int
+}
+
+// DeriveKey derives a key of size bytes from the secret (password) with PBKDF2Key.
+func (p PBKDF2) DeriveKey(secret []byte, size int) ([]byte, error) {
+	if p.Iterations < 1 {
+		return nil, errors.New("sm3kdf: invalid PBKDF2 iteration count")
+	}
+	if size < 0 {
+		return nil, errors.New("sm3kdf: invalid PBKDF2 key size")
+	}
+	return PBKDF2Key(secret, p.Salt, p.Iterations, size), nil
+}
@@ -0,0 +1,117 @@
+package sm3kdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// The known answers are cross-checked with OpenSSL 3, the inputs are from the
+// test cases of RFC 4231 (HMAC), RFC 5869 (HKDF) and RFC 6070 (PBKDF2).
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var ikm = bytes.Repeat([]byte{0x0b}, 22)
+
+func TestHMAC(t *testing.T) {
+	tests := []struct {
+		key, data []byte
+		want      string
+	}{
+		{ikm, []byte("Hi There"), "c98c399b21ab773692e7e2b8d69ead91c47ae25de9c418191c962904f80f139e"},
+		{[]byte("Jefe"), []byte("what do ya want for nothing?"), "2e87f1d16862e6d964b50a5200bf2b10b764faa9680a296a2405f24bec39f882"},
+	}
+	for i, tt := range tests {
+		if got := HMAC(tt.key, tt.data); !bytes.Equal(got, decodeHex(t, tt.want)) {
+			t.Errorf("case %d: HMAC = %x, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	salt := decodeHex(t, "000102030405060708090a0b0c")
+	info := decodeHex(t, "f0f1f2f3f4f5f6f7f8f9")
+	prk := Extract(ikm, salt)
+	if want := decodeHex(t, "e0d6f7b0bd056327b7659f1f39ad850561fbcf4fb10fb58e88eafa55cf7cd01e"); !bytes.Equal(prk, want) {
+		t.Errorf("Extract = %x, want %x", prk, want)
+	}
+	tests := []struct {
+		kdf  HKDF
+		want string
+	}{
+		{HKDF{Salt: salt, Info: info}, "c69fe91b7aaee2dd5718d72dcaee0cce93f1b8e41f792da51261b6a517e68b36ed2c595572b01dfa359b"},
+		{HKDF{}, "c8c91a38ae2fb3b023a7c38ce9f0748f28230d59b6b950ba3ba949bf0d713a5774815778801741cb2034"},
+	}
+	for i, tt := range tests {
+		got, err := tt.kdf.DeriveKey(ikm, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := decodeHex(t, tt.want); !bytes.Equal(got, want) {
+			t.Errorf("case %d: HKDF = %x, want %x", i, got, want)
+		}
+	}
+	if _, err := Expand(prk, info, 255*32+1); err == nil {
+		t.Error("expected error for too long key")
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"password", "salt", 1, "4612f922a1fdcefaf4312fc6f8f3322b489cbf24f2ea361b44c2bd8fa2c6dcb0"},
+		{"password", "salt", 4096, "b6e8f2074c87432b78f62e5ced980fdff89e86af2f693dab1638e2b3683045dd"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "3b6282ac8519f059e465abff0ea37b0dbfe6c672a76e6b805312d53900db630732ccc1a88fa5512a"},
+	}
+	for i, tt := range tests {
+		want := decodeHex(t, tt.want)
+		got, err := PBKDF2{Salt: []byte(tt.salt), Iterations: tt.iterations}.DeriveKey([]byte(tt.password), len(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("case %d: PBKDF2 = %x, want %x", i, got, want)
+		}
+	}
+	if _, err := (PBKDF2{Salt: []byte("salt")}).DeriveKey([]byte("password"), 32); err == nil {
+		t.Error("expected error for zero iteration count")
+	}
+}
+
+func TestKDFInterchangeable(t *testing.T) {
+	kdfs := []struct {
+		kdf  KDF
+		want string
+	}{
+		{X963{}, "2e24fd46d50d6d29493a7afba03cf143587b5392d75a9afeeb8eb78e3e51db8129ce6cd069b6176b"},
+		{HKDF{}, "c8c91a38ae2fb3b023a7c38ce9f0748f28230d59b6b950ba3ba949bf0d713a5774815778"},
+		{PBKDF2{Salt: []byte("salt"), Iterations: 1}, "4612f922a1fdcefaf4312fc6f8f3322b489cbf24f2ea361b44c2bd8fa2c6dcb0"},
+	}
+	for i, tt := range kdfs {
+		want := decodeHex(t, tt.want)
+		secret := ikm
+		if _, ok := tt.kdf.(PBKDF2); ok {
+			secret = []byte("password")
+		}
+		got, err := tt.kdf.DeriveKey(secret, len(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("case %d: %T = %x, want %x", i, tt.kdf, got, want)
+		}
+		if _, err := tt.kdf.DeriveKey(secret, -1); err == nil {
+			t.Errorf("case %d: expected error for negative size", i)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package sm3merkle implements the Merkle tree of RFC 6962 / RFC 9162 with SM3
+// as the hash function, with inclusion and consistency proofs.
+//
+// The leaf hash is SM3(0x00 || data) and the node hash is SM3(0x01 || left || right),
+// the hash of an empty tree is SM3 of the empty string.
+package sm3merkle
+
+import (
+	"errors"
+	"math/bits"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+const (
+	leafPrefix = 0
+	nodePrefix = 1
+)
+
+// LeafHash returns the hash of a leaf with the given data.
+func LeafHash(data []byte) [sm3.Size]byte {
+	h := sm3.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	var sum [sm3.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// NodeHash returns the hash of an interior node with the given children.
+func NodeHash(left, right [sm3.Size]byte) [sm3.Size]byte {
+	var buf [1 + 2*sm3.Size]byte
+	buf[0] = nodePrefix
+	copy(buf[1:], left[:])
+	copy(buf[1+sm3.Size:], right[:])
+	return sm3.Sum(buf[:])
+}
+
+// leafHashes returns the leaf hashes of the data, they are hashed in parallel
+// by sm3.SumMulti.
+func leafHashes(data [][]byte) [][sm3.Size]byte {
+	msgs := make([][]byte, len(data))
+	for i, d := range data {
+		msgs[i] = make([]byte, 1+len(d))
+		msgs[i][0] = leafPrefix
+		copy(msgs[i][1:], d)
+	}
+	return sm3.SumMulti(msgs)
+}
+
+// nodeHashes returns the hashes of the pairs of adjacent nodes, that is the
+// hash of nodes[2i] and nodes[2i+1], they are hashed in parallel by sm3.SumMulti.
+func nodeHashes(nodes [][sm3.Size]byte) [][sm3.Size]byte {
+	n := len(nodes) / 2
+	buf := make([]byte, n*(1+2*sm3.Size))
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msg := buf[i*(1+2*sm3.Size) : (i+1)*(1+2*sm3.Size)]
+		msg[0] = nodePrefix
+		copy(msg[1:], nodes[2*i][:])
+		copy(msg[1+sm3.Size:], nodes[2*i+1][:])
+		msgs[i] = msg
+	}
+	return sm3.SumMulti(msgs)
+}
+
+// RootHash returns the Merkle tree hash of the leaves.
+func RootHash(leaves [][]byte) [sm3.Size]byte {
+	t := New()
+	t.AppendBatch(leaves)
+	return t.Root()
+}
+
+// TreeHash splits data into leaves of leafSize bytes (the last one may be shorter)
+// and returns the Merkle tree hash of them. It panics if leafSize is not positive.
+func TreeHash(data []byte, leafSize int) [sm3.Size]byte {
+	if leafSize <= 0 {
+		panic("sm3merkle: invalid leaf size")
+	}
+	var leaves [][]byte
+	for len(data) > leafSize {
+		leaves = append(leaves, data[:leafSize])
+		data = data[leafSize:]
+	}
+	if len(data) > 0 {
+		leaves = append(leaves, data)
+	}
+	return RootHash(leaves)
+}
+
+// Tree is an append-only Merkle tree which keeps the hashes of all its complete
+// subtrees, so that roots and proofs of it and of its earlier versions are computed
+// in logarithmic time. It's NOT concurrent safe.
+type Tree struct {
+	// levels[k][i] is the hash of the complete subtree of the 2^k leaves from i<<k.
+	levels [][][sm3.Size]byte
+}
+
+// New returns an empty Merkle tree.
+func New() *Tree {
+	return &Tree{levels: make([][][sm3.Size]byte, 1)}
+}
+
+// Size returns the number of leaves of the tree.
+func (t *Tree) Size() uint64 {
+	return uint64(len(t.levels[0]))
+}
+
+// Append appends a leaf with the given data and returns its index.
+func (t *Tree) Append(data []byte) uint64 {
+	return t.AppendHash(LeafHash(data))
+}
+
+// AppendHash appends a leaf with the given leaf hash and returns its index.
+func (t *Tree) AppendHash(leafHash [sm3.Size]byte) uint64 {
+	index := t.Size()
+	t.levels[0] = append(t.levels[0], leafHash)
+	for k := 0; len(t.levels[k])%2 == 0; k++ {
+		if k+1 == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		nodes := t.levels[k]
+		t.levels[k+1] = append(t.levels[k+1], NodeHash(nodes[len(nodes)-2], nodes[len(nodes)-1]))
+	}
+	return index
+}
+
+// AppendBatch appends leaves with the given data and returns the index of the first one.
+// The leaf and node hashes are computed in parallel where supported, which is much
+// faster than calling Append for each leaf.
+func (t *Tree) AppendBatch(data [][]byte) uint64 {
+	index := t.Size()
+	t.levels[0] = append(t.levels[0], leafHashes(data)...)
+	for k := 0; len(t.levels[k]) >= 2; k++ {
+		if k+1 == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		// The nodes of level k from 2*len(t.levels[k+1]) are not combined yet.
+		pending := t.levels[k][2*len(t.levels[k+1]):]
+		if len(pending) < 2 {
+			break
+		}
+		t.levels[k+1] = append(t.levels[k+1], nodeHashes(pending)...)
+	}
+	return index
+}
+
+// LeafHash returns the hash of the leaf at index.
+func (t *Tree) LeafHash(index uint64) ([sm3.Size]byte, error) {
+	if index >= t.Size() {
+		return [sm3.Size]byte{}, errors.New("sm3merkle: leaf index out of range")
+	}
+	return t.levels[0][index], nil
+}
+
+// Root returns the root hash of the tree.
+func (t *Tree) Root() [sm3.Size]byte {
+	root, _ := t.RootAt(t.Size())
+	return root
+}
+
+// RootAt returns the root hash of the tree when it had size leaves.
+func (t *Tree) RootAt(size uint64) ([sm3.Size]byte, error) {
+	if size > t.Size() {
+		return [sm3.Size]byte{}, errors.New("sm3merkle: tree size out of range")
+	}
+	if size == 0 {
+		return sm3.Sum(nil), nil
+	}
+	return t.hashRange(0, size), nil
+}
+
+// hashRange returns the Merkle tree hash of the leaves [lo, hi), lo < hi. The range
+// is either a complete subtree or, as all ranges of RFC 6962, lo is a multiple of
+// the largest power of two less than hi-lo.
+func (t *Tree) hashRange(lo, hi uint64) [sm3.Size]byte {
+	n := hi - lo
+	if n&(n-1) == 0 {
+		k := bits.TrailingZeros64(n)
+		return t.levels[k][lo>>k]
+	}
+	k := split(n)
+	return NodeHash(t.hashRange(lo, lo+k), t.hashRange(lo+k, hi))
+}
+
+// split returns the largest power of two less than n, n > 1.
+func split(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// InclusionProof returns the audit path of the leaf at index in the tree of
+// size leaves, see RFC 9162 2.1.3.1.
+func (t *Tree) InclusionProof(index, size uint64) ([][sm3.Size]byte, error) {
+	if size > t.Size() {
+		return nil, errors.New("sm3merkle: tree size out of range")
+	}
+	if index >= size {
+		return nil, errors.New("sm3merkle: leaf index out of range")
+	}
+	var proof [][sm3.Size]byte
+	t.path(&proof, index, 0, size)
+	return proof, nil
+}
+
+// path appends the audit path of the leaf at index in the subtree of the leaves
+// [lo, hi) to proof, from the leaf to the top.
+func (t *Tree) path(proof *[][sm3.Size]byte, index, lo, hi uint64) {
+	if hi-lo <= 1 {
+		return
+	}
+	k := lo + split(hi-lo)
+	if index < k {
+		t.path(proof, index, lo, k)
+		*proof = append(*proof, t.hashRange(k, hi))
+	} else {
+		t.path(proof, index, k, hi)
+		*proof = append(*proof, t.hashRange(lo, k))
+	}
+}
+
+// ConsistencyProof returns the proof that the tree of size2 leaves is an append-only
+// extension of the tree of size1 leaves, see RFC 9162 2.1.4.1.
+func (t *Tree) ConsistencyProof(size1, size2 uint64) ([][sm3.Size]byte, error) {
+	if size2 > t.Size() {
+		return nil, errors.New("sm3merkle: tree size out of range")
+	}
+	if size1 > size2 {
+		return nil, errors.New("sm3merkle: first tree size larger than second")
+	}
+	var proof [][sm3.Size]byte
+	if size1 > 0 {
+		t.subproof(&proof, size1, 0, size2, true)
+	}
+	return proof, nil
+}
+
+// subproof appends SUBPROOF(m, D[lo:hi], complete) of RFC 9162 to proof.
+func (t *Tree) subproof(proof *[][sm3.Size]byte, m, lo, hi uint64, complete bool) {
+	n := hi - lo
+	if m == n {
+		if !complete {
+			*proof = append(*proof, t.hashRange(lo, hi))
+		}
+		return
+	}
+	k := split(n)
+	if m <= k {
+		t.subproof(proof, m, lo, lo+k, complete)
+		*proof = append(*proof, t.hashRange(lo+k, hi))
+	} else {
+		t.subproof(proof, m-k, lo+k, hi, false)
+		*proof = append(*proof, t.hashRange(lo, lo+k))
+	}
+}
+
+// VerifyInclusion verifies that the leaf with leafHash is at index of the tree of
+// size leaves with the given root, see RFC 9162 2.1.3.2.
+func VerifyInclusion(index, size uint64, leafHash [sm3.Size]byte, proof [][sm3.Size]byte, root [sm3.Size]byte) error {
+	if index >= size {
+		return errors.New("sm3merkle: leaf index out of range")
+	}
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return errors.New("sm3merkle: inclusion proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("sm3merkle: inclusion proof too short")
+	}
+	if r != root {
+		return errors.New("sm3merkle: root hash mismatch")
+	}
+	return nil
+}
+
+// VerifyConsistency verifies that the tree of size2 leaves with root2 is an
+// append-only extension of the tree of size1 leaves with root1, see RFC 9162 2.1.4.2.
+func VerifyConsistency(size1, size2 uint64, proof [][sm3.Size]byte, root1, root2 [sm3.Size]byte) error {
+	switch {
+	case size1 > size2:
+		return errors.New("sm3merkle: first tree size larger than second")
+	case size1 == size2 || size1 == 0:
+		if len(proof) != 0 {
+			return errors.New("sm3merkle: consistency proof should be empty")
+		}
+		if size1 == size2 && root1 != root2 {
+			return errors.New("sm3merkle: root hash mismatch")
+		}
+		return nil
+	case len(proof) == 0:
+		return errors.New("sm3merkle: empty consistency proof")
+	}
+	if size1&(size1-1) == 0 {
+		proof = append([][sm3.Size]byte{root1}, proof...)
+	}
+	fn, sn := size1-1, size2-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return errors.New("sm3merkle: consistency proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = NodeHash(c, fr)
+			sr = NodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = NodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("sm3merkle: consistency proof too short")
+	}
+	if fr != root1 || sr != root2 {
+		return errors.New("sm3merkle: root hash mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package sm3merkle
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// The leaves of the RFC 6962 reference tests, the known roots are cross-checked with OpenSSL 3.
+var testLeaves = []string{
+	"", "00", "10", "2021", "3031", "40414243",
+	"5051525354555657", "606162636465666768696a6b6c6d6e6f",
+}
+
+func decodeHex(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testData(n int) [][]byte {
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+	return data
+}
+
+// referenceRoot is the MTH of RFC 9162 2.1.1.
+func referenceRoot(data [][]byte) [sm3.Size]byte {
+	switch len(data) {
+	case 0:
+		return sm3.Sum(nil)
+	case 1:
+		return LeafHash(data[0])
+	}
+	k := split(uint64(len(data)))
+	return NodeHash(referenceRoot(data[:k]), referenceRoot(data[k:]))
+}
+
+func TestKnownRoots(t *testing.T) {
+	tests := []struct {
+		size uint64
+		want string
+	}{
+		{0, "1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"},
+		{1, "2daef60e7a0b8f5e024c81cd2ab3109f2b4f155cf83adeb2ae5532f74a157fdf"},
+		{3, "209ec96a210d662a964772680e8544d18cab7b88ffec3e00962220349b56ea56"},
+		{7, "bd36c22a1ac6ff4308e0c3cc1a85bf0ffa30538ec60a55c70413ab15d45db4d2"},
+		{8, "bc48ba7a709184b5f2a631e1adeb8dc2a0d4c018c1d6cc89b5664fe154c93b38"},
+	}
+	tree := New()
+	for _, leaf := range testLeaves {
+		tree.Append(decodeHex(t, leaf))
+	}
+	for _, tt := range tests {
+		root, err := tree.RootAt(tt.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(root[:]); got != tt.want {
+			t.Errorf("size %d: root = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestAppendBatch(t *testing.T) {
+	data := testData(70)
+	for _, split := range []int{0, 1, 5, 8, 33, 70} {
+		tree := New()
+		for _, d := range data[:split] {
+			tree.Append(d)
+		}
+		if index := tree.AppendBatch(data[split:]); index != uint64(split) {
+			t.Errorf("split %d: index = %d", split, index)
+		}
+		single := New()
+		for _, d := range data {
+			single.Append(d)
+		}
+		for size := uint64(0); size <= uint64(len(data)); size++ {
+			got, _ := tree.RootAt(size)
+			want, _ := single.RootAt(size)
+			if got != want || got != referenceRoot(data[:size]) {
+				t.Errorf("split %d: root of size %d mismatch", split, size)
+			}
+		}
+	}
+	if RootHash(data) != referenceRoot(data) {
+		t.Errorf("RootHash mismatch")
+	}
+}
+
+func TestTreeHash(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	leaves := [][]byte{data[:300], data[300:600], data[600:900], data[900:]}
+	if TreeHash(data, 300) != referenceRoot(leaves) {
+		t.Errorf("TreeHash mismatch")
+	}
+	if TreeHash(nil, 300) != sm3.Sum(nil) {
+		t.Errorf("TreeHash of empty data mismatch")
+	}
+}
+
+func TestInclusionProof(t *testing.T) {
+	data := testData(40)
+	tree := New()
+	tree.AppendBatch(data)
+	for size := uint64(1); size <= tree.Size(); size++ {
+		root, _ := tree.RootAt(size)
+		for index := uint64(0); index < size; index++ {
+			proof, err := tree.InclusionProof(index, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaf := LeafHash(data[index])
+			if err := VerifyInclusion(index, size, leaf, proof, root); err != nil {
+				t.Fatalf("index %d, size %d: %v", index, size, err)
+			}
+			if err := VerifyInclusion(index^1, size, leaf, proof, root); err == nil && index^1 < size {
+				t.Errorf("index %d, size %d: wrong index verified", index, size)
+			}
+			if len(proof) > 0 {
+				if err := VerifyInclusion(index, size, leaf, proof[:len(proof)-1], root); err == nil {
+					t.Errorf("index %d, size %d: truncated proof verified", index, size)
+				}
+				if err := VerifyInclusion(index, size, leaf, append(proof, root), root); err == nil {
+					t.Errorf("index %d, size %d: extended proof verified", index, size)
+				}
+			}
+			leaf[0] ^= 1
+			if err := VerifyInclusion(index, size, leaf, proof, root); err == nil {
+				t.Errorf("index %d, size %d: wrong leaf verified", index, size)
+			}
+		}
+	}
+	if _, err := tree.InclusionProof(3, 3); err == nil {
+		t.Error("expected error for index out of range")
+	}
+	if _, err := tree.InclusionProof(0, 41); err == nil {
+		t.Error("expected error for size out of range")
+	}
+}
+
+func TestConsistencyProof(t *testing.T) {
+	tree := New()
+	tree.AppendBatch(testData(40))
+	for size2 := uint64(0); size2 <= tree.Size(); size2++ {
+		root2, _ := tree.RootAt(size2)
+		for size1 := uint64(0); size1 <= size2; size1++ {
+			root1, _ := tree.RootAt(size1)
+			proof, err := tree.ConsistencyProof(size1, size2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyConsistency(size1, size2, proof, root1, root2); err != nil {
+				t.Fatalf("sizes %d, %d: %v", size1, size2, err)
+			}
+			if size1 == 0 || size1 == size2 {
+				continue
+			}
+			if err := VerifyConsistency(size1, size2, proof[:len(proof)-1], root1, root2); err == nil {
+				t.Errorf("sizes %d, %d: truncated proof verified", size1, size2)
+			}
+			if err := VerifyConsistency(size1, size2, append(proof, root1), root1, root2); err == nil {
+				t.Errorf("sizes %d, %d: extended proof verified", size1, size2)
+			}
+			bad := root1
+			bad[0] ^= 1
+			if err := VerifyConsistency(size1, size2, proof, bad, root2); err == nil {
+				t.Errorf("sizes %d, %d: wrong first root verified", size1, size2)
+			}
+			if err := VerifyConsistency(size1, size2, proof, root1, bad); err == nil {
+				t.Errorf("sizes %d, %d: wrong second root verified", size1, size2)
+			}
+		}
+	}
+	if _, err := tree.ConsistencyProof(5, 4); err == nil {
+		t.Error("expected error for first size larger than second")
+	}
+	if _, err := tree.ConsistencyProof(5, 41); err == nil {
+		t.Error("expected error for size out of range")
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	data := testData(1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree := New()
+		for _, d := range data {
+			tree.Append(d)
+		}
+	}
+}
+
+func BenchmarkAppendBatch(b *testing.B) {
+	data := testData(1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New().AppendBatch(data)
+	}
+}
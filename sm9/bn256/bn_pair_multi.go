@@ -0,0 +1,110 @@
+package bn256
+
+// millerMulti computes the product of the Miller loops of the pairs (qs[i], ps[i]).
+// The loops run in parallel on one accumulator, so the squarings of the accumulator are
+// shared by all pairs. The line functions of a pair with a point at infinity are replaced
+// by 1 in constant time, that is, the pair contributes 1 to the product as in pairing.
+func millerMulti(qs []*twistPoint, ps []*curvePoint) *gfP12 {
+	n := len(qs)
+	ret := (&gfP12{}).SetOne()
+
+	aAffine := make([]twistPoint, n)
+	minusA := make([]twistPoint, n)
+	bAffine := make([]curvePoint, n)
+	r2 := make([]gfP2, n)
+	r := make([]*twistPoint, n)
+	newR := make([]*twistPoint, n)
+	infinity := make([]int, n)
+	for j := 0; j < n; j++ {
+		if qs[j].IsInfinity() || ps[j].IsInfinity() {
+			infinity[j] = 1
+		}
+		aAffine[j].Set(qs[j])
+		aAffine[j].MakeAffine()
+		minusA[j].Neg(&aAffine[j])
+		bAffine[j].Set(ps[j])
+		bAffine[j].MakeAffine()
+		r[j] = &twistPoint{}
+		r[j].Set(&aAffine[j])
+		newR[j] = &twistPoint{}
+		r2[j].Square(&aAffine[j].y)
+	}
+
+	a, b, c := &gfP2{}, &gfP2{}, &gfP2{}
+	lineOne, lineZero := (&gfP2{}).SetOne(), &gfP2{}
+	// mulLineAndSwap multiplies the line function of the j-th pair and moves to the next point.
+	mulLineAndSwap := func(j int) {
+		a.Select(lineOne, a, infinity[j])
+		b.Select(lineZero, b, infinity[j])
+		c.Select(lineZero, c, infinity[j])
+		mulLine(ret, a, b, c)
+		r[j], newR[j] = newR[j], r[j]
+	}
+	for i := len(sixUPlus2NAF) - 1; i > 0; i-- {
+		if i != len(sixUPlus2NAF)-1 {
+			ret.Square(ret)
+		}
+		for j := 0; j < n; j++ {
+			lineFunctionDouble(r[j], newR[j], &bAffine[j], a, b, c)
+			mulLineAndSwap(j)
+		}
+		switch sixUPlus2NAF[i-1] {
+		case 1:
+			for j := 0; j < n; j++ {
+				lineFunctionAdd(r[j], &aAffine[j], newR[j], &bAffine[j], &r2[j], a, b, c)
+				mulLineAndSwap(j)
+			}
+		case -1:
+			for j := 0; j < n; j++ {
+				lineFunctionAdd(r[j], &minusA[j], newR[j], &bAffine[j], &r2[j], a, b, c)
+				mulLineAndSwap(j)
+			}
+		}
+	}
+
+	// See miller for the computation of Q1 and -Q2.
+	q1, minusQ2 := &twistPoint{}, &twistPoint{}
+	for j := 0; j < n; j++ {
+		q1.x.Conjugate(&aAffine[j].x)
+		q1.x.MulScalar(&q1.x, betaToNegPPlus1Over3)
+		q1.y.Conjugate(&aAffine[j].y)
+		q1.y.MulScalar(&q1.y, betaToNegPPlus1Over2)
+		q1.z.SetOne()
+		q1.t.SetOne()
+
+		minusQ2.x.Set(&aAffine[j].x)
+		minusQ2.x.MulScalar(&minusQ2.x, betaToNegP2Plus1Over3)
+		minusQ2.y.Neg(&aAffine[j].y)
+		minusQ2.y.MulScalar(&minusQ2.y, betaToNegP2Plus1Over2)
+		minusQ2.z.SetOne()
+		minusQ2.t.SetOne()
+
+		r2[j].Square(&q1.y)
+		lineFunctionAdd(r[j], q1, newR[j], &bAffine[j], &r2[j], a, b, c)
+		mulLineAndSwap(j)
+
+		r2[j].Square(&minusQ2.y)
+		lineFunctionAdd(r[j], minusQ2, newR[j], &bAffine[j], &r2[j], a, b, c)
+		mulLineAndSwap(j)
+	}
+	return ret
+}
+
+// MultiPair calculates the product of R-Ate pairings Pair(g1[0], g2[0]) * ... * Pair(g1[n-1], g2[n-1]).
+// The Miller loops share the squarings and there is only one final exponentiation, so it's
+// much faster than multiplying the results of Pair. The pairs with a point at infinity
+// contribute 1 to the product, just like Pair.
+//
+// It panics if the lengths of g1 and g2 are different.
+func MultiPair(g1 []*G1, g2 []*G2) *GT {
+	if len(g1) != len(g2) {
+		panic("sm9: MultiPair called with different number of G1 and G2 points")
+	}
+	ps := make([]*curvePoint, len(g1))
+	qs := make([]*twistPoint, len(g2))
+	for i := range g1 {
+		ps[i] = g1[i].p
+		qs[i] = g2[i].p
+	}
+	return &GT{finalExponentiation(millerMulti(qs, ps))}
+}
@@ -0,0 +1,67 @@
+package bn256
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestMultiPair(t *testing.T) {
+	for n := 0; n < 5; n++ {
+		g1s := make([]*G1, n)
+		g2s := make([]*G2, n)
+		expected := new(GT).SetOne()
+		for i := 0; i < n; i++ {
+			var err error
+			if _, g1s[i], err = RandomG1(rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+			if _, g2s[i], err = RandomG2(rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+			expected.Add(expected, Pair(g1s[i], g2s[i]))
+		}
+		if got := MultiPair(g1s, g2s); *got.p != *expected.p {
+			t.Errorf("%d pairs: MultiPair mismatch", n)
+		}
+	}
+}
+
+func TestMultiPairCheck(t *testing.T) {
+	// e(aP1, P2) * e(-P1, aP2) = 1
+	k, g1, err := RandomG1(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := new(G2).ScalarBaseMult(NormalizeScalar(k.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	minusGen1 := new(G1).Neg(Gen1)
+	if got := MultiPair([]*G1{g1, minusGen1}, []*G2{Gen2, g2}); !got.p.IsOne() {
+		t.Errorf("expected one")
+	}
+}
+
+func TestMultiPairInfinity(t *testing.T) {
+	g1 := new(G1)
+	g1.p = &curvePoint{}
+	g1.p.SetInfinity()
+	g2 := new(G2)
+	g2.p = &twistPoint{}
+	g2.p.SetInfinity()
+	expected := Pair(Gen1, Gen2)
+	got := MultiPair([]*G1{g1, Gen1, Gen1}, []*G2{Gen2, Gen2, g2})
+	if *got.p != *expected.p {
+		t.Errorf("pairs with infinity should contribute one")
+	}
+}
+
+func BenchmarkMultiPair(b *testing.B) {
+	g1s := []*G1{Gen1, Gen1, Gen1, Gen1}
+	g2s := []*G2{Gen2, Gen2, Gen2, Gen2}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MultiPair(g1s, g2s)
+	}
+}
@@ -0,0 +1,136 @@
+package bn256
+
+// lineCoeffs is a line function whose b, c are not yet multiplied by the
+// coordinates of the G1 point, see lineFunctionDouble and lineFunctionAdd.
+type lineCoeffs struct {
+	a, b, c gfP2
+}
+
+// G2Prepared holds the precomputed line functions of the Miller loop for a G2 point.
+// The line functions only depend on the G2 point, so pairings of a fixed G2 point
+// (such as the SM9 user sign public key) with many G1 points can skip all the
+// twist point arithmetic.
+type G2Prepared struct {
+	coeffs   []lineCoeffs
+	infinity bool
+}
+
+// PrepareG2 precomputes the line functions of the Miller loop for g2.
+func PrepareG2(g2 *G2) *G2Prepared {
+	prepared := &G2Prepared{infinity: g2.p.IsInfinity()}
+	if prepared.infinity {
+		return prepared
+	}
+	// The coefficients b and c are multiplied by the coordinates of this
+	// "point" (1, 1), that is, they are kept as is.
+	unit := &curvePoint{}
+	unit.x.Set(one)
+	unit.y.Set(one)
+
+	aAffine := &twistPoint{}
+	aAffine.Set(g2.p)
+	aAffine.MakeAffine()
+
+	minusA := &twistPoint{}
+	minusA.Neg(aAffine)
+
+	r := &twistPoint{}
+	r.Set(aAffine)
+
+	r2 := (&gfP2{}).Square(&aAffine.y)
+
+	newR := &twistPoint{}
+	record := func() *lineCoeffs {
+		prepared.coeffs = append(prepared.coeffs, lineCoeffs{})
+		return &prepared.coeffs[len(prepared.coeffs)-1]
+	}
+	for i := len(sixUPlus2NAF) - 1; i > 0; i-- {
+		l := record()
+		lineFunctionDouble(r, newR, unit, &l.a, &l.b, &l.c)
+		r, newR = newR, r
+		switch sixUPlus2NAF[i-1] {
+		case 1:
+			l = record()
+			lineFunctionAdd(r, aAffine, newR, unit, r2, &l.a, &l.b, &l.c)
+		case -1:
+			l = record()
+			lineFunctionAdd(r, minusA, newR, unit, r2, &l.a, &l.b, &l.c)
+		default:
+			continue
+		}
+		r, newR = newR, r
+	}
+
+	// See miller for the computation of Q1 and -Q2.
+	q1 := &twistPoint{}
+	q1.x.Conjugate(&aAffine.x)
+	q1.x.MulScalar(&q1.x, betaToNegPPlus1Over3)
+	q1.y.Conjugate(&aAffine.y)
+	q1.y.MulScalar(&q1.y, betaToNegPPlus1Over2)
+	q1.z.SetOne()
+	q1.t.SetOne()
+
+	minusQ2 := &twistPoint{}
+	minusQ2.x.Set(&aAffine.x)
+	minusQ2.x.MulScalar(&minusQ2.x, betaToNegP2Plus1Over3)
+	minusQ2.y.Neg(&aAffine.y)
+	minusQ2.y.MulScalar(&minusQ2.y, betaToNegP2Plus1Over2)
+	minusQ2.z.SetOne()
+	minusQ2.t.SetOne()
+
+	r2.Square(&q1.y)
+	l := record()
+	lineFunctionAdd(r, q1, newR, unit, r2, &l.a, &l.b, &l.c)
+	r, newR = newR, r
+
+	r2.Square(&minusQ2.y)
+	l = record()
+	lineFunctionAdd(r, minusQ2, newR, unit, r2, &l.a, &l.b, &l.c)
+
+	return prepared
+}
+
+// millerPrepared is the same as miller, with the precomputed line functions of q.
+func millerPrepared(q *G2Prepared, p *curvePoint) *gfP12 {
+	ret := (&gfP12{}).SetOne()
+
+	bAffine := &curvePoint{}
+	bAffine.Set(p)
+	bAffine.MakeAffine()
+
+	a, b, c := &gfP2{}, &gfP2{}, &gfP2{}
+	coeffs := q.coeffs
+	mulPreparedLine := func() {
+		l := &coeffs[0]
+		coeffs = coeffs[1:]
+		a.Set(&l.a)
+		b.MulScalar(&l.b, &bAffine.x)
+		c.MulScalar(&l.c, &bAffine.y)
+		mulLine(ret, a, b, c)
+	}
+	for i := len(sixUPlus2NAF) - 1; i > 0; i-- {
+		if i != len(sixUPlus2NAF)-1 {
+			ret.Square(ret)
+		}
+		mulPreparedLine()
+		if sixUPlus2NAF[i-1] != 0 {
+			mulPreparedLine()
+		}
+	}
+	mulPreparedLine()
+	mulPreparedLine()
+	return ret
+}
+
+// PairPrepared calculates an R-Ate pairing with the prepared G2 point,
+// PairPrepared(g1, PrepareG2(g2)) is equivalent to Pair(g1, g2).
+func PairPrepared(g1 *G1, g2 *G2Prepared) *GT {
+	if g2.infinity {
+		return &GT{(&gfP12{}).SetOne()}
+	}
+	ret := finalExponentiation(millerPrepared(g2, g1.p))
+	if g1.p.IsInfinity() {
+		ret.SetOne()
+	}
+	return &GT{ret}
+}
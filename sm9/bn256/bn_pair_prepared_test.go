@@ -0,0 +1,54 @@
+package bn256
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestPairPrepared(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		_, g1, err := RandomG1(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, g2, err := RandomG2(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prepared := PrepareG2(g2)
+		expected := Pair(g1, g2)
+		if got := PairPrepared(g1, prepared); *got.p != *expected.p {
+			t.Errorf("PairPrepared mismatch")
+		}
+		// the prepared point can be reused
+		_, g1, _ = RandomG1(rand.Reader)
+		expected = Pair(g1, g2)
+		if got := PairPrepared(g1, prepared); *got.p != *expected.p {
+			t.Errorf("PairPrepared mismatch when reused")
+		}
+	}
+}
+
+func TestPairPreparedInfinity(t *testing.T) {
+	g1 := new(G1)
+	g1.p = &curvePoint{}
+	g1.p.SetInfinity()
+	if got := PairPrepared(g1, PrepareG2(Gen2)); !got.p.IsOne() {
+		t.Errorf("expected one for G1 infinity")
+	}
+	g2 := new(G2)
+	g2.p = &twistPoint{}
+	g2.p.SetInfinity()
+	if got := PairPrepared(Gen1, PrepareG2(g2)); !got.p.IsOne() {
+		t.Errorf("expected one for G2 infinity")
+	}
+}
+
+func BenchmarkPairPrepared(b *testing.B) {
+	prepared := PrepareG2(Gen2)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PairPrepared(Gen1, prepared)
+	}
+}
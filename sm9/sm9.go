@@ -10,6 +10,7 @@ import (
 	"math/big"
 
 	"github.com/emmansun/gmsm/internal/bigmod"
+	"github.com/emmansun/gmsm/internal/keyexchange"
 	"github.com/emmansun/gmsm/internal/randutil"
 	"github.com/emmansun/gmsm/internal/subtle"
 	"github.com/emmansun/gmsm/kdf"
@@ -137,6 +138,10 @@ func Sign(rand io.Reader, priv *SignPrivateKey, hash []byte) (h *big.Int, s *bn2
 // is not currently used but, in keeping with the crypto.Signer interface.
 // The result is SM9Signature ASN.1 format.
 //
+// SignPrivateKey doesn't implement crypto.Signer, it has no public key of its own: the
+// signatures are verified with the master public key (see MasterPublic) and the signer's
+// identity (uid and hid).
+//
 // The signature is randomized. Most applications should use [crypto/rand.Reader]
 // as rand. Note that the returned signature does not depend deterministically on
 // the bytes read from rand, and may change between calls and/or between versions.
@@ -244,39 +249,11 @@ func parseSignatureLegacy(sig []byte) (*big.Int, *bn256.G1, error) {
 // VerifyASN1 verifies the ASN.1 encoded signature of type SM9Signature, sig, of hash using the
 // public key, pub. Its return value records whether the signature is valid.
 func VerifyASN1(pub *SignMasterPublicKey, uid []byte, hid byte, hash, sig []byte) bool {
-	h, s, err := parseSignature(sig)
-	if err != nil {
-		return false
-	}
-	if !s.IsOnCurve() {
-		return false
-	}
-
-	hNat, err := bigmod.NewNat().SetBytes(h, orderNat)
-	if err != nil {
-		return false
-	}
-	if hNat.IsZero() == 1 {
-		return false
-	}
-
-	t, err := pub.ScalarBaseMult(hNat.Bytes(orderNat))
-	if err != nil {
-		return false
-	}
-
-	// user sign public key p generation
-	p := pub.GenerateUserPublicKey(uid, hid)
-
-	u := bn256.Pair(s, p)
-	w := new(bn256.GT).Add(u, t)
-
-	var buffer []byte
-	buffer = append(buffer, hash...)
-	buffer = append(buffer, w.Marshal()...)
-	h2 := hashH2(buffer)
-
-	return h2.Equal(hNat) == 1
+	return verifySignature(pub, hash, sig, func(s *bn256.G1) *bn256.GT {
+		// user sign public key p generation
+		p := pub.GenerateUserPublicKey(uid, hid)
+		return bn256.Pair(s, p)
+	})
 }
 
 // Verify verifies the ASN.1 encoded signature, sig, of hash using the
@@ -595,6 +572,9 @@ type KeyExchange struct {
 	g1           *bn256.GT          // internal state which will be used when compute the key and signature
 	g2           *bn256.GT          // internal state which will be used when compute the key and signature
 	g3           *bn256.GT          // internal state which will be used when compute the key and signature
+	state        keyexchange.State  // current step of the key exchange flow
+	isResponder  bool               // whether the owner is the responder
+	responderSig []byte             // the optional signature of the responder
 }
 
 // NewKeyExchange creates one new KeyExchange object
@@ -622,6 +602,7 @@ func (ke *KeyExchange) Destroy() {
 	if ke.g3 != nil {
 		ke.g3.SetOne()
 	}
+	ke.state = keyexchange.StateClosed
 }
 
 func initKeyExchange(ke *KeyExchange, hid byte, r *bigmod.Nat) {
@@ -632,10 +613,14 @@ func initKeyExchange(ke *KeyExchange, hid byte, r *bigmod.Nat) {
 		panic(err)
 	}
 	ke.secret = rA
+	ke.state = keyexchange.StateInitiated
 }
 
 // InitKeyExchange generates random with responder uid, for initiator's step A1-A4
 func (ke *KeyExchange) InitKeyExchange(rand io.Reader, hid byte) (*bn256.G1, error) {
+	if ke.state != keyexchange.StateNew {
+		return nil, ErrKeyExchangeOutOfOrder
+	}
 	r, err := randomScalar(rand)
 	if err != nil {
 		return nil, err
@@ -689,7 +674,9 @@ func (ke *KeyExchange) generateSharedKey(isResponder bool) ([]byte, error) {
 }
 
 func respondKeyExchange(ke *KeyExchange, hid byte, r *bigmod.Nat, rA *bn256.G1) (*bn256.G1, []byte, error) {
+	ke.isResponder = true
 	if !rA.IsOnCurve() {
+		ke.state = keyexchange.StateClosed
 		return nil, nil, errors.New("sm9: invalid initiator's ephemeral public key")
 	}
 	ke.peerSecret = rA
@@ -716,15 +703,19 @@ func respondKeyExchange(ke *KeyExchange, hid byte, r *bigmod.Nat, rA *bn256.G1)
 	}
 	ke.g2 = g2
 
+	ke.state = keyexchange.StateResponded
 	if !ke.genSignature {
 		return ke.secret, nil, nil
 	}
-
-	return ke.secret, ke.sign(true, 0x82), nil
+	ke.responderSig = ke.sign(true, 0x82)
+	return ke.secret, ke.responderSig, nil
 }
 
 // RepondKeyExchange when responder receive rA, for responder's step B1-B7
 func (ke *KeyExchange) RepondKeyExchange(rand io.Reader, hid byte, rA *bn256.G1) (*bn256.G1, []byte, error) {
+	if ke.state != keyexchange.StateNew {
+		return nil, nil, ErrKeyExchangeOutOfOrder
+	}
 	r, err := randomScalar(rand)
 	if err != nil {
 		return nil, nil, err
@@ -734,7 +725,11 @@ func (ke *KeyExchange) RepondKeyExchange(rand io.Reader, hid byte, rA *bn256.G1)
 
 // ConfirmResponder for initiator's step A5-A7
 func (ke *KeyExchange) ConfirmResponder(rB *bn256.G1, sB []byte) ([]byte, []byte, error) {
+	if ke.state != keyexchange.StateInitiated {
+		return nil, nil, ErrKeyExchangeOutOfOrder
+	}
 	if !rB.IsOnCurve() {
+		ke.state = keyexchange.StateClosed
 		return nil, nil, errors.New("sm9: invalid responder's ephemeral public key")
 	}
 	// step 5
@@ -755,13 +750,16 @@ func (ke *KeyExchange) ConfirmResponder(rB *bn256.G1, sB []byte) ([]byte, []byte
 	if len(sB) > 0 {
 		signature := ke.sign(false, 0x82)
 		if goSubtle.ConstantTimeCompare(signature, sB) != 1 {
+			ke.state = keyexchange.StateClosed
 			return nil, nil, errors.New("sm9: invalid responder's signature")
 		}
+		ke.responderSig = append([]byte{}, sB...)
 	}
 	key, err := ke.generateSharedKey(false)
 	if err != nil {
 		return nil, nil, err
 	}
+	ke.state = keyexchange.StateCompleted
 	if !ke.genSignature {
 		return key, nil, nil
 	}
@@ -770,11 +768,20 @@ func (ke *KeyExchange) ConfirmResponder(rB *bn256.G1, sB []byte) ([]byte, []byte
 
 // ConfirmInitiator for responder's step B8
 func (ke *KeyExchange) ConfirmInitiator(s1 []byte) ([]byte, error) {
+	if ke.state != keyexchange.StateResponded {
+		return nil, ErrKeyExchangeOutOfOrder
+	}
 	if s1 != nil {
 		buffer := ke.sign(true, 0x83)
 		if goSubtle.ConstantTimeCompare(buffer, s1) != 1 {
+			ke.state = keyexchange.StateClosed
 			return nil, errors.New("sm9: invalid initiator's signature")
 		}
 	}
-	return ke.generateSharedKey(true)
+	key, err := ke.generateSharedKey(true)
+	if err != nil {
+		return nil, err
+	}
+	ke.state = keyexchange.StateCompleted
+	return key, nil
 }
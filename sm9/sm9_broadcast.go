@@ -0,0 +1,204 @@
+package sm9
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/internal/subtle"
+	"github.com/emmansun/gmsm/sm3"
+	"github.com/emmansun/gmsm/sm4"
+	"github.com/emmansun/gmsm/sm9/bn256"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// This file contains SM9 multi-recipient (broadcast) encryption, the payload is encrypted
+// once with SM4-GCM under a random content key, and the content key is wrapped for each
+// recipient with WrapKey:
+//
+//	(Ki, Ci) = WrapKey(IDi, hid), encryptedKeyi = CEK xor Ki
+//
+// SM9BroadcastCipher ::= SEQUENCE {
+//   recipients SEQUENCE OF SEQUENCE {
+//     tag          OCTET STRING, -- first 8 bytes of SM3(IDi), to find the entry of a recipient
+//     C            BIT STRING,   -- the cipher of WrapKey, uncompressed G1 point
+//     encryptedKey OCTET STRING
+//   },
+//   nonce      OCTET STRING,
+//   ciphertext OCTET STRING     -- SM4-GCM, the DER of recipients is the additional data
+// }
+//
+// The list of recipients is authenticated by the GCM tag, so no recipient can be removed or
+// replaced without being detected. The tags are hashes of the uids, which hide the uids from
+// casual inspection only, they don't provide recipient anonymity.
+
+const (
+	broadcastKeySize = 16
+	broadcastTagSize = 8
+)
+
+func broadcastTag(uid []byte) []byte {
+	h := sm3.Sum(uid)
+	return h[:broadcastTagSize]
+}
+
+// EncryptBroadcast encrypts plaintext once for all the uids, any private key of the listed
+// identities (with the system hid) can decrypt it. It returns the ciphertext in ASN.1 format,
+// SM9BroadcastCipher definition.
+//
+// The rand parameter is used as a source of entropy. Most applications should use
+// [crypto/rand.Reader] as rand.
+func EncryptBroadcast(rand io.Reader, pub *EncryptMasterPublicKey, uids [][]byte, hid byte, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, ErrEmptyPlaintext
+	}
+	if len(uids) == 0 {
+		return nil, errors.New("sm9: no recipients")
+	}
+	seen := make(map[string]bool, len(uids))
+	for _, uid := range uids {
+		if seen[string(uid)] {
+			return nil, errors.New("sm9: duplicate recipient")
+		}
+		seen[string(uid)] = true
+	}
+
+	cek := make([]byte, broadcastKeySize)
+	if _, err := io.ReadFull(rand, cek); err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	for _, uid := range uids {
+		key, c, err := WrapKey(rand, pub, uid, hid, broadcastKeySize)
+		if err != nil {
+			return nil, err
+		}
+		subtle.XORBytes(key, key, cek)
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1OctetString(broadcastTag(uid))
+			b.AddASN1BitString(c.MarshalUncompressed())
+			b.AddASN1OctetString(key)
+		})
+	}
+	var recipients cryptobyte.Builder
+	recipients.AddASN1(asn1.SEQUENCE, func(r *cryptobyte.Builder) {
+		r.AddBytes(b.BytesOrPanic())
+	})
+	recipientsBytes, err := recipients.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newBroadcastAEAD(cek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand, nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, recipientsBytes)
+
+	var out cryptobyte.Builder
+	out.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddBytes(recipientsBytes)
+		b.AddASN1OctetString(nonce)
+		b.AddASN1OctetString(ciphertext)
+	})
+	return out.Bytes()
+}
+
+func newBroadcastAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type broadcastRecipient struct {
+	tag, encryptedKey []byte
+	c                 *bn256.G1
+}
+
+func parseBroadcastCipher(ciphertext []byte) (recipientsBytes []byte, recipients []broadcastRecipient, nonce, sealed []byte, err error) {
+	var inner, list cryptobyte.String
+	input := cryptobyte.String(ciphertext)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Element((*cryptobyte.String)(&recipientsBytes), asn1.SEQUENCE) ||
+		!inner.ReadASN1Bytes(&nonce, asn1.OCTET_STRING) ||
+		!inner.ReadASN1Bytes(&sealed, asn1.OCTET_STRING) ||
+		!inner.Empty() {
+		return nil, nil, nil, nil, errors.New("sm9: invalid broadcast ciphertext asn.1 data")
+	}
+	entries := cryptobyte.String(recipientsBytes)
+	if !entries.ReadASN1(&list, asn1.SEQUENCE) {
+		return nil, nil, nil, nil, errors.New("sm9: invalid broadcast ciphertext asn.1 data")
+	}
+	for !list.Empty() {
+		var (
+			entry  cryptobyte.String
+			r      broadcastRecipient
+			cBytes []byte
+		)
+		if !list.ReadASN1(&entry, asn1.SEQUENCE) ||
+			!entry.ReadASN1Bytes(&r.tag, asn1.OCTET_STRING) ||
+			!entry.ReadASN1BitStringAsBytes(&cBytes) ||
+			!entry.ReadASN1Bytes(&r.encryptedKey, asn1.OCTET_STRING) ||
+			!entry.Empty() ||
+			len(r.encryptedKey) != broadcastKeySize || len(cBytes) == 0 {
+			return nil, nil, nil, nil, errors.New("sm9: invalid broadcast ciphertext asn.1 data")
+		}
+		if r.c, err = unmarshalG1(cBytes); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	return recipientsBytes, recipients, nonce, sealed, nil
+}
+
+// DecryptBroadcast decrypts the ciphertext of EncryptBroadcast, in ASN.1 format SM9BroadcastCipher
+// definition, with the private key of uid.
+func DecryptBroadcast(priv *EncryptPrivateKey, uid, ciphertext []byte) ([]byte, error) {
+	recipientsBytes, recipients, nonce, sealed, err := parseBroadcastCipher(ciphertext)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	tag := broadcastTag(uid)
+	for _, r := range recipients {
+		if string(r.tag) != string(tag) {
+			continue
+		}
+		key, err := UnwrapKey(priv, uid, r.c, broadcastKeySize)
+		if err != nil {
+			continue
+		}
+		subtle.XORBytes(key, key, r.encryptedKey)
+		aead, err := newBroadcastAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(nonce) != aead.NonceSize() {
+			return nil, ErrDecryption
+		}
+		// tags may collide, try the next entry on failure.
+		if plaintext, err := aead.Open(nil, nonce, sealed, recipientsBytes); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrDecryption
+}
+
+// EncryptBroadcast encrypts plaintext once for all the uids, returns ciphertext in ASN.1 format,
+// SM9BroadcastCipher definition. See EncryptBroadcast.
+func (pub *EncryptMasterPublicKey) EncryptBroadcast(rand io.Reader, uids [][]byte, hid byte, plaintext []byte) ([]byte, error) {
+	return EncryptBroadcast(rand, pub, uids, hid, plaintext)
+}
+
+// DecryptBroadcast decrypts the ciphertext in ASN.1 format, SM9BroadcastCipher definition, with
+// the private key of uid.
+func (priv *EncryptPrivateKey) DecryptBroadcast(uid, ciphertext []byte) ([]byte, error) {
+	return DecryptBroadcast(priv, uid, ciphertext)
+}
@@ -0,0 +1,136 @@
+package sm9
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+func TestBroadcastEncryptDecrypt(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	uids := [][]byte{[]byte("Alice"), []byte("Bob"), []byte("Carol")}
+	plaintext := []byte("Chinese IBE standard")
+	ciphertext, err := masterKey.Public().EncryptBroadcast(rand.Reader, uids, hid, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uid := range uids {
+		userKey, err := masterKey.GenerateUserKey(uid, hid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := userKey.DecryptBroadcast(uid, ciphertext)
+		if err != nil {
+			t.Fatalf("%s: %v", uid, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("%s: decrypted plaintext mismatch", uid)
+		}
+	}
+
+	outsider, err := masterKey.GenerateUserKey([]byte("Dave"), hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outsider.DecryptBroadcast([]byte("Dave"), ciphertext); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+	// the private key of another identity
+	if _, err := outsider.DecryptBroadcast(uids[0], ciphertext); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+}
+
+func TestBroadcastTampered(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	uids := [][]byte{[]byte("Alice"), []byte("Bob")}
+	ciphertext, err := EncryptBroadcast(rand.Reader, masterKey.Public(), uids, hid, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey, err := masterKey.GenerateUserKey(uids[1], hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the first recipient, the additional data no longer matches.
+	recipientsBytes, recipients, nonce, sealed, err := parseBroadcastCipher(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 2 {
+		t.Fatalf("expected 2 recipients, got %v", len(recipients))
+	}
+	var list cryptobyte.String
+	entries := cryptobyte.String(recipientsBytes)
+	entries.ReadASN1(&list, asn1.SEQUENCE)
+	var first cryptobyte.String
+	list.ReadASN1Element(&first, asn1.SEQUENCE)
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddBytes(list)
+		})
+		b.AddASN1OctetString(nonce)
+		b.AddASN1OctetString(sealed)
+	})
+	if _, err := DecryptBroadcast(userKey, uids[1], b.BytesOrPanic()); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := DecryptBroadcast(userKey, uids[1], tampered); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+	if _, err := DecryptBroadcast(userKey, uids[1], ciphertext[:len(ciphertext)-1]); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+}
+
+func TestBroadcastInvalidInput(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := masterKey.Public()
+	if _, err := EncryptBroadcast(rand.Reader, pub, [][]byte{[]byte("Alice")}, 0x01, nil); err != ErrEmptyPlaintext {
+		t.Errorf("expected ErrEmptyPlaintext, got %v", err)
+	}
+	if _, err := EncryptBroadcast(rand.Reader, pub, nil, 0x01, []byte("hello")); err == nil {
+		t.Errorf("expected error for no recipients")
+	}
+	if _, err := EncryptBroadcast(rand.Reader, pub, [][]byte{[]byte("Alice"), []byte("Alice")}, 0x01, []byte("hello")); err == nil {
+		t.Errorf("expected error for duplicate recipients")
+	}
+}
+
+func BenchmarkEncryptBroadcast(b *testing.B) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	uids := make([][]byte, 10)
+	for i := range uids {
+		uids[i] = []byte{'u', byte('0' + i)}
+	}
+	plaintext := make([]byte, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := EncryptBroadcast(rand.Reader, masterKey.Public(), uids, 0x01, plaintext); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package sm9
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/emmansun/gmsm/internal/keyexchange"
+	"github.com/emmansun/gmsm/sm9/bn256"
+)
+
+// ErrKeyExchangeOutOfOrder is returned when a method of KeyExchange is called out of order,
+// or called more than once.
+//
+// The state check only rejects a message repeated within one KeyExchange. A message replayed
+// from another session is rejected by the key confirmation, the signatures SB and SA cover the
+// ephemeral public keys of both parties, so ConfirmResponder or ConfirmInitiator fails. Without
+// genSignature there is no key confirmation, the upper layer protocol must confirm the key.
+var ErrKeyExchangeOutOfOrder = errors.New("sm9: key exchange method called out of order")
+
+// KeyExchangeInit is the message sent by the initiator, the result of InitKeyExchange.
+type KeyExchangeInit struct {
+	RA *bn256.G1 // initiator's ephemeral public key
+}
+
+// KeyExchangeResponse is the message sent by the responder, the result of RepondKeyExchange.
+type KeyExchangeResponse struct {
+	RB *bn256.G1 // responder's ephemeral public key
+	SB []byte    // optional signature of the responder
+}
+
+// KeyExchangeConfirm is the message sent by the initiator, the signature returned by ConfirmResponder.
+type KeyExchangeConfirm struct {
+	SA []byte // signature of the initiator
+}
+
+// MarshalASN1 marshals the message to asn.1 format data, the points are uncompressed:
+//
+//	SM9KeyExchangeInit ::= SEQUENCE {
+//	  type INTEGER (1),
+//	  rA   BIT STRING
+//	}
+func (m *KeyExchangeInit) MarshalASN1() ([]byte, error) {
+	return keyexchange.MarshalInit(m.RA.MarshalUncompressed())
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data, SM9KeyExchangeInit definition.
+func (m *KeyExchangeInit) UnmarshalASN1(der []byte) error {
+	point, err := keyexchange.UnmarshalInit(der)
+	if err != nil {
+		return fmt.Errorf("sm9: %w", err)
+	}
+	rA, err := unmarshalG1(point)
+	if err != nil {
+		return err
+	}
+	m.RA = rA
+	return nil
+}
+
+// MarshalASN1 marshals the message to asn.1 format data, the points are uncompressed:
+//
+//	SM9KeyExchangeResponse ::= SEQUENCE {
+//	  type INTEGER (2),
+//	  rB   BIT STRING,
+//	  sB   OCTET STRING OPTIONAL
+//	}
+func (m *KeyExchangeResponse) MarshalASN1() ([]byte, error) {
+	return keyexchange.MarshalResponse(m.RB.MarshalUncompressed(), m.SB)
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data, SM9KeyExchangeResponse definition.
+func (m *KeyExchangeResponse) UnmarshalASN1(der []byte) error {
+	point, sB, err := keyexchange.UnmarshalResponse(der)
+	if err != nil {
+		return fmt.Errorf("sm9: %w", err)
+	}
+	rB, err := unmarshalG1(point)
+	if err != nil {
+		return err
+	}
+	m.RB = rB
+	m.SB = sB
+	return nil
+}
+
+// MarshalASN1 marshals the message to asn.1 format data:
+//
+//	SM9KeyExchangeConfirm ::= SEQUENCE {
+//	  type INTEGER (3),
+//	  sA   OCTET STRING
+//	}
+func (m *KeyExchangeConfirm) MarshalASN1() ([]byte, error) {
+	return keyexchange.MarshalConfirm(m.SA)
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data, SM9KeyExchangeConfirm definition.
+func (m *KeyExchangeConfirm) UnmarshalASN1(der []byte) error {
+	sA, err := keyexchange.UnmarshalConfirm(der)
+	if err != nil {
+		return fmt.Errorf("sm9: %w", err)
+	}
+	m.SA = sA
+	return nil
+}
+
+// TranscriptHash returns the SM3 hash of the key exchange transcript:
+//
+//	SM3(len(IDA) || IDA || len(IDB) || IDB || RA || RB || SB)
+//
+// where A is the initiator, B is the responder, the lengths are 4 bytes big endian, the points
+// are uncompressed and SB is the optional signature of the responder. Both parties get the same
+// value, which can be used to bind the session to the upper layer protocol.
+//
+// It's available after ConfirmResponder (initiator) or RepondKeyExchange (responder).
+func (ke *KeyExchange) TranscriptHash() ([]byte, error) {
+	if !ke.state.HasTranscript() {
+		return nil, ErrKeyExchangeOutOfOrder
+	}
+	uidA, uidB := ke.uid, ke.peerUID
+	rA, rB := ke.secret, ke.peerSecret
+	if ke.isResponder {
+		uidA, uidB = uidB, uidA
+		rA, rB = rB, rA
+	}
+	return keyexchange.TranscriptHash(uidA, uidB, rA.MarshalUncompressed(), rB.MarshalUncompressed(), ke.responderSig), nil
+}
@@ -0,0 +1,218 @@
+package sm9
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func newKeyExchangePair(t *testing.T, genSignature bool) (*KeyExchange, *KeyExchange) {
+	t.Helper()
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userA, userB := []byte("Alice"), []byte("Bob")
+	keyA, err := masterKey.GenerateUserKey(userA, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := masterKey.GenerateUserKey(userB, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKeyExchange(keyA, userA, userB, 16, genSignature), NewKeyExchange(keyB, userB, userA, 16, genSignature)
+}
+
+func TestKeyExchangeMessages(t *testing.T) {
+	for _, genSignature := range []bool{true, false} {
+		initiator, responder := newKeyExchangePair(t, genSignature)
+
+		rA, err := initiator.InitKeyExchange(rand.Reader, 0x02)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := (&KeyExchangeInit{RA: rA}).MarshalASN1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var init KeyExchangeInit
+		if err := init.UnmarshalASN1(der); err != nil {
+			t.Fatal(err)
+		}
+		if err := new(KeyExchangeResponse).UnmarshalASN1(der); err == nil {
+			t.Fatal("expected error for unexpected message type")
+		}
+
+		rB, sB, err := responder.RepondKeyExchange(rand.Reader, 0x02, init.RA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if der, err = (&KeyExchangeResponse{RB: rB, SB: sB}).MarshalASN1(); err != nil {
+			t.Fatal(err)
+		}
+		var resp KeyExchangeResponse
+		if err := resp.UnmarshalASN1(der); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resp.SB, sB) {
+			t.Fatal("signature mismatch")
+		}
+
+		key1, sA, err := initiator.ConfirmResponder(resp.RB, resp.SB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var key2 []byte
+		if genSignature {
+			if der, err = (&KeyExchangeConfirm{SA: sA}).MarshalASN1(); err != nil {
+				t.Fatal(err)
+			}
+			var confirm KeyExchangeConfirm
+			if err := confirm.UnmarshalASN1(der); err != nil {
+				t.Fatal(err)
+			}
+			key2, err = responder.ConfirmInitiator(confirm.SA)
+		} else {
+			key2, err = responder.ConfirmInitiator(nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(key1, key2) {
+			t.Fatal("got different key")
+		}
+
+		h1, err := initiator.TranscriptHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h2, err := responder.TranscriptHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(h1, h2) {
+			t.Fatal("got different transcript hash")
+		}
+	}
+}
+
+func TestKeyExchangeOutOfOrder(t *testing.T) {
+	initiator, responder := newKeyExchangePair(t, true)
+	if _, err := initiator.TranscriptHash(); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	if _, err := responder.ConfirmInitiator(nil); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	rA, err := initiator.InitKeyExchange(rand.Reader, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := initiator.InitKeyExchange(rand.Reader, 0x02); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	if _, _, err := initiator.RepondKeyExchange(rand.Reader, 0x02, rA); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	rB, sB, err := responder.RepondKeyExchange(rand.Reader, 0x02, rA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// repeated init message
+	if _, _, err := responder.RepondKeyExchange(rand.Reader, 0x02, rA); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	_, sA, err := initiator.ConfirmResponder(rB, sB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := initiator.ConfirmResponder(rB, sB); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	if _, err := responder.ConfirmInitiator(sA); err != nil {
+		t.Fatal(err)
+	}
+	// repeated confirm message
+	if _, err := responder.ConfirmInitiator(sA); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+	initiator.Destroy()
+	if _, err := initiator.TranscriptHash(); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+}
+
+func TestKeyExchangeFailureCloses(t *testing.T) {
+	initiator, responder := newKeyExchangePair(t, true)
+	rA, err := initiator.InitKeyExchange(rand.Reader, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rB, sB, err := responder.RepondKeyExchange(rand.Reader, 0x02, rA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := append([]byte{}, sB...)
+	bad[0] ^= 1
+	if _, _, err := initiator.ConfirmResponder(rB, bad); err == nil {
+		t.Fatal("expected error for invalid signature")
+	}
+	if _, _, err := initiator.ConfirmResponder(rB, sB); err != ErrKeyExchangeOutOfOrder {
+		t.Errorf("expected ErrKeyExchangeOutOfOrder, got %v", err)
+	}
+}
+
+// TestKeyExchangeReplay checks that the messages of one session are rejected by the key
+// confirmation of another session between the same parties.
+func TestKeyExchangeReplay(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userA, userB := []byte("Alice"), []byte("Bob")
+	keyA, err := masterKey.GenerateUserKey(userA, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := masterKey.GenerateUserKey(userB, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := func() (*KeyExchange, *KeyExchange) {
+		return NewKeyExchange(keyA, userA, userB, 16, true), NewKeyExchange(keyB, userB, userA, 16, true)
+	}
+	initiator, responder := session()
+	rA, err := initiator.InitKeyExchange(rand.Reader, 0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rB, sB, err := responder.RepondKeyExchange(rand.Reader, 0x02, rA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sA, err := initiator.ConfirmResponder(rB, sB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := responder.ConfirmInitiator(sA); err != nil {
+		t.Fatal(err)
+	}
+
+	// replayed init and confirm messages
+	_, responder = session()
+	if _, _, err := responder.RepondKeyExchange(rand.Reader, 0x02, rA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := responder.ConfirmInitiator(sA); err == nil {
+		t.Fatal("expected error for replayed confirm message")
+	}
+	// replayed response message
+	initiator, _ = session()
+	if _, err := initiator.InitKeyExchange(rand.Reader, 0x02); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := initiator.ConfirmResponder(rB, sB); err == nil {
+		t.Fatal("expected error for replayed response message")
+	}
+}
@@ -0,0 +1,112 @@
+package sm9
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// This file contains helpers of time-period identities, the standard way to revoke SM9 user
+// private keys: the KGC issues user private keys for the identity uid||'|'||period, such as
+// "alice@example.com|2026-10", and re-issues them for each new period. A leaked private key
+// is useless once the peers switch to the new period.
+
+// periodSeparator separates the uid and the period, the period must not contain it.
+const periodSeparator = '|'
+
+// Layouts of commonly used periods, see PeriodOf.
+const (
+	DailyPeriod   = "2006-01-02"
+	MonthlyPeriod = "2006-01"
+	YearlyPeriod  = "2006"
+)
+
+// PeriodOf returns the period of t (in UTC) formatted with the layout, such as MonthlyPeriod.
+func PeriodOf(t time.Time, layout string) string {
+	return t.UTC().Format(layout)
+}
+
+// PeriodIdentity returns the identity uid||'|'||period which binds uid to the validity period.
+func PeriodIdentity(uid []byte, period string) ([]byte, error) {
+	if len(period) == 0 || strings.IndexByte(period, periodSeparator) >= 0 {
+		return nil, errors.New("sm9: invalid period")
+	}
+	id := make([]byte, 0, len(uid)+1+len(period))
+	id = append(id, uid...)
+	id = append(id, periodSeparator)
+	id = append(id, period...)
+	return id, nil
+}
+
+// ParsePeriodIdentity splits the identity returned by PeriodIdentity into uid and period.
+func ParsePeriodIdentity(id []byte) ([]byte, string, error) {
+	i := bytes.LastIndexByte(id, periodSeparator)
+	if i < 0 || i == len(id)-1 {
+		return nil, "", errors.New("sm9: invalid period identity")
+	}
+	return id[:i], string(id[i+1:]), nil
+}
+
+// GenerateUserKeysForPeriod generates the user encrypt private keys of the uids for the period,
+// the i-th key is bound to PeriodIdentity(uids[i], period).
+func (master *EncryptMasterPrivateKey) GenerateUserKeysForPeriod(uids [][]byte, hid byte, period string) ([]*EncryptPrivateKey, error) {
+	keys := make([]*EncryptPrivateKey, len(uids))
+	for i, uid := range uids {
+		id, err := PeriodIdentity(uid, period)
+		if err != nil {
+			return nil, err
+		}
+		if keys[i], err = master.GenerateUserKey(id, hid); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// GenerateUserKeysForPeriod generates the user sign private keys of the uids for the period,
+// the i-th key is bound to PeriodIdentity(uids[i], period).
+func (master *SignMasterPrivateKey) GenerateUserKeysForPeriod(uids [][]byte, hid byte, period string) ([]*SignPrivateKey, error) {
+	keys := make([]*SignPrivateKey, len(uids))
+	for i, uid := range uids {
+		id, err := PeriodIdentity(uid, period)
+		if err != nil {
+			return nil, err
+		}
+		if keys[i], err = master.GenerateUserKey(id, hid); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// EncryptForPeriod encrypts plaintext for uid in the period, returns ciphertext with ASN.1 format,
+// SM9Cipher definition. Only the private key of the uid issued for the period can decrypt it.
+func EncryptForPeriod(rand io.Reader, pub *EncryptMasterPublicKey, uid []byte, hid byte, period string, plaintext []byte, opts EncrypterOpts) ([]byte, error) {
+	id, err := PeriodIdentity(uid, period)
+	if err != nil {
+		return nil, err
+	}
+	return pub.Encrypt(rand, id, hid, plaintext, opts)
+}
+
+// DecryptForPeriod decrypts the ciphertext with ASN.1 format, SM9Cipher definition, which was
+// encrypted for uid in the period. It fails if priv was not issued for the uid and the period.
+func DecryptForPeriod(priv *EncryptPrivateKey, uid []byte, period string, ciphertext []byte) ([]byte, error) {
+	id, err := PeriodIdentity(uid, period)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptASN1(priv, id, ciphertext)
+}
+
+// VerifyForPeriod verifies the ASN.1 encoded signature of type SM9Signature, sig, of hash with
+// the identity of uid in the period. Signatures generated by the private keys of other periods are rejected.
+func VerifyForPeriod(pub *SignMasterPublicKey, uid []byte, hid byte, period string, hash, sig []byte) bool {
+	id, err := PeriodIdentity(uid, period)
+	if err != nil {
+		return false
+	}
+	return VerifyASN1(pub, id, hid, hash, sig)
+}
@@ -0,0 +1,111 @@
+package sm9
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func TestPeriodIdentity(t *testing.T) {
+	now := time.Date(2026, 10, 17, 23, 0, 0, 0, time.FixedZone("UTC-8", -8*3600))
+	if p := PeriodOf(now, MonthlyPeriod); p != "2026-10" {
+		t.Errorf("unexpected monthly period %v", p)
+	}
+	if p := PeriodOf(now, DailyPeriod); p != "2026-10-18" {
+		t.Errorf("unexpected daily period %v", p)
+	}
+	id, err := PeriodIdentity([]byte("a|b"), "2026")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(id) != "a|b|2026" {
+		t.Errorf("unexpected identity %s", id)
+	}
+	uid, period, err := ParsePeriodIdentity(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(uid) != "a|b" || period != "2026" {
+		t.Errorf("unexpected uid %s or period %v", uid, period)
+	}
+	for _, p := range []string{"", "2026|10"} {
+		if _, err := PeriodIdentity([]byte("alice"), p); err == nil {
+			t.Errorf("%q: expected error", p)
+		}
+	}
+	for _, id := range []string{"alice", "alice|"} {
+		if _, _, err := ParsePeriodIdentity([]byte(id)); err == nil {
+			t.Errorf("%q: expected error", id)
+		}
+	}
+}
+
+func TestEncryptForPeriod(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x03)
+	uids := [][]byte{[]byte("Alice"), []byte("Bob")}
+	october, err := masterKey.GenerateUserKeysForPeriod(uids, hid, "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	november, err := masterKey.GenerateUserKeysForPeriod(uids, hid, "2026-11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(october) != len(uids) || len(november) != len(uids) {
+		t.Fatal("unexpected number of keys")
+	}
+	plaintext := []byte("Chinese IBE standard")
+	ciphertext, err := EncryptForPeriod(rand.Reader, masterKey.Public(), uids[1], hid, "2026-11", plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecryptForPeriod(november[1], uids[1], "2026-11", ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Error("decrypted plaintext mismatch")
+	}
+	// the key of the previous period is revoked
+	if _, err := DecryptForPeriod(october[1], uids[1], "2026-11", ciphertext); err == nil {
+		t.Error("expected error for the key of another period")
+	}
+	if _, err := DecryptForPeriod(november[1], uids[1], "2026-10", ciphertext); err == nil {
+		t.Error("expected error for another period")
+	}
+	if _, err := masterKey.GenerateUserKeysForPeriod(uids, hid, "2026|11"); err == nil {
+		t.Error("expected error for invalid period")
+	}
+}
+
+func TestVerifyForPeriod(t *testing.T) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	uid := []byte("Alice")
+	keys, err := masterKey.GenerateUserKeysForPeriod([][]byte{uid}, hid, "2026")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := []byte("Chinese IBS standard")
+	sig, err := keys[0].Sign(rand.Reader, hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyForPeriod(masterKey.Public(), uid, hid, "2026", hash, sig) {
+		t.Error("failed to verify signature")
+	}
+	if VerifyForPeriod(masterKey.Public(), uid, hid, "2027", hash, sig) {
+		t.Error("signature of another period should be rejected")
+	}
+	if VerifyForPeriod(masterKey.Public(), uid, hid, "", hash, sig) {
+		t.Error("invalid period should be rejected")
+	}
+}
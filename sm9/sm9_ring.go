@@ -0,0 +1,228 @@
+package sm9
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	"github.com/emmansun/gmsm/internal/randutil"
+	"github.com/emmansun/gmsm/sm9/bn256"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// This file contains SM9 identity based ring signature, the signer proves that
+// it holds the sign private key of one of the identities in the ring, without
+// revealing which one.
+//
+// Let g = e(P1, Ppub-s), Qi = [H1(IDi||hid, N)]P2 + Ppub-s, then e(dA, QA) = g.
+// The signer at index π in a ring of n identities:
+//
+//	random r, w(π) = g^r, h(π+1) = H2(M || L || w(π), N),
+//	for i = π+1, ..., π-1 (mod n): random Si in G1, w(i) = e(Si, Qi)·g^h(i), h(i+1) = H2(M || L || w(i), N),
+//	S(π) = [r - h(π)]dA.
+//
+// where L is the encoding of the ring. The signature is (h(0), S0, ..., Sn-1), the verifier
+// recomputes the chain from h(0) and checks it closes at h(n) = h(0).
+//
+// SM9RingSignature ::= SEQUENCE {
+//   h OCTET STRING,
+//   S SEQUENCE OF BIT STRING
+// }
+
+// RingMember is an identity of the ring.
+type RingMember struct {
+	UID []byte
+	HID byte
+}
+
+// encodeRing returns L = len(UID0) || UID0 || HID0 || ... which binds the ring to the signature.
+func encodeRing(ring []RingMember) ([]byte, error) {
+	if len(ring) == 0 {
+		return nil, errors.New("sm9: empty ring")
+	}
+	var buf []byte
+	var l [4]byte
+	seen := make(map[string]bool, len(ring))
+	for _, m := range ring {
+		binary.BigEndian.PutUint32(l[:], uint32(len(m.UID)))
+		start := len(buf)
+		buf = append(buf, l[:]...)
+		buf = append(buf, m.UID...)
+		buf = append(buf, m.HID)
+		id := string(buf[start:])
+		if seen[id] {
+			return nil, errors.New("sm9: duplicate ring member")
+		}
+		seen[id] = true
+	}
+	return buf, nil
+}
+
+// ringHash computes H2(M || L || w, N).
+func ringHash(hash, ringBytes []byte, w *bn256.GT) *bigmod.Nat {
+	var buffer []byte
+	buffer = append(buffer, hash...)
+	buffer = append(buffer, ringBytes...)
+	buffer = append(buffer, w.Marshal()...)
+	return hashH2(buffer)
+}
+
+// RingSignASN1 signs a hash (which should be the result of hashing a larger message)
+// on behalf of the ring, with the private key of identity (uid, hid) which must be a member
+// of the ring. It returns the ASN.1 encoded signature of type SM9RingSignature.
+//
+// The signature is randomized. Most applications should use [crypto/rand.Reader] as rand.
+func RingSignASN1(rand io.Reader, priv *SignPrivateKey, uid []byte, hid byte, ring []RingMember, hash []byte) ([]byte, error) {
+	ringBytes, err := encodeRing(ring)
+	if err != nil {
+		return nil, err
+	}
+	pi := -1
+	for i, m := range ring {
+		if m.HID == hid && bytes.Equal(m.UID, uid) {
+			pi = i
+			break
+		}
+	}
+	if pi < 0 {
+		return nil, errors.New("sm9: signer is not a member of the ring")
+	}
+	pub := priv.SignMasterPublicKey
+	g := pub.pair()
+	if !bytes.Equal(bn256.Pair(priv.PrivateKey, pub.GenerateUserPublicKey(uid, hid)).Marshal(), g.Marshal()) {
+		return nil, errors.New("sm9: private key does not match the signer identity")
+	}
+
+	randutil.MaybeReadByte(rand)
+	n := len(ring)
+	hs := make([]*bigmod.Nat, n)
+	ss := make([]*bn256.G1, n)
+	for {
+		r, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		w, err := pub.ScalarBaseMult(r.Bytes(orderNat))
+		if err != nil {
+			return nil, err
+		}
+		for j := 1; j < n; j++ {
+			i := (pi + j) % n
+			hs[i] = ringHash(hash, ringBytes, w)
+			x, err := randomScalar(rand)
+			if err != nil {
+				return nil, err
+			}
+			if ss[i], err = new(bn256.G1).ScalarBaseMult(x.Bytes(orderNat)); err != nil {
+				return nil, err
+			}
+			if w, err = ringLink(pub, ring[i], ss[i], hs[i]); err != nil {
+				return nil, err
+			}
+		}
+		hs[pi] = ringHash(hash, ringBytes, w)
+		// S(π) = [r - h(π)]dA
+		r.Sub(hs[pi], orderNat)
+		if r.IsZero() == 1 {
+			continue
+		}
+		if ss[pi], err = new(bn256.G1).ScalarMult(priv.PrivateKey, r.Bytes(orderNat)); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1OctetString(hs[0].Bytes(orderNat))
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			for _, s := range ss {
+				b.AddASN1BitString(s.MarshalUncompressed())
+			}
+		})
+	})
+	return b.Bytes()
+}
+
+// ringLink computes w = e(S, Q)·g^h for the ring member.
+func ringLink(pub *SignMasterPublicKey, m RingMember, s *bn256.G1, h *bigmod.Nat) (*bn256.GT, error) {
+	t, err := pub.ScalarBaseMult(h.Bytes(orderNat))
+	if err != nil {
+		return nil, err
+	}
+	u := bn256.Pair(s, pub.GenerateUserPublicKey(m.UID, m.HID))
+	return new(bn256.GT).Add(u, t), nil
+}
+
+// RingVerifyASN1 verifies the ASN.1 encoded signature of type SM9RingSignature, sig, of hash
+// using the master public key and the ring. Its return value records whether the signature is valid.
+func RingVerifyASN1(pub *SignMasterPublicKey, ring []RingMember, hash, sig []byte) bool {
+	ringBytes, err := encodeRing(ring)
+	if err != nil {
+		return false
+	}
+	h0, ss, err := parseRingSignature(sig)
+	if err != nil || len(ss) != len(ring) {
+		return false
+	}
+	hNat, err := bigmod.NewNat().SetBytes(h0, orderNat)
+	if err != nil || hNat.IsZero() == 1 {
+		return false
+	}
+	h := hNat
+	for i, m := range ring {
+		w, err := ringLink(pub, m, ss[i], h)
+		if err != nil {
+			return false
+		}
+		h = ringHash(hash, ringBytes, w)
+	}
+	return h.Equal(hNat) == 1
+}
+
+func parseRingSignature(sig []byte) ([]byte, []*bn256.G1, error) {
+	var (
+		hBytes []byte
+		inner  cryptobyte.String
+		points cryptobyte.String
+		ss     []*bn256.G1
+	)
+	input := cryptobyte.String(sig)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Bytes(&hBytes, asn1.OCTET_STRING) ||
+		!inner.ReadASN1(&points, asn1.SEQUENCE) ||
+		!inner.Empty() {
+		return nil, nil, errors.New("sm9: invalid ring signature asn.1 data")
+	}
+	for !points.Empty() {
+		var sBytes []byte
+		if !points.ReadASN1BitStringAsBytes(&sBytes) || len(sBytes) == 0 || sBytes[0] != 4 {
+			return nil, nil, errors.New("sm9: invalid ring signature asn.1 data")
+		}
+		s := new(bn256.G1)
+		if _, err := s.Unmarshal(sBytes[1:]); err != nil {
+			return nil, nil, err
+		}
+		if !s.IsOnCurve() {
+			return nil, nil, errors.New("sm9: invalid point in ring signature")
+		}
+		ss = append(ss, s)
+	}
+	return hBytes, ss, nil
+}
+
+// RingSign signs hash on behalf of the ring with the private key of identity (uid, hid),
+// the result is SM9RingSignature ASN.1 format. See RingSignASN1.
+func (priv *SignPrivateKey) RingSign(rand io.Reader, uid []byte, hid byte, ring []RingMember, hash []byte) ([]byte, error) {
+	return RingSignASN1(rand, priv, uid, hid, ring, hash)
+}
+
+// RingVerify verifies the ASN.1 encoded ring signature, sig, of hash with the ring.
+// Its return value records whether the signature is valid.
+func (pub *SignMasterPublicKey) RingVerify(ring []RingMember, hash, sig []byte) bool {
+	return RingVerifyASN1(pub, ring, hash, sig)
+}
@@ -0,0 +1,107 @@
+package sm9
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+func TestRingSignASN1(t *testing.T) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	var ring []RingMember
+	for i := 0; i < 4; i++ {
+		ring = append(ring, RingMember{UID: []byte(fmt.Sprintf("user%d@example.com", i)), HID: hid})
+	}
+	hashed := []byte("Chinese IBS standard")
+	pub := masterKey.Public()
+	for i, m := range ring {
+		userKey, err := masterKey.GenerateUserKey(m.UID, m.HID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := userKey.RingSign(rand.Reader, m.UID, m.HID, ring, hashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.RingVerify(ring, hashed, sig) {
+			t.Errorf("signer %v: verify failed", i)
+		}
+		if RingVerifyASN1(pub, ring, []byte("another message"), sig) {
+			t.Errorf("signer %v: verify with another message successed", i)
+		}
+		if RingVerifyASN1(pub, ring[1:], hashed, sig) {
+			t.Errorf("signer %v: verify with another ring successed", i)
+		}
+		reordered := append([]RingMember{ring[1], ring[0]}, ring[2:]...)
+		if RingVerifyASN1(pub, reordered, hashed, sig) {
+			t.Errorf("signer %v: verify with reordered ring successed", i)
+		}
+	}
+}
+
+func TestRingSignSingleMember(t *testing.T) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("emmansun")
+	hid := byte(0x01)
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := []RingMember{{UID: uid, HID: hid}}
+	hashed := []byte("Chinese IBS standard")
+	sig, err := RingSignASN1(rand.Reader, userKey, uid, hid, ring, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !RingVerifyASN1(masterKey.Public(), ring, hashed, sig) {
+		t.Errorf("verify failed")
+	}
+}
+
+func TestRingSignInvalid(t *testing.T) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	userKey, err := masterKey.GenerateUserKey([]byte("Alice"), hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := []byte("Chinese IBS standard")
+	ring := []RingMember{{UID: []byte("Bob"), HID: hid}, {UID: []byte("Carol"), HID: hid}}
+	if _, err := RingSignASN1(rand.Reader, userKey, []byte("Alice"), hid, ring, hashed); err == nil {
+		t.Errorf("expected error for non-member signer")
+	}
+	if _, err := RingSignASN1(rand.Reader, userKey, []byte("Bob"), hid, ring, hashed); err == nil {
+		t.Errorf("expected error for mismatched private key")
+	}
+	if _, err := RingSignASN1(rand.Reader, userKey, []byte("Alice"), hid, nil, hashed); err == nil {
+		t.Errorf("expected error for empty ring")
+	}
+	dup := []RingMember{{UID: []byte("Alice"), HID: hid}, {UID: []byte("Alice"), HID: hid}}
+	if _, err := RingSignASN1(rand.Reader, userKey, []byte("Alice"), hid, dup, hashed); err == nil {
+		t.Errorf("expected error for duplicate ring members")
+	}
+
+	ring = append(ring, RingMember{UID: []byte("Alice"), HID: hid})
+	sig, err := RingSignASN1(rand.Reader, userKey, []byte("Alice"), hid, ring, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !RingVerifyASN1(masterKey.Public(), ring, hashed, sig) {
+		t.Fatal("verify failed")
+	}
+	for _, bad := range [][]byte{nil, {0x30, 0x00}, append(append([]byte{}, sig...), 0), sig[:len(sig)-1]} {
+		if RingVerifyASN1(masterKey.Public(), ring, hashed, bad) {
+			t.Errorf("verify invalid signature %x successed", bad)
+		}
+	}
+}
@@ -0,0 +1,766 @@
+package sm9
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	"github.com/emmansun/gmsm/sm9/bn256"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// This file contains a distributed KGC, the master private key s is Shamir-shared among
+// n servers, any t of them can issue a user private key, no single server knows s.
+//
+// The user private key is [s/(H1+s)]P = P - [H1]·[(H1+s)^-1]P, (H1+s)^-1 is not linear in s,
+// so every issuance consumes an issuance ticket, a pair of degree t-1 sharings of a random ρ
+// and of ρ·s, generated by the servers in advance:
+//
+//	server i:  μi = ρi·H1 + ci, Di = [ρi]P
+//	client:    μ = Σ λi·μi = ρ(H1+s), R = Σ [λi]Di = [ρ]P, dA = P - [H1·μ^-1]R
+//
+// where λi are the Lagrange coefficients at zero. A ticket must never issue the keys of two
+// identities, two user keys issued with the same ρ reveal s. Deleting a used ticket is not
+// enough: with n >= 2t servers, two disjoint sets of t servers could issue different identities
+// with the same ticket. So a ticket is bound to one identity (uid, hid) when it is generated,
+// every message of the protocol carries the identity, a server rejects the messages of any
+// other identity, and issues only the user key of the bound identity with the ticket.
+//
+// Tickets are generated with a two-round protocol among at least 2t-1 servers (the participants):
+//
+//	round 1: each participant i shares a random ρ(i) to all servers, ρj = Σ ρ(i)j.
+//	round 2: each participant j shares ρj·sj (a degree 2t-2 sharing of ρ·s) to all servers,
+//	         server l computes cl = Σ λj·(ρj·sj)l, which is a degree t-1 sharing of ρ·s.
+//
+// All the messages must be sent over confidential and authenticated channels, the protocol
+// assumes honest-but-curious servers.
+
+// thresholdShare is the share of a master private key held by one server.
+type thresholdShare struct {
+	index     int
+	threshold int
+	parties   int
+	d         *bigmod.Nat
+
+	mu      sync.Mutex
+	pending map[uint64]*pendingTicket
+	tickets map[uint64]*issuanceTicket
+}
+
+type pendingTicket struct {
+	identity     []byte
+	participants []int
+	rho          *bigmod.Nat
+}
+
+type issuanceTicket struct {
+	identity []byte      // uid ‖ hid the ticket is bound to
+	rho      *bigmod.Nat // share of ρ
+	c        *bigmod.Nat // share of ρ·s
+}
+
+// TicketMessage is a message of the issuance ticket generation protocol, sent from server
+// From to server To. Identity is uid ‖ hid of the user key the ticket is bound to. Value
+// is a share of a secret, it must be kept confidential.
+type TicketMessage struct {
+	Ticket   uint64
+	From, To int
+	Identity []byte
+	Value    []byte
+}
+
+// ticketIdentity returns uid ‖ hid, the identity an issuance ticket is bound to.
+func ticketIdentity(uid []byte, hid byte) []byte {
+	var id []byte
+	id = append(id, uid...)
+	return append(id, hid)
+}
+
+func natFromInt(x int) *bigmod.Nat {
+	n, _ := bigmod.NewNat().SetBytes(big.NewInt(int64(x)).Bytes(), orderNat)
+	return n
+}
+
+// splitSecret returns the shares f(1), ..., f(parties) of a random polynomial f of
+// degree threshold-1 with f(0) = secret.
+func splitSecret(rand io.Reader, secret *bigmod.Nat, threshold, parties int) ([]*bigmod.Nat, error) {
+	coeffs := make([]*bigmod.Nat, threshold)
+	coeffs[0] = secret
+	for i := 1; i < threshold; i++ {
+		c, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		coeffs[i] = c
+	}
+	shares := make([]*bigmod.Nat, parties)
+	for x := 1; x <= parties; x++ {
+		xNat := natFromInt(x)
+		y := bigmod.NewNat().Set(coeffs[threshold-1])
+		for i := threshold - 2; i >= 0; i-- {
+			y.Mul(xNat, orderNat)
+			y.Add(coeffs[i], orderNat)
+		}
+		shares[x-1] = y
+	}
+	return shares, nil
+}
+
+// lagrangeCoefficient returns the Lagrange coefficient at zero of index i for the indexes,
+// that is Π xj/(xj - xi) for all xj != xi.
+func lagrangeCoefficient(indexes []int, i int) *bigmod.Nat {
+	num := natFromInt(1)
+	den := natFromInt(1)
+	xi := natFromInt(i)
+	for _, j := range indexes {
+		if j == i {
+			continue
+		}
+		xj := natFromInt(j)
+		num.Mul(xj, orderNat)
+		den.Mul(xj.Sub(xi, orderNat), orderNat)
+	}
+	return num.Mul(bigmod.NewNat().Exp(den, orderMinus2, orderNat), orderNat)
+}
+
+// checkIndexes checks that the indexes are distinct and in range [1, parties] (parties <= 0 means unlimited).
+func checkIndexes(indexes []int, parties int) error {
+	seen := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		if i < 1 || (parties > 0 && i > parties) {
+			return errors.New("sm9: invalid share index")
+		}
+		if seen[i] {
+			return errors.New("sm9: duplicate share index")
+		}
+		seen[i] = true
+	}
+	return nil
+}
+
+func splitMasterKey(rand io.Reader, d *big.Int, threshold, parties int) ([]*thresholdShare, error) {
+	if threshold < 1 || parties < 2*threshold-1 {
+		return nil, errors.New("sm9: invalid threshold, parties must be at least 2*threshold-1")
+	}
+	dNat, err := bigmod.NewNat().SetBytes(d.Bytes(), orderNat)
+	if err != nil {
+		return nil, err
+	}
+	values, err := splitSecret(rand, dNat, threshold, parties)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*thresholdShare, parties)
+	for i, v := range values {
+		shares[i] = &thresholdShare{index: i + 1, threshold: threshold, parties: parties, d: v}
+	}
+	return shares, nil
+}
+
+// startTicket runs the first round of the ticket generation protocol.
+func (s *thresholdShare) startTicket(rand io.Reader, id uint64, identity []byte) ([]TicketMessage, error) {
+	s.mu.Lock()
+	_, used := s.tickets[id]
+	s.mu.Unlock()
+	if used {
+		return nil, errors.New("sm9: issuance ticket already exists")
+	}
+	rho, err := randomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	values, err := splitSecret(rand, rho, s.threshold, s.parties)
+	if err != nil {
+		return nil, err
+	}
+	return s.messages(id, identity, values), nil
+}
+
+func (s *thresholdShare) messages(id uint64, identity []byte, values []*bigmod.Nat) []TicketMessage {
+	msgs := make([]TicketMessage, len(values))
+	for i, v := range values {
+		msgs[i] = TicketMessage{Ticket: id, From: s.index, To: i + 1, Identity: identity, Value: v.Bytes(orderNat)}
+	}
+	return msgs
+}
+
+// receive checks the messages of one round and returns the senders in ascending order and the values.
+func (s *thresholdShare) receive(id uint64, identity []byte, msgs []TicketMessage) ([]int, map[int]*bigmod.Nat, error) {
+	if len(msgs) < 2*s.threshold-1 {
+		return nil, nil, errors.New("sm9: not enough ticket messages")
+	}
+	senders := make([]int, 0, len(msgs))
+	values := make(map[int]*bigmod.Nat, len(msgs))
+	for _, m := range msgs {
+		if m.Ticket != id || m.To != s.index {
+			return nil, nil, errors.New("sm9: unexpected ticket message")
+		}
+		if !bytes.Equal(m.Identity, identity) {
+			return nil, nil, errors.New("sm9: ticket message is bound to another identity")
+		}
+		v, err := bigmod.NewNat().SetBytes(m.Value, orderNat)
+		if err != nil {
+			return nil, nil, errors.New("sm9: invalid ticket message value")
+		}
+		senders = append(senders, m.From)
+		values[m.From] = v
+	}
+	if err := checkIndexes(senders, s.parties); err != nil {
+		return nil, nil, err
+	}
+	sort.Ints(senders)
+	return senders, values, nil
+}
+
+// reshareTicket runs the second round of the ticket generation protocol.
+func (s *thresholdShare) reshareTicket(rand io.Reader, id uint64, identity []byte, msgs []TicketMessage) ([]TicketMessage, error) {
+	participants, values, err := s.receive(id, identity, msgs)
+	if err != nil {
+		return nil, err
+	}
+	rho := bigmod.NewNat().ExpandFor(orderNat)
+	for _, v := range values {
+		rho.Add(v, orderNat)
+	}
+
+	s.mu.Lock()
+	if _, ok := s.tickets[id]; ok {
+		s.mu.Unlock()
+		return nil, errors.New("sm9: issuance ticket already exists")
+	}
+	if _, ok := s.pending[id]; ok {
+		s.mu.Unlock()
+		return nil, errors.New("sm9: issuance ticket is being generated")
+	}
+	if s.pending == nil {
+		s.pending = make(map[uint64]*pendingTicket)
+	}
+	s.pending[id] = &pendingTicket{identity: identity, participants: participants, rho: rho}
+	s.mu.Unlock()
+
+	if _, ok := values[s.index]; !ok {
+		// not a participant, just wait for the shares of ρ·s.
+		return nil, nil
+	}
+	product := bigmod.NewNat().Set(rho).Mul(s.d, orderNat)
+	shares, err := splitSecret(rand, product, s.threshold, s.parties)
+	if err != nil {
+		return nil, err
+	}
+	return s.messages(id, identity, shares), nil
+}
+
+// finishTicket completes the ticket generation protocol and stores the ticket.
+func (s *thresholdShare) finishTicket(id uint64, identity []byte, msgs []TicketMessage) error {
+	senders, values, err := s.receive(id, identity, msgs)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pending[id]
+	if !ok {
+		return errors.New("sm9: unknown issuance ticket")
+	}
+	if !bytes.Equal(p.identity, identity) {
+		return errors.New("sm9: issuance ticket is bound to another identity")
+	}
+	if len(senders) != len(p.participants) {
+		return errors.New("sm9: ticket messages are not from the participants")
+	}
+	for i := range senders {
+		if senders[i] != p.participants[i] {
+			return errors.New("sm9: ticket messages are not from the participants")
+		}
+	}
+	c := bigmod.NewNat().ExpandFor(orderNat)
+	for _, j := range senders {
+		c.Add(lagrangeCoefficient(senders, j).Mul(values[j], orderNat), orderNat)
+	}
+	delete(s.pending, id)
+	if s.tickets == nil {
+		s.tickets = make(map[uint64]*issuanceTicket)
+	}
+	s.tickets[id] = &issuanceTicket{identity: p.identity, rho: p.rho, c: c}
+	return nil
+}
+
+// issue consumes the ticket and returns μi = ρi·H1 + ci and ρi, the identity must be
+// the one the ticket is bound to.
+func (s *thresholdShare) issue(id uint64, uid []byte, hid byte) (mu, rho []byte, err error) {
+	identity := ticketIdentity(uid, hid)
+	s.mu.Lock()
+	t, ok := s.tickets[id]
+	if ok && !bytes.Equal(t.identity, identity) {
+		s.mu.Unlock()
+		return nil, nil, errors.New("sm9: issuance ticket is bound to another identity")
+	}
+	delete(s.tickets, id)
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil, errors.New("sm9: unknown or used issuance ticket")
+	}
+	h := hashH1(identity)
+	h.Mul(t.rho, orderNat).Add(t.c, orderNat)
+	return h.Bytes(orderNat), t.rho.Bytes(orderNat), nil
+}
+
+func (s *thresholdShare) ticketCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tickets)
+}
+
+func (s *thresholdShare) marshal(b *cryptobyte.Builder, pub []byte) {
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(int64(s.index))
+		b.AddASN1Int64(int64(s.threshold))
+		b.AddASN1Int64(int64(s.parties))
+		b.AddASN1BigInt(new(big.Int).SetBytes(s.d.Bytes(orderNat)))
+		b.AddASN1BitString(pub)
+	})
+}
+
+func unmarshalThresholdShare(der []byte) (*thresholdShare, []byte, error) {
+	var (
+		inner                     cryptobyte.String
+		index, threshold, parties int64
+		pub                       []byte
+	)
+	d := new(big.Int)
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(&index) ||
+		!inner.ReadASN1Integer(&threshold) ||
+		!inner.ReadASN1Integer(&parties) ||
+		!inner.ReadASN1Integer(d) ||
+		!inner.ReadASN1BitStringAsBytes(&pub) ||
+		!inner.Empty() || len(pub) == 0 {
+		return nil, nil, errors.New("sm9: invalid master key share asn1 data")
+	}
+	if threshold < 1 || parties < 2*threshold-1 || index < 1 || index > parties || d.Sign() <= 0 {
+		return nil, nil, errors.New("sm9: invalid master key share")
+	}
+	dNat, err := bigmod.NewNat().SetBytes(d.Bytes(), orderNat)
+	if err != nil {
+		return nil, nil, errors.New("sm9: invalid master key share")
+	}
+	return &thresholdShare{index: int(index), threshold: int(threshold), parties: int(parties), d: dNat}, pub, nil
+}
+
+// userKeyShare is the common part of the user key shares.
+type userKeyShare struct {
+	Index  int      // index of the issuing server
+	Ticket uint64   // the issuance ticket
+	Mu     *big.Int // μi = ρi·H1 + ci
+}
+
+func (u *userKeyShare) marshal(point []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(int64(u.Index))
+		b.AddASN1Uint64(u.Ticket)
+		b.AddASN1BigInt(u.Mu)
+		b.AddASN1BitString(point)
+	})
+	return b.Bytes()
+}
+
+func (u *userKeyShare) unmarshal(der []byte) ([]byte, error) {
+	var (
+		inner cryptobyte.String
+		index int64
+		point []byte
+	)
+	mu := new(big.Int)
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(&index) ||
+		!inner.ReadASN1Integer(&u.Ticket) ||
+		!inner.ReadASN1Integer(mu) ||
+		!inner.ReadASN1BitStringAsBytes(&point) ||
+		!inner.Empty() || len(point) == 0 || index < 1 || mu.Sign() < 0 {
+		return nil, errors.New("sm9: invalid user key share asn1 data")
+	}
+	u.Index = int(index)
+	u.Mu = mu
+	return point, nil
+}
+
+// combineUserKeyShares returns the Lagrange coefficients of the shares, H1 and μ^-1.
+func combineUserKeyShares(shares []*userKeyShare, uid []byte, hid byte) (lambdas []*bigmod.Nat, h, muInv *bigmod.Nat, err error) {
+	if len(shares) == 0 {
+		return nil, nil, nil, errors.New("sm9: no user key shares")
+	}
+	indexes := make([]int, len(shares))
+	for i, s := range shares {
+		if s.Ticket != shares[0].Ticket {
+			return nil, nil, nil, errors.New("sm9: user key shares are issued with different tickets")
+		}
+		indexes[i] = s.Index
+	}
+	if err := checkIndexes(indexes, 0); err != nil {
+		return nil, nil, nil, err
+	}
+	mu := bigmod.NewNat().ExpandFor(orderNat)
+	lambdas = make([]*bigmod.Nat, len(shares))
+	for i, s := range shares {
+		m, err := bigmod.NewNat().SetBytes(s.Mu.Bytes(), orderNat)
+		if err != nil {
+			return nil, nil, nil, errors.New("sm9: invalid user key share")
+		}
+		lambdas[i] = lagrangeCoefficient(indexes, s.Index)
+		mu.Add(m.Mul(lambdas[i], orderNat), orderNat)
+	}
+	if mu.IsZero() == 1 {
+		return nil, nil, nil, errors.New("sm9: invalid user key shares")
+	}
+	return lambdas, hashH1(ticketIdentity(uid, hid)), bigmod.NewNat().Exp(mu, orderMinus2, orderNat), nil
+}
+
+// userKeyScalar returns -H1·μ^-1, the user private key is P + [-H1·μ^-1]R where R = Σ [λi]Di.
+func userKeyScalar(h, muInv *bigmod.Nat) []byte {
+	b := bigmod.NewNat().ExpandFor(orderNat)
+	b.Sub(h.Mul(muInv, orderNat), orderNat)
+	return b.Bytes(orderNat)
+}
+
+// SignMasterKeyShare is the share of a sign master private key held by one server of
+// a distributed KGC.
+type SignMasterKeyShare struct {
+	*SignMasterPublicKey
+	share *thresholdShare
+}
+
+// SignUserKeyShare is the share of a user sign private key issued by one server of a distributed KGC.
+type SignUserKeyShare struct {
+	userKeyShare
+	D *bn256.G1 // [ρi]P1
+}
+
+// Split splits the sign master private key into parties shares, any threshold of them can issue
+// user sign private keys. parties must be at least 2*threshold-1 to generate issuance tickets.
+//
+// The dealer should destroy the master private key after distributing the shares.
+func (master *SignMasterPrivateKey) Split(rand io.Reader, threshold, parties int) ([]*SignMasterKeyShare, error) {
+	shares, err := splitMasterKey(rand, master.D, threshold, parties)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*SignMasterKeyShare, len(shares))
+	for i, s := range shares {
+		ret[i] = &SignMasterKeyShare{SignMasterPublicKey: master.SignMasterPublicKey, share: s}
+	}
+	return ret, nil
+}
+
+// Index returns the index of the share, starting from 1.
+func (share *SignMasterKeyShare) Index() int {
+	return share.share.index
+}
+
+// Threshold returns the number of shares required to issue a user key.
+func (share *SignMasterKeyShare) Threshold() int {
+	return share.share.threshold
+}
+
+// Parties returns the number of shares.
+func (share *SignMasterKeyShare) Parties() int {
+	return share.share.parties
+}
+
+// Tickets returns the number of unused issuance tickets.
+func (share *SignMasterKeyShare) Tickets() int {
+	return share.share.ticketCount()
+}
+
+// StartTicket starts the generation of the issuance ticket id for the user key of (uid, hid),
+// it's called by each of the participants (at least 2*threshold-1 servers), the returned
+// messages should be delivered to all servers, including itself.
+func (share *SignMasterKeyShare) StartTicket(rand io.Reader, id uint64, uid []byte, hid byte) ([]TicketMessage, error) {
+	return share.share.startTicket(rand, id, ticketIdentity(uid, hid))
+}
+
+// ReshareTicket processes the messages of StartTicket sent to this server, it's called by all servers
+// with the same (uid, hid) as StartTicket, messages bound to another identity are rejected.
+// If this server is a participant, the returned messages should be delivered to all servers,
+// including itself, otherwise it returns no messages.
+func (share *SignMasterKeyShare) ReshareTicket(rand io.Reader, id uint64, uid []byte, hid byte, msgs []TicketMessage) ([]TicketMessage, error) {
+	return share.share.reshareTicket(rand, id, ticketIdentity(uid, hid), msgs)
+}
+
+// FinishTicket processes the messages of ReshareTicket sent to this server, after that the
+// issuance ticket id is ready to issue the user key of (uid, hid), and no other.
+func (share *SignMasterKeyShare) FinishTicket(id uint64, uid []byte, hid byte, msgs []TicketMessage) error {
+	return share.share.finishTicket(id, ticketIdentity(uid, hid), msgs)
+}
+
+// GenerateUserKeyShare issues the share of the user sign private key of (uid, hid) with the issuance ticket,
+// (uid, hid) must be the identity the ticket is bound to. The ticket is deleted, all servers must issue
+// the shares of one user key with the same ticket.
+func (share *SignMasterKeyShare) GenerateUserKeyShare(ticket uint64, uid []byte, hid byte) (*SignUserKeyShare, error) {
+	mu, rho, err := share.share.issue(ticket, uid, hid)
+	if err != nil {
+		return nil, err
+	}
+	d, err := new(bn256.G1).ScalarBaseMult(rho)
+	if err != nil {
+		return nil, err
+	}
+	return &SignUserKeyShare{
+		userKeyShare: userKeyShare{Index: share.share.index, Ticket: ticket, Mu: new(big.Int).SetBytes(mu)},
+		D:            d,
+	}, nil
+}
+
+// MarshalASN1 marshals the master key share (without the issuance tickets) to asn.1 format data.
+// Issuance tickets are never persisted, restoring them from a backup could cause a ticket to be used twice.
+func (share *SignMasterKeyShare) MarshalASN1() ([]byte, error) {
+	var b cryptobyte.Builder
+	share.share.marshal(&b, share.MasterPublicKey.MarshalUncompressed())
+	return b.Bytes()
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data to master key share.
+func (share *SignMasterKeyShare) UnmarshalASN1(der []byte) error {
+	s, pubBytes, err := unmarshalThresholdShare(der)
+	if err != nil {
+		return err
+	}
+	pub := new(SignMasterPublicKey)
+	if err := pub.UnmarshalRaw(pubBytes); err != nil {
+		return err
+	}
+	share.SignMasterPublicKey = pub
+	share.share = s
+	return nil
+}
+
+// MarshalASN1 marshals the user key share to asn.1 format data.
+func (share *SignUserKeyShare) MarshalASN1() ([]byte, error) {
+	return share.marshal(share.D.MarshalUncompressed())
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data to user key share.
+func (share *SignUserKeyShare) UnmarshalASN1(der []byte) error {
+	point, err := share.unmarshal(der)
+	if err != nil {
+		return err
+	}
+	d, err := unmarshalG1(point)
+	if err != nil {
+		return err
+	}
+	share.D = d
+	return nil
+}
+
+// CombineSignUserKey combines at least threshold user key shares, issued with the same ticket, into
+// the user sign private key of (uid, hid), the result is checked against the master public key.
+func CombineSignUserKey(pub *SignMasterPublicKey, uid []byte, hid byte, shares []*SignUserKeyShare) (*SignPrivateKey, error) {
+	common := make([]*userKeyShare, len(shares))
+	for i, s := range shares {
+		common[i] = &s.userKeyShare
+	}
+	lambdas, h, muInv, err := combineUserKeyShares(common, uid, hid)
+	if err != nil {
+		return nil, err
+	}
+	var r *bn256.G1
+	for i, s := range shares {
+		if s.D == nil || !s.D.IsOnCurve() {
+			return nil, errors.New("sm9: invalid user key share")
+		}
+		t, err := new(bn256.G1).ScalarMult(s.D, lambdas[i].Bytes(orderNat))
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			r = t
+		} else {
+			r.Add(r, t)
+		}
+	}
+	d, err := new(bn256.G1).ScalarMult(r, userKeyScalar(h, muInv))
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, bn256.Gen1)
+
+	priv := &SignPrivateKey{PrivateKey: d, SignMasterPublicKey: pub}
+	if !bytes.Equal(bn256.Pair(d, pub.GenerateUserPublicKey(uid, hid)).Marshal(), pub.pair().Marshal()) {
+		return nil, errors.New("sm9: invalid user key shares")
+	}
+	return priv, nil
+}
+
+// EncryptMasterKeyShare is the share of an encrypt master private key held by one server of
+// a distributed KGC.
+type EncryptMasterKeyShare struct {
+	*EncryptMasterPublicKey
+	share *thresholdShare
+}
+
+// EncryptUserKeyShare is the share of a user encrypt private key issued by one server of a distributed KGC.
+type EncryptUserKeyShare struct {
+	userKeyShare
+	D *bn256.G2 // [ρi]P2
+}
+
+// Split splits the encrypt master private key into parties shares, any threshold of them can issue
+// user encrypt private keys. parties must be at least 2*threshold-1 to generate issuance tickets.
+//
+// The dealer should destroy the master private key after distributing the shares.
+func (master *EncryptMasterPrivateKey) Split(rand io.Reader, threshold, parties int) ([]*EncryptMasterKeyShare, error) {
+	shares, err := splitMasterKey(rand, master.D, threshold, parties)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*EncryptMasterKeyShare, len(shares))
+	for i, s := range shares {
+		ret[i] = &EncryptMasterKeyShare{EncryptMasterPublicKey: master.EncryptMasterPublicKey, share: s}
+	}
+	return ret, nil
+}
+
+// Index returns the index of the share, starting from 1.
+func (share *EncryptMasterKeyShare) Index() int {
+	return share.share.index
+}
+
+// Threshold returns the number of shares required to issue a user key.
+func (share *EncryptMasterKeyShare) Threshold() int {
+	return share.share.threshold
+}
+
+// Parties returns the number of shares.
+func (share *EncryptMasterKeyShare) Parties() int {
+	return share.share.parties
+}
+
+// Tickets returns the number of unused issuance tickets.
+func (share *EncryptMasterKeyShare) Tickets() int {
+	return share.share.ticketCount()
+}
+
+// StartTicket starts the generation of the issuance ticket id for the user key of (uid, hid),
+// see SignMasterKeyShare.StartTicket.
+func (share *EncryptMasterKeyShare) StartTicket(rand io.Reader, id uint64, uid []byte, hid byte) ([]TicketMessage, error) {
+	return share.share.startTicket(rand, id, ticketIdentity(uid, hid))
+}
+
+// ReshareTicket processes the messages of StartTicket sent to this server, see SignMasterKeyShare.ReshareTicket.
+func (share *EncryptMasterKeyShare) ReshareTicket(rand io.Reader, id uint64, uid []byte, hid byte, msgs []TicketMessage) ([]TicketMessage, error) {
+	return share.share.reshareTicket(rand, id, ticketIdentity(uid, hid), msgs)
+}
+
+// FinishTicket processes the messages of ReshareTicket sent to this server, see SignMasterKeyShare.FinishTicket.
+func (share *EncryptMasterKeyShare) FinishTicket(id uint64, uid []byte, hid byte, msgs []TicketMessage) error {
+	return share.share.finishTicket(id, ticketIdentity(uid, hid), msgs)
+}
+
+// GenerateUserKeyShare issues the share of the user encrypt private key of (uid, hid) with the issuance ticket,
+// (uid, hid) must be the identity the ticket is bound to. The ticket is deleted, all servers must issue
+// the shares of one user key with the same ticket.
+func (share *EncryptMasterKeyShare) GenerateUserKeyShare(ticket uint64, uid []byte, hid byte) (*EncryptUserKeyShare, error) {
+	mu, rho, err := share.share.issue(ticket, uid, hid)
+	if err != nil {
+		return nil, err
+	}
+	d, err := new(bn256.G2).ScalarBaseMult(rho)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptUserKeyShare{
+		userKeyShare: userKeyShare{Index: share.share.index, Ticket: ticket, Mu: new(big.Int).SetBytes(mu)},
+		D:            d,
+	}, nil
+}
+
+// MarshalASN1 marshals the master key share (without the issuance tickets) to asn.1 format data.
+// Issuance tickets are never persisted, restoring them from a backup could cause a ticket to be used twice.
+func (share *EncryptMasterKeyShare) MarshalASN1() ([]byte, error) {
+	var b cryptobyte.Builder
+	share.share.marshal(&b, share.MasterPublicKey.MarshalUncompressed())
+	return b.Bytes()
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data to master key share.
+func (share *EncryptMasterKeyShare) UnmarshalASN1(der []byte) error {
+	s, pubBytes, err := unmarshalThresholdShare(der)
+	if err != nil {
+		return err
+	}
+	pub := new(EncryptMasterPublicKey)
+	if err := pub.UnmarshalRaw(pubBytes); err != nil {
+		return err
+	}
+	share.EncryptMasterPublicKey = pub
+	share.share = s
+	return nil
+}
+
+// MarshalASN1 marshals the user key share to asn.1 format data.
+func (share *EncryptUserKeyShare) MarshalASN1() ([]byte, error) {
+	return share.marshal(share.D.MarshalUncompressed())
+}
+
+// UnmarshalASN1 unmarshals asn.1 format data to user key share.
+func (share *EncryptUserKeyShare) UnmarshalASN1(der []byte) error {
+	point, err := share.unmarshal(der)
+	if err != nil {
+		return err
+	}
+	d, err := unmarshalG2(point)
+	if err != nil {
+		return err
+	}
+	share.D = d
+	return nil
+}
+
+// CombineEncryptUserKey combines at least threshold user key shares, issued with the same ticket, into
+// the user encrypt private key of (uid, hid), the result is checked against the master public key.
+func CombineEncryptUserKey(pub *EncryptMasterPublicKey, uid []byte, hid byte, shares []*EncryptUserKeyShare) (*EncryptPrivateKey, error) {
+	common := make([]*userKeyShare, len(shares))
+	for i, s := range shares {
+		common[i] = &s.userKeyShare
+	}
+	lambdas, h, muInv, err := combineUserKeyShares(common, uid, hid)
+	if err != nil {
+		return nil, err
+	}
+	var r *bn256.G2
+	for i, s := range shares {
+		if s.D == nil || !s.D.IsOnCurve() {
+			return nil, errors.New("sm9: invalid user key share")
+		}
+		t, err := new(bn256.G2).ScalarMult(s.D, lambdas[i].Bytes(orderNat))
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			r = t
+		} else {
+			r.Add(r, t)
+		}
+	}
+	d, err := new(bn256.G2).ScalarMult(r, userKeyScalar(h, muInv))
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, bn256.Gen2)
+
+	priv := &EncryptPrivateKey{PrivateKey: d, EncryptMasterPublicKey: pub}
+	if !bytes.Equal(bn256.Pair(pub.GenerateUserPublicKey(uid, hid), d).Marshal(), pub.pair().Marshal()) {
+		return nil, errors.New("sm9: invalid user key shares")
+	}
+	return priv, nil
+}
@@ -0,0 +1,345 @@
+package sm9
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+type ticketServer interface {
+	Index() int
+	StartTicket(rand io.Reader, id uint64, uid []byte, hid byte) ([]TicketMessage, error)
+	ReshareTicket(rand io.Reader, id uint64, uid []byte, hid byte, msgs []TicketMessage) ([]TicketMessage, error)
+	FinishTicket(id uint64, uid []byte, hid byte, msgs []TicketMessage) error
+}
+
+// generateTicket runs the ticket generation protocol among the servers for the user key
+// of (uid, hid), the first participants servers are the participants.
+func generateTicket(t *testing.T, servers []ticketServer, participants int, id uint64, uid []byte, hid byte) {
+	t.Helper()
+	inbox := make(map[int][]TicketMessage)
+	deliver := func(msgs []TicketMessage) {
+		for _, m := range msgs {
+			inbox[m.To] = append(inbox[m.To], m)
+		}
+	}
+	for _, s := range servers[:participants] {
+		msgs, err := s.StartTicket(rand.Reader, id, uid, hid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deliver(msgs)
+	}
+	round1 := inbox
+	inbox = make(map[int][]TicketMessage)
+	for _, s := range servers {
+		msgs, err := s.ReshareTicket(rand.Reader, id, uid, hid, round1[s.Index()])
+		if err != nil {
+			t.Fatal(err)
+		}
+		deliver(msgs)
+	}
+	for _, s := range servers {
+		if err := s.FinishTicket(id, uid, hid, inbox[s.Index()]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestThresholdSignUserKey(t *testing.T) {
+	master, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Split(rand.Reader, 3, 4); err == nil {
+		t.Fatal("expected error for parties < 2*threshold-1")
+	}
+	shares, err := master.Split(rand.Reader, 3, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := make([]ticketServer, len(shares))
+	for i, s := range shares {
+		servers[i] = s
+	}
+	uid := []byte("Alice")
+	hid := byte(0x01)
+	generateTicket(t, servers, 5, 1, uid, hid)
+	for _, s := range shares {
+		if s.Tickets() != 1 {
+			t.Fatalf("server %v: expected 1 ticket, got %v", s.Index(), s.Tickets())
+		}
+	}
+
+	// any 3 of the 6 servers can issue the user key
+	var userShares []*SignUserKeyShare
+	for _, s := range []*SignMasterKeyShare{shares[5], shares[1], shares[3]} {
+		us, err := s.GenerateUserKeyShare(1, uid, hid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := us.MarshalASN1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed := new(SignUserKeyShare)
+		if err := parsed.UnmarshalASN1(der); err != nil {
+			t.Fatal(err)
+		}
+		userShares = append(userShares, parsed)
+	}
+	if _, err := shares[1].GenerateUserKeyShare(1, uid, hid); err == nil {
+		t.Fatal("expected error for used ticket")
+	}
+	if _, err := CombineSignUserKey(master.Public(), uid, hid, userShares[:2]); err == nil {
+		t.Fatal("expected error for not enough shares")
+	}
+	if _, err := CombineSignUserKey(master.Public(), []byte("Bob"), hid, userShares); err == nil {
+		t.Fatal("expected error for wrong identity")
+	}
+	priv, err := CombineSignUserKey(master.Public(), uid, hid, userShares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := master.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(priv.PrivateKey.Marshal(), expected.PrivateKey.Marshal()) {
+		t.Fatal("user key mismatch")
+	}
+	hash := []byte("Chinese IBS standard")
+	sig, err := priv.Sign(rand.Reader, hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !master.Public().Verify(uid, hid, hash, sig) {
+		t.Fatal("failed to verify signature")
+	}
+}
+
+func TestThresholdEncryptUserKey(t *testing.T) {
+	master, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := master.Split(rand.Reader, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := make([]ticketServer, len(shares))
+	for i, s := range shares {
+		servers[i] = s
+	}
+	uid := []byte("Bob")
+	hid := byte(0x03)
+	generateTicket(t, servers, 3, 7, uid, hid)
+	generateTicket(t, servers, 3, 8, uid, hid)
+
+	var userShares []*EncryptUserKeyShare
+	for _, s := range shares[1:] {
+		us, err := s.GenerateUserKeyShare(7, uid, hid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := us.MarshalASN1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed := new(EncryptUserKeyShare)
+		if err := parsed.UnmarshalASN1(der); err != nil {
+			t.Fatal(err)
+		}
+		userShares = append(userShares, parsed)
+	}
+	// mixing tickets is rejected
+	other, err := shares[0].GenerateUserKeyShare(8, uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CombineEncryptUserKey(master.Public(), uid, hid, append([]*EncryptUserKeyShare{other}, userShares[0])); err == nil {
+		t.Fatal("expected error for different tickets")
+	}
+	priv, err := CombineEncryptUserKey(master.Public(), uid, hid, userShares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := master.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(priv.PrivateKey.Marshal(), expected.PrivateKey.Marshal()) {
+		t.Fatal("user key mismatch")
+	}
+	plaintext := []byte("Chinese IBE standard")
+	ciphertext, err := master.Public().Encrypt(rand.Reader, uid, hid, plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := priv.DecryptASN1(uid, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatal("decrypted plaintext mismatch")
+	}
+}
+
+func TestThresholdTicketProtocol(t *testing.T) {
+	master, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := master.Split(rand.Reader, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("Alice")
+	hid := byte(0x01)
+	msgs, err := shares[0].StartTicket(rand.Reader, 1, uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// not enough participants
+	if _, err := shares[0].ReshareTicket(rand.Reader, 1, uid, hid, msgs[:1]); err == nil {
+		t.Fatal("expected error for not enough messages")
+	}
+	// messages for another server
+	if _, err := shares[0].ReshareTicket(rand.Reader, 1, uid, hid, []TicketMessage{msgs[1], msgs[1], msgs[1]}); err == nil {
+		t.Fatal("expected error for unexpected messages")
+	}
+	// duplicate sender
+	if _, err := shares[0].ReshareTicket(rand.Reader, 1, uid, hid, []TicketMessage{msgs[0], msgs[0], msgs[0]}); err == nil {
+		t.Fatal("expected error for duplicate sender")
+	}
+	if err := shares[0].FinishTicket(2, uid, hid, nil); err == nil {
+		t.Fatal("expected error for unknown ticket")
+	}
+}
+
+func TestThresholdMasterKeyShareASN1(t *testing.T) {
+	master, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := master.Split(rand.Reader, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := make([]ticketServer, len(shares))
+	for i, s := range shares {
+		der, err := s.MarshalASN1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed := new(EncryptMasterKeyShare)
+		if err := parsed.UnmarshalASN1(der); err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Index() != s.Index() || parsed.Threshold() != 2 || parsed.Parties() != 3 ||
+			!bytes.Equal(parsed.MasterPublicKey.Marshal(), master.MasterPublicKey.Marshal()) {
+			t.Fatal("master key share mismatch")
+		}
+		restored[i] = parsed
+	}
+	uid := []byte("Carol")
+	generateTicket(t, restored, 3, 1, uid, 0x03)
+	var userShares []*EncryptUserKeyShare
+	for _, s := range restored[:2] {
+		us, err := s.(*EncryptMasterKeyShare).GenerateUserKeyShare(1, uid, 0x03)
+		if err != nil {
+			t.Fatal(err)
+		}
+		userShares = append(userShares, us)
+	}
+	if _, err := CombineEncryptUserKey(master.Public(), uid, 0x03, userShares); err != nil {
+		t.Fatal(err)
+	}
+	if err := new(EncryptMasterKeyShare).UnmarshalASN1([]byte{0x30, 0x00}); err == nil {
+		t.Fatal("expected error for invalid asn.1 data")
+	}
+}
+
+// TestThresholdTicketIdentity checks that a ticket issues the user key of one identity only,
+// two user keys issued with the same ticket would reveal the master private key.
+func TestThresholdTicketIdentity(t *testing.T) {
+	signMaster, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signShares, err := signMaster.Split(rand.Reader, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptMaster, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptShares, err := encryptMaster.Split(rand.Reader, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signServers := make([]ticketServer, len(signShares))
+	for i, s := range signShares {
+		signServers[i] = s
+	}
+	encryptServers := make([]ticketServer, len(encryptShares))
+	for i, s := range encryptShares {
+		encryptServers[i] = s
+	}
+
+	alice, bob := []byte("Alice"), []byte("Bob")
+	generateTicket(t, signServers, 3, 1, alice, 0x01)
+	generateTicket(t, encryptServers, 3, 1, alice, 0x03)
+	// two disjoint sets of threshold servers, the second one can't issue another identity
+	for _, s := range signShares[:2] {
+		if _, err := s.GenerateUserKeyShare(1, alice, 0x01); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range signShares[2:] {
+		if _, err := s.GenerateUserKeyShare(1, bob, 0x01); err == nil {
+			t.Fatal("expected error for another identity")
+		}
+		if _, err := s.GenerateUserKeyShare(1, alice, 0x02); err == nil {
+			t.Fatal("expected error for another hid")
+		}
+	}
+	for _, s := range encryptShares[:2] {
+		if _, err := s.GenerateUserKeyShare(1, alice, 0x03); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range encryptShares[2:] {
+		if _, err := s.GenerateUserKeyShare(1, bob, 0x03); err == nil {
+			t.Fatal("expected error for another identity")
+		}
+	}
+	// the rejected servers can still issue the bound identity
+	for _, s := range encryptShares[2:] {
+		if _, err := s.GenerateUserKeyShare(1, alice, 0x03); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// servers told different identities during generation reject each other's messages
+	msgs, err := signShares[0].StartTicket(rand.Reader, 2, alice, 0x01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := signShares[1].StartTicket(rand.Reader, 2, bob, 0x01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	more, err := signShares[2].StartTicket(rand.Reader, 2, alice, 0x01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signShares[3].ReshareTicket(rand.Reader, 2, alice, 0x01, []TicketMessage{msgs[3], other[3], more[3]}); err == nil {
+		t.Fatal("expected error for messages bound to another identity")
+	}
+	if _, err := signShares[3].ReshareTicket(rand.Reader, 2, bob, 0x01, []TicketMessage{msgs[3], other[3], more[3]}); err == nil {
+		t.Fatal("expected error for messages bound to another identity")
+	}
+}
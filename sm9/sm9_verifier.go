@@ -0,0 +1,149 @@
+package sm9
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/emmansun/gmsm/internal/bigmod"
+	"github.com/emmansun/gmsm/sm9/bn256"
+)
+
+// DefaultVerifierCacheSize is the default number of identities cached by a SignVerifier.
+const DefaultVerifierCacheSize = 1024
+
+// SignVerifier verifies SM9 signatures under one sign master public key.
+//
+// For each identity, the user sign public key P = [H1(ID||hid, N)]P2 + Ppub-s and
+// the line functions of the Miller loop for P are computed once and cached (LRU),
+// the base point g = e(P1, Ppub-s) uses the precomputed table of the master public key.
+// A SignVerifier is safe for concurrent use.
+type SignVerifier struct {
+	pub      *SignMasterPublicKey
+	capacity int
+
+	mu    sync.Mutex
+	lru   *list.List // of *verifierCacheEntry, the front is the most recently used
+	cache map[string]*list.Element
+}
+
+type verifierCacheEntry struct {
+	id       string
+	prepared *bn256.G2Prepared
+}
+
+// SignatureEntry is an (identity, hash, signature) triple to be verified in a batch.
+type SignatureEntry struct {
+	UID  []byte
+	HID  byte
+	Hash []byte
+	Sig  []byte // ASN.1 encoded SM9Signature
+}
+
+// NewSignVerifier creates a SignVerifier bound to the sign master public key, which caches
+// the precomputation of at most cacheSize identities. If cacheSize <= 0,
+// DefaultVerifierCacheSize is used.
+func NewSignVerifier(pub *SignMasterPublicKey, cacheSize int) *SignVerifier {
+	if cacheSize <= 0 {
+		cacheSize = DefaultVerifierCacheSize
+	}
+	return &SignVerifier{
+		pub:      pub,
+		capacity: cacheSize,
+		lru:      list.New(),
+		cache:    make(map[string]*list.Element),
+	}
+}
+
+// Precompute computes and caches the precomputation of the identity (uid, hid) in advance.
+func (v *SignVerifier) Precompute(uid []byte, hid byte) {
+	v.prepared(uid, hid)
+}
+
+// prepared returns the cached line functions of the user sign public key.
+func (v *SignVerifier) prepared(uid []byte, hid byte) *bn256.G2Prepared {
+	id := string(append(append([]byte{}, uid...), hid))
+	v.mu.Lock()
+	if e, ok := v.cache[id]; ok {
+		v.lru.MoveToFront(e)
+		v.mu.Unlock()
+		return e.Value.(*verifierCacheEntry).prepared
+	}
+	v.mu.Unlock()
+
+	// compute it without holding the lock, concurrent calls may compute the same identity.
+	prepared := bn256.PrepareG2(v.pub.GenerateUserPublicKey(uid, hid))
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if e, ok := v.cache[id]; ok {
+		v.lru.MoveToFront(e)
+		return e.Value.(*verifierCacheEntry).prepared
+	}
+	v.cache[id] = v.lru.PushFront(&verifierCacheEntry{id: id, prepared: prepared})
+	for v.lru.Len() > v.capacity {
+		oldest := v.lru.Back()
+		v.lru.Remove(oldest)
+		delete(v.cache, oldest.Value.(*verifierCacheEntry).id)
+	}
+	return prepared
+}
+
+// Verify verifies the ASN.1 encoded signature of type SM9Signature, sig, of hash using
+// the identity uid and hid. It's equivalent to VerifyASN1 with the bound master public key.
+func (v *SignVerifier) Verify(uid []byte, hid byte, hash, sig []byte) bool {
+	return verifySignature(v.pub, hash, sig, func(s *bn256.G1) *bn256.GT {
+		return bn256.PairPrepared(s, v.prepared(uid, hid))
+	})
+}
+
+// VerifyBatch verifies all entries. It reports whether all signatures are valid,
+// if not, the indexes of the invalid entries are returned in ascending order.
+//
+// SM9 signature h = H2(M || w, N) hashes each w = e(S, P)·g^h, so the pairings can't
+// be merged into one, the entries of the same identity share the precomputation instead.
+// An empty batch is considered valid.
+func (v *SignVerifier) VerifyBatch(entries []SignatureEntry) (bool, []int) {
+	var failed []int
+	for i := range entries {
+		e := &entries[i]
+		if !v.Verify(e.UID, e.HID, e.Hash, e.Sig) {
+			failed = append(failed, i)
+		}
+	}
+	return len(failed) == 0, failed
+}
+
+// verifySignature verifies the signature with the pairing function e(S, P),
+// where P is the user sign public key.
+func verifySignature(pub *SignMasterPublicKey, hash, sig []byte, pair func(*bn256.G1) *bn256.GT) bool {
+	h, s, err := parseSignature(sig)
+	if err != nil {
+		return false
+	}
+	if !s.IsOnCurve() {
+		return false
+	}
+
+	hNat, err := bigmod.NewNat().SetBytes(h, orderNat)
+	if err != nil {
+		return false
+	}
+	if hNat.IsZero() == 1 {
+		return false
+	}
+
+	t, err := pub.ScalarBaseMult(hNat.Bytes(orderNat))
+	if err != nil {
+		return false
+	}
+
+	u := pair(s)
+	w := new(bn256.GT).Add(u, t)
+
+	var buffer []byte
+	buffer = append(buffer, hash...)
+	buffer = append(buffer, w.Marshal()...)
+	h2 := hashH2(buffer)
+
+	return h2.Equal(hNat) == 1
+}
@@ -0,0 +1,113 @@
+package sm9
+
+import (
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSignVerifier(t *testing.T) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	verifier := NewSignVerifier(masterKey.Public(), 2)
+	var entries []SignatureEntry
+	for i := 0; i < 4; i++ {
+		uid := []byte(fmt.Sprintf("device-%d", i%3))
+		hashed := []byte(fmt.Sprintf("message %d", i))
+		userKey, err := masterKey.GenerateUserKey(uid, hid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := SignASN1(rand.Reader, userKey, hashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !verifier.Verify(uid, hid, hashed, sig) {
+			t.Errorf("entry %v: verify failed", i)
+		}
+		if verifier.Verify(uid, hid+1, hashed, sig) {
+			t.Errorf("entry %v: verify with wrong hid successed", i)
+		}
+		entries = append(entries, SignatureEntry{UID: uid, HID: hid, Hash: hashed, Sig: sig})
+	}
+	if verifier.lru.Len() > 2 || len(verifier.cache) != verifier.lru.Len() {
+		t.Errorf("unexpected cache size %v", verifier.lru.Len())
+	}
+
+	ok, failed := verifier.VerifyBatch(entries)
+	if !ok || len(failed) != 0 {
+		t.Fatalf("batch verify failed: %v", failed)
+	}
+	entries[1].Hash = []byte("tampered")
+	entries[3].UID = []byte("device-9")
+	ok, failed = verifier.VerifyBatch(entries)
+	if ok || !reflect.DeepEqual(failed, []int{1, 3}) {
+		t.Errorf("unexpected batch result %v, %v", ok, failed)
+	}
+	if ok, failed := verifier.VerifyBatch(nil); !ok || failed != nil {
+		t.Errorf("empty batch should be valid")
+	}
+}
+
+func TestSignVerifierLegacySign(t *testing.T) {
+	// verify the signature encoded from the result of Sign
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("Alice")
+	hid := byte(0x01)
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := []byte("Chinese IBS standard")
+	h, s, err := Sign(rand.Reader, userKey, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := encodeSignature(h.Bytes(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewSignVerifier(masterKey.Public(), 0)
+	verifier.Precompute(uid, hid)
+	if !verifier.Verify(uid, hid, hashed, sig) || !VerifyASN1(masterKey.Public(), uid, hid, hashed, sig) {
+		t.Errorf("verify failed")
+	}
+	sig[0] = 0xff
+	if verifier.Verify(uid, hid, hashed, sig) {
+		t.Errorf("verify with invalid asn1 format successed")
+	}
+}
+
+func BenchmarkSignVerifier(b *testing.B) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	uid := []byte("emmansun")
+	hid := byte(0x01)
+	hashed := []byte("Chinese IBS standard")
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sig, err := SignASN1(rand.Reader, userKey, hashed)
+	if err != nil {
+		b.Fatal(err)
+	}
+	verifier := NewSignVerifier(masterKey.Public(), 0)
+	verifier.Verify(uid, hid, hashed, sig)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !verifier.Verify(uid, hid, hashed, sig) {
+			b.Fatal("verify failed")
+		}
+	}
+}
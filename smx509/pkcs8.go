@@ -12,6 +12,8 @@ import (
 	"github.com/emmansun/gmsm/sm9"
 )
 
+// The GM/T 0006 OID sm9-1 identifies both the SM9 sign keys and the SM9 signature
+// algorithm, SM9WithSM3 in signatureAlgorithmDetails.
 var (
 	oidSM9     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302}
 	oidSM9Sign = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302, 1}
@@ -0,0 +1,324 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm9"
+)
+
+// pemTypeSM9PublicParameters is the PEM block type of SM9 public parameters.
+const pemTypeSM9PublicParameters = "SM9 PUBLIC PARAMETERS"
+
+// The SM9 public parameters document distributes a KGC master public key with its metadata:
+//
+//	SM9PublicParameters ::= SEQUENCE {
+//	  tbsPublicParameters  TBSSM9PublicParameters,
+//	  signatureAlgorithm   AlgorithmIdentifier,
+//	  signatureValue       BIT STRING
+//	}
+//
+//	TBSSM9PublicParameters ::= SEQUENCE {
+//	  version          [0] EXPLICIT INTEGER DEFAULT 0,
+//	  serialNumber     INTEGER,
+//	  issuer           Name,
+//	  validity         Validity,
+//	  usage            OBJECT IDENTIFIER, -- sm9sign or sm9encrypt
+//	  hid              INTEGER,
+//	  masterPublicKey  BIT STRING,
+//	  signerUID        [1] EXPLICIT OCTET STRING OPTIONAL,
+//	  signerHID        [2] EXPLICIT INTEGER DEFAULT 0
+//	}
+//
+// The signerUID and signerHID are present only if it's signed by an SM9 sign private key.
+type sm9PublicParameters struct {
+	TBSPublicParameters tbsSM9PublicParameters
+	SignatureAlgorithm  pkix.AlgorithmIdentifier
+	SignatureValue      asn1.BitString
+}
+
+type tbsSM9PublicParameters struct {
+	Raw             asn1.RawContent
+	Version         int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber    *big.Int
+	Issuer          asn1.RawValue
+	Validity        validity
+	Usage           asn1.ObjectIdentifier
+	HID             int
+	MasterPublicKey asn1.BitString
+	SignerUID       []byte `asn1:"optional,explicit,tag:1"`
+	SignerHID       int    `asn1:"optional,explicit,default:0,tag:2"`
+}
+
+// SM9PublicParameters represents the signed SM9 public parameters of a KGC.
+type SM9PublicParameters struct {
+	Raw                    []byte // Complete ASN.1 DER content (parameters, signature algorithm and signature).
+	RawTBSPublicParameters []byte // ASN.1 DER contents, the signed part.
+
+	Version      int
+	SerialNumber *big.Int
+	Issuer       pkix.Name // The KGC.
+	NotBefore    time.Time
+	NotAfter     time.Time
+
+	// HID is the hid used to generate the user keys under the master public key.
+	HID byte
+	// MasterPublicKey is a *sm9.SignMasterPublicKey or a *sm9.EncryptMasterPublicKey,
+	// it determines the usage of the parameters.
+	MasterPublicKey any
+
+	// SignerUID and SignerHID identify the signer if the parameters are signed by an
+	// SM9 sign private key. They are ignored for SM2 signers.
+	SignerUID []byte
+	SignerHID byte
+
+	SignatureAlgorithm SignatureAlgorithm
+	Signature          []byte
+}
+
+func marshalSM9MasterPublicKey(pub any) (asn1.ObjectIdentifier, []byte, error) {
+	switch k := pub.(type) {
+	case *sm9.SignMasterPublicKey:
+		return oidSM9Sign, k.MasterPublicKey.MarshalUncompressed(), nil
+	case *sm9.EncryptMasterPublicKey:
+		return oidSM9Enc, k.MasterPublicKey.MarshalUncompressed(), nil
+	}
+	return nil, nil, errors.New("x509: unsupported SM9 master public key type")
+}
+
+func parseSM9MasterPublicKey(usage asn1.ObjectIdentifier, bytes []byte) (any, error) {
+	if len(bytes) == 0 {
+		return nil, errors.New("x509: invalid SM9 master public key")
+	}
+	switch {
+	case usage.Equal(oidSM9Sign):
+		pub := new(sm9.SignMasterPublicKey)
+		if err := pub.UnmarshalRaw(bytes); err != nil {
+			return nil, err
+		}
+		return pub, nil
+	case usage.Equal(oidSM9Enc):
+		pub := new(sm9.EncryptMasterPublicKey)
+		if err := pub.UnmarshalRaw(bytes); err != nil {
+			return nil, err
+		}
+		return pub, nil
+	}
+	return nil, errors.New("x509: unknown SM9 public parameters usage")
+}
+
+// CreateSM9PublicParameters creates new SM9 public parameters based on a template.
+// The following members of template are used: SerialNumber, Issuer, NotBefore, NotAfter,
+// HID, MasterPublicKey, and SignerUID and SignerHID for SM9 signers.
+//
+// The priv is either a crypto.Signer with an SM2 public key, such as *sm2.PrivateKey, or a
+// *sm9.SignPrivateKey issued for template.SignerUID and template.SignerHID.
+//
+// The returned slice is the SM9 public parameters in DER encoding.
+func CreateSM9PublicParameters(rand io.Reader, template *SM9PublicParameters, priv any) ([]byte, error) {
+	if template.SerialNumber == nil {
+		return nil, errors.New("x509: no SerialNumber given")
+	}
+	if template.SerialNumber.Sign() == -1 {
+		return nil, errors.New("x509: serial number must be positive")
+	}
+	usage, masterPublicKey, err := marshalSM9MasterPublicKey(template.MasterPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := asn1.Marshal(template.Issuer.ToRDNSequence())
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := tbsSM9PublicParameters{
+		SerialNumber:    template.SerialNumber,
+		Issuer:          asn1.RawValue{FullBytes: issuer},
+		Validity:        validity{template.NotBefore.UTC(), template.NotAfter.UTC()},
+		Usage:           usage,
+		HID:             int(template.HID),
+		MasterPublicKey: asn1.BitString{Bytes: masterPublicKey, BitLength: 8 * len(masterPublicKey)},
+	}
+
+	var sigAlgo pkix.AlgorithmIdentifier
+	switch k := priv.(type) {
+	case *sm9.SignPrivateKey:
+		if len(template.SignerUID) == 0 {
+			return nil, errors.New("x509: no SignerUID given for SM9 signer")
+		}
+		sigAlgo.Algorithm = oidSM9Sign
+		tbs.SignerUID = template.SignerUID
+		tbs.SignerHID = int(template.SignerHID)
+	case crypto.Signer:
+		pub, ok := k.Public().(*ecdsa.PublicKey)
+		if !ok || pub.Curve != sm2.P256() {
+			return nil, errors.New("x509: only SM2 or SM9 keys can sign SM9 public parameters")
+		}
+		sigAlgo.Algorithm = oidSignatureSM2WithSM3
+	default:
+		return nil, errors.New("x509: only SM2 or SM9 keys can sign SM9 public parameters")
+	}
+
+	tbsContents, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	tbs.Raw = tbsContents
+
+	var signature []byte
+	switch k := priv.(type) {
+	case *sm9.SignPrivateKey:
+		signature, err = k.Sign(rand, tbsContents, nil)
+	case crypto.Signer:
+		signature, err = k.Sign(rand, tbsContents, sm2.DefaultSM2SignerOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(sm9PublicParameters{
+		TBSPublicParameters: tbs,
+		SignatureAlgorithm:  sigAlgo,
+		SignatureValue:      asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// ParseSM9PublicParameters parses SM9 public parameters from the given ASN.1 DER data.
+// The signature is not verified, see CheckSignatureFrom.
+func ParseSM9PublicParameters(der []byte) (*SM9PublicParameters, error) {
+	var params sm9PublicParameters
+	rest, err := asn1.Unmarshal(der, &params)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+	tbs := &params.TBSPublicParameters
+	if tbs.Version != 0 {
+		return nil, errors.New("x509: unsupported SM9 public parameters version")
+	}
+	if tbs.HID < 0 || tbs.HID > 0xff || tbs.SignerHID < 0 || tbs.SignerHID > 0xff {
+		return nil, errors.New("x509: invalid SM9 public parameters hid")
+	}
+
+	out := &SM9PublicParameters{
+		Raw:                    der,
+		RawTBSPublicParameters: tbs.Raw,
+		Version:                tbs.Version,
+		SerialNumber:           tbs.SerialNumber,
+		NotBefore:              tbs.Validity.NotBefore,
+		NotAfter:               tbs.Validity.NotAfter,
+		HID:                    byte(tbs.HID),
+		Signature:              params.SignatureValue.RightAlign(),
+	}
+
+	out.SignatureAlgorithm = getSignatureAlgorithmFromAI(params.SignatureAlgorithm)
+	switch out.SignatureAlgorithm {
+	case SM2WithSM3:
+	case SM9WithSM3:
+		if len(tbs.SignerUID) == 0 {
+			return nil, errors.New("x509: missing SM9 signer uid")
+		}
+		out.SignerUID = tbs.SignerUID
+		out.SignerHID = byte(tbs.SignerHID)
+	default:
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+
+	var issuer pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(tbs.Issuer.FullBytes, &issuer); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after X.509 issuer")
+	}
+	out.Issuer.FillFromRDNSequence(&issuer)
+
+	if out.MasterPublicKey, err = parseSM9MasterPublicKey(tbs.Usage, tbs.MasterPublicKey.RightAlign()); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckSignatureFrom verifies that the signature on p is valid from the signer.
+// The signer is a *Certificate or an *ecdsa.PublicKey with SM2 public key for
+// SM2WithSM3, or a *sm9.SignMasterPublicKey for SM9WithSM3.
+//
+// As with Certificate.CheckSignatureFrom, a signer certificate must be allowed to
+// sign certificates.
+func (p *SM9PublicParameters) CheckSignatureFrom(signer any) error {
+	switch k := signer.(type) {
+	case *Certificate:
+		if k.Version == 3 && !k.BasicConstraintsValid ||
+			k.BasicConstraintsValid && !k.IsCA {
+			return x509.ConstraintViolationError{}
+		}
+		if k.KeyUsage != 0 && k.KeyUsage&KeyUsageCertSign == 0 {
+			return x509.ConstraintViolationError{}
+		}
+		if k.PublicKeyAlgorithm == UnknownPublicKeyAlgorithm {
+			return x509.ErrUnsupportedAlgorithm
+		}
+		if p.SignatureAlgorithm != SM2WithSM3 {
+			return x509.ErrUnsupportedAlgorithm
+		}
+		return checkSignature(SM2WithSM3, p.RawTBSPublicParameters, p.Signature, k.PublicKey, false)
+	case *ecdsa.PublicKey:
+		if p.SignatureAlgorithm != SM2WithSM3 {
+			return x509.ErrUnsupportedAlgorithm
+		}
+		return checkSignature(SM2WithSM3, p.RawTBSPublicParameters, p.Signature, k, false)
+	case *sm9.SignMasterPublicKey:
+		if p.SignatureAlgorithm != SM9WithSM3 {
+			return x509.ErrUnsupportedAlgorithm
+		}
+		if !sm9.VerifyASN1(k, p.SignerUID, p.SignerHID, p.RawTBSPublicParameters, p.Signature) {
+			return errors.New("x509: SM9 verification failure")
+		}
+		return nil
+	}
+	return x509.ErrUnsupportedAlgorithm
+}
+
+// IsValidAt reports whether t is within the validity period of p.
+func (p *SM9PublicParameters) IsValidAt(t time.Time) bool {
+	return !t.Before(p.NotBefore) && !t.After(p.NotAfter)
+}
+
+// Equal reports whether p and other are the same SM9 public parameters.
+func (p *SM9PublicParameters) Equal(other *SM9PublicParameters) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	return bytes.Equal(p.Raw, other.Raw)
+}
+
+// ParseSM9PublicParametersPEM parses SM9 public parameters from the first
+// "SM9 PUBLIC PARAMETERS" PEM block of data.
+func ParseSM9PublicParametersPEM(data []byte) (*SM9PublicParameters, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("x509: failed to parse SM9 public parameters PEM block")
+		}
+		if block.Type == pemTypeSM9PublicParameters {
+			return ParseSM9PublicParameters(block.Bytes)
+		}
+	}
+}
+
+// MarshalPEM returns the SM9 public parameters in an "SM9 PUBLIC PARAMETERS" PEM block.
+func (p *SM9PublicParameters) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypeSM9PublicParameters, Bytes: p.Raw})
+}
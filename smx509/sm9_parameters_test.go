@@ -0,0 +1,185 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm9"
+)
+
+func sm9PublicParametersTemplate(masterPublicKey any) *SM9PublicParameters {
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &SM9PublicParameters{
+		SerialNumber:    big.NewInt(2026),
+		Issuer:          pkix.Name{CommonName: "KGC", Organization: []string{"Example"}},
+		NotBefore:       notBefore,
+		NotAfter:        notBefore.AddDate(1, 0, 0),
+		HID:             0x03,
+		MasterPublicKey: masterPublicKey,
+	}
+}
+
+func TestSM9PublicParametersSignedBySM2(t *testing.T) {
+	masterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := sm9PublicParametersTemplate(masterKey.Public())
+	der, err := CreateSM9PublicParameters(rand.Reader, template, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := ParseSM9PublicParametersPEM(sm9PublicParametersPEM(t, der))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.SignatureAlgorithm != SM2WithSM3 {
+		t.Errorf("unexpected signature algorithm %v", params.SignatureAlgorithm)
+	}
+	if params.SerialNumber.Cmp(template.SerialNumber) != 0 || params.HID != template.HID ||
+		params.Issuer.CommonName != "KGC" || !params.NotAfter.Equal(template.NotAfter) {
+		t.Errorf("unexpected parameters %+v", params)
+	}
+	pub, ok := params.MasterPublicKey.(*sm9.EncryptMasterPublicKey)
+	if !ok {
+		t.Fatalf("unexpected master public key type %T", params.MasterPublicKey)
+	}
+	if !bytes.Equal(pub.MasterPublicKey.Marshal(), masterKey.MasterPublicKey.Marshal()) {
+		t.Error("master public key mismatch")
+	}
+	if !params.IsValidAt(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)) || params.IsValidAt(time.Date(2028, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected validity")
+	}
+	if err := params.CheckSignatureFrom(&priv.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	issuer := &Certificate{
+		Version:               3,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              KeyUsageCertSign,
+		PublicKeyAlgorithm:    ECDSA,
+		PublicKey:             &priv.PublicKey,
+	}
+	if err := params.CheckSignatureFrom(issuer); err != nil {
+		t.Fatal(err)
+	}
+	issuer.KeyUsage = KeyUsageDigitalSignature
+	if err := params.CheckSignatureFrom(issuer); err == nil {
+		t.Error("expected error for issuer without KeyUsageCertSign")
+	}
+	issuer.KeyUsage, issuer.IsCA = KeyUsageCertSign, false
+	if err := params.CheckSignatureFrom(issuer); err == nil {
+		t.Error("expected error for non-CA issuer")
+	}
+	other, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := params.CheckSignatureFrom(&other.PublicKey); err == nil {
+		t.Error("expected error for another signer")
+	}
+	if err := params.CheckSignatureFrom(masterKey.Public()); err == nil {
+		t.Error("expected error for unsupported signer")
+	}
+}
+
+func TestSM9PublicParametersSignedBySM9(t *testing.T) {
+	encMasterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signMasterKey, err := sm9.GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerUID := []byte("kgc@example.com")
+	signer, err := signMasterKey.GenerateUserKey(signerUID, 0x01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := sm9PublicParametersTemplate(signMasterKey.Public())
+	if _, err := CreateSM9PublicParameters(rand.Reader, template, signer); err == nil {
+		t.Fatal("expected error for missing signer uid")
+	}
+	template.MasterPublicKey = encMasterKey.Public()
+	template.SignerUID = signerUID
+	template.SignerHID = 0x01
+	der, err := CreateSM9PublicParameters(rand.Reader, template, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := ParseSM9PublicParameters(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.SignatureAlgorithm != SM9WithSM3 || !bytes.Equal(params.SignerUID, signerUID) || params.SignerHID != 0x01 {
+		t.Errorf("unexpected signer %v %s %v", params.SignatureAlgorithm, params.SignerUID, params.SignerHID)
+	}
+	if err := params.CheckSignatureFrom(signMasterKey.Public()); err != nil {
+		t.Fatal(err)
+	}
+	params.RawTBSPublicParameters[len(params.RawTBSPublicParameters)-1] ^= 1
+	if err := params.CheckSignatureFrom(signMasterKey.Public()); err == nil {
+		t.Error("expected error for tampered parameters")
+	}
+}
+
+func TestSM9PublicParametersInvalid(t *testing.T) {
+	masterKey, err := sm9.GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateSM9PublicParameters(rand.Reader, sm9PublicParametersTemplate(&priv.PublicKey), priv); err == nil {
+		t.Error("expected error for invalid master public key")
+	}
+	template := sm9PublicParametersTemplate(masterKey.Public())
+	template.SerialNumber = nil
+	if _, err := CreateSM9PublicParameters(rand.Reader, template, priv); err == nil {
+		t.Error("expected error for missing serial number")
+	}
+	der, err := CreateSM9PublicParameters(rand.Reader, sm9PublicParametersTemplate(masterKey.Public()), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSM9PublicParameters(append(der, 0)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+	if _, err := ParseSM9PublicParametersPEM([]byte("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n")); err == nil {
+		t.Error("expected error for missing PEM block")
+	}
+}
+
+func sm9PublicParametersPEM(t *testing.T, der []byte) []byte {
+	t.Helper()
+	params, err := ParseSM9PublicParameters(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return params.MarshalPEM()
+}
+
+func TestSM9WithSM3Details(t *testing.T) {
+	ai := pkix.AlgorithmIdentifier{Algorithm: oidSM9Sign}
+	if algo := getSignatureAlgorithmFromAI(ai); algo != SM9WithSM3 {
+		t.Errorf("unexpected signature algorithm %v", algo)
+	}
+	for _, details := range signatureAlgorithmDetails {
+		if details.algo == SM9WithSM3 && (details.pubKeyAlgo != SM9 || details.name != "SM9-SM3") {
+			t.Errorf("unexpected details %+v", details)
+		}
+	}
+}
@@ -229,6 +229,7 @@ const (
 	PureEd25519      = x509.PureEd25519
 
 	SM2WithSM3 SignatureAlgorithm = 99
+	SM9WithSM3 SignatureAlgorithm = 100 // Only supported for SM9 public parameters.
 )
 
 func isRSAPSS(algo SignatureAlgorithm) bool {
@@ -249,6 +250,8 @@ const (
 	DSA     = x509.DSA // Only supported for parsing.
 	ECDSA   = x509.ECDSA
 	Ed25519 = x509.Ed25519
+
+	SM9 PublicKeyAlgorithm = 99 // Only supported for SM9 public parameters.
 )
 
 // OIDs for signature algorithms
@@ -362,6 +365,7 @@ var signatureAlgorithmDetails = []struct {
 	{ECDSAWithSHA512, "ECDSA-SHA512", oidSignatureECDSAWithSHA512, ECDSA, crypto.SHA512},
 	{PureEd25519, "Ed25519", oidSignatureEd25519, Ed25519, crypto.Hash(0) /* no pre-hashing */},
 	{SM2WithSM3, "SM2-SM3", oidSignatureSM2WithSM3, ECDSA, crypto.Hash(0) /* no pre-hashing */},
+	{SM9WithSM3, "SM9-SM3", oidSM9Sign, SM9, crypto.Hash(0) /* no pre-hashing */},
 }
 
 // hashToPSSParameters contains the DER encoded RSA PSS parameters for the
@@ -1386,6 +1390,11 @@ var emptyASN1Subject = []byte{0x30, 0}
 // ed25519.PublicKey. pub must be a supported key type, and priv must be a
 // crypto.Signer with a supported public key.
 //
+// SM9 sign private keys are not crypto.Signer and can't sign certificates: an SM9
+// signature is verified with the signer's identity and the KGC master public key,
+// not with a certified public key. Use CreateSM9PublicParameters to distribute the
+// master public key.
+//
 // The AuthorityKeyId will be taken from the SubjectKeyId of parent, if any,
 // unless the resulting certificate is self-signed. Otherwise the value from
 // template will be used.
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm9"
 )
 
 func TestMarshalInvalidPublicKey(t *testing.T) {
@@ -2743,6 +2744,24 @@ func TestCreateCertificateBrokenSigner(t *testing.T) {
 	}
 }
 
+func TestCreateCertificateSM9Signer(t *testing.T) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(10),
+		DNSNames:     []string{"example.com"},
+	}
+	masterKey, err := sm9.GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %s", err)
+	}
+	k, err := masterKey.GenerateUserKey([]byte("emmansun"), 0x01)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %s", err)
+	}
+	if _, err := CreateCertificate(rand.Reader, template, template, k.MasterPublic(), k); err == nil {
+		t.Fatal("expected CreateCertificate to fail with an SM9 signer")
+	}
+}
+
 func TestCreateCertificateLegacy(t *testing.T) {
 	sigAlg := MD5WithRSA
 	template := &Certificate{